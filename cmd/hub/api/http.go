@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -18,6 +19,7 @@ import (
 	"github.com/gorilla/websocket"
 
 	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/tracing"
 	"github.com/agilestacks/hub/cmd/hub/util"
 )
 
@@ -234,6 +236,21 @@ func doWithAuthorization(client *http.Client, method, path string, reqBody io.Re
 }
 
 func do(client *http.Client, req *http.Request, jsResp interface{}) (int, error, []byte) {
+	ctx, span := tracing.StartKind(context.Background(), "HTTP "+req.Method, tracing.KindClient)
+	if span != nil {
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.url", fmt.Sprintf("%s://%s%s", req.URL.Scheme, req.URL.Host, req.URL.Path))
+		req.Header.Set("traceparent", tracing.TraceParent(ctx))
+	}
+	code, err, body := doRequest(client, req, jsResp)
+	if code > 0 {
+		span.SetAttribute("http.status_code", code)
+	}
+	span.EndWithError(err)
+	return code, err, body
+}
+
+func doRequest(client *http.Client, req *http.Request, jsResp interface{}) (int, error, []byte) {
 	resp, err := client.Do(req)
 	if err != nil {
 		return 0, fmt.Errorf("Error during HTTP request: %v", err), nil
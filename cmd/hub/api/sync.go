@@ -38,6 +38,13 @@ func SyncStackInstance(selector, status string, stateFilenames []string) {
 	if err != nil {
 		log.Fatalf("Unable to sync Stack Instance: %v", err)
 	}
+	if len(stateFilenames) > 0 {
+		// queued state is superseded
+		err = DequeueSync(selector)
+		if err != nil {
+			util.Warn("Unable to remove stack instance state from sync queue: %v", err)
+		}
+	}
 
 	errs := formatStackInstanceEntity(patched, false, false, false, make([]error, 0))
 	if len(errs) > 0 {
@@ -46,17 +53,17 @@ func SyncStackInstance(selector, status string, stateFilenames []string) {
 	}
 }
 
-func TransformStateToApi(state *state.StateManifest) StackInstancePatch {
+func TransformStateToApi(st *state.StateManifest) StackInstancePatch {
 	return StackInstancePatch{
-		ComponentsEnabled: state.Lifecycle.Order,
-		Parameters:        transformStackParametersToApi(state.StackParameters),
+		ComponentsEnabled: st.Lifecycle.Order,
+		Parameters:        transformStackParametersToApi(state.DecryptParameters(st.StackParameters)),
 		Status: &StackInstanceStatus{
-			Status:     state.Status,
-			Components: transformComponentsToApi(state.Lifecycle.Order, state.Components),
+			Status:     st.Status,
+			Components: transformComponentsToApi(st.Lifecycle.Order, st.Components),
 		},
-		InflightOperations: transformOperationsToApi(state.Operations),
-		Outputs:            transformStackOutputsToApi(appendKubernetesOutputs(state.StackOutputs, state.Components)),
-		Provides:           state.Provides,
+		InflightOperations: transformOperationsToApi(st.Operations),
+		Outputs:            transformStackOutputsToApi(appendKubernetesOutputs(st.StackOutputs, st.Components)),
+		Provides:           st.Provides,
 	}
 }
 
@@ -130,7 +137,7 @@ func transformComponentsToApi(order []string, stateComponents map[string]*state.
 	var prevOutputs []parameters.CapturedOutput
 	for _, name := range order {
 		if component, exist := stateComponents[name]; exist && component.Status != "" {
-			noSecretOutputs := filterOutSecretOutputs(component.CapturedOutputs)
+			noSecretOutputs := filterOutSecretOutputs(state.DecryptOutputs(component.CapturedOutputs))
 			outputs := transformComponentOutputsToApi(state.DiffOutputs(noSecretOutputs, prevOutputs))
 			if len(noSecretOutputs) > 0 {
 				prevOutputs = noSecretOutputs
@@ -212,7 +219,7 @@ func appendKubernetesOutputs(outputs []parameters.ExpandedOutput, components map
 				}
 				for _, output := range provider.CapturedOutputs {
 					if output.Name == outputName {
-						outputs = append(outputs, parameters.ExpandedOutput{Name: outputQName, Value: output.Value})
+						outputs = append(outputs, parameters.ExpandedOutput{Name: outputQName, Value: state.DecryptValue(output.Value)})
 					}
 				}
 			}
@@ -235,6 +242,7 @@ func transformStackOutputsToApi(stackOutputs []parameters.ExpandedOutput) []Outp
 			}
 		}
 
+		o.Value = state.DecryptValue(o.Value)
 		var value interface{}
 		kind := ""
 		if strings.HasPrefix(o.Kind, "secret") || util.LooksLikeSecret(name) ||
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/crypto"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+// Stack Instance state patches that failed to sync are spooled next to the cache file
+// to be replayed by `hub api instance sync --pending`; only the latest patch per instance is kept.
+
+type PendingSync struct {
+	ApiBaseUrl string             `json:"apiBaseUrl"`
+	Instance   string             `json:"instance"`
+	Timestamp  time.Time          `json:"timestamp"`
+	Error      string             `json:"error,omitempty"`
+	Patch      StackInstancePatch `json:"patch"`
+}
+
+type syncQueue struct {
+	Version int           `json:"version"`
+	Pending []PendingSync `json:"pending"`
+}
+
+func syncQueueFilename() string {
+	if config.CacheFile == "" {
+		return ""
+	}
+	return strings.TrimSuffix(config.CacheFile, ".yaml") + "-sync-queue.json"
+}
+
+func readSyncQueue() (*syncQueue, error) {
+	queue := &syncQueue{Version: 1}
+	filename := syncQueueFilename()
+	if filename == "" {
+		return nil, fmt.Errorf("No cache file set, try --cache")
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return queue, nil
+		}
+		return nil, err
+	}
+	// queued patches carry secrets and are encrypted when HUB_CRYPTO_* is set
+	if crypto.IsEncryptedData(data) {
+		data, err = crypto.Decrypt(data)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to decrypt `%s`: %v", filename, err)
+		}
+	}
+	err = json.Unmarshal(data, queue)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse `%s`: %v", filename, err)
+	}
+	return queue, nil
+}
+
+func writeSyncQueue(queue *syncQueue) error {
+	filename := syncQueueFilename()
+	if len(queue.Pending) == 0 {
+		err := os.Remove(filename)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(queue)
+	if err != nil {
+		return err
+	}
+	if crypto.CurrentKeySetup().IsSet() {
+		data, err = crypto.Encrypt(data)
+		if err != nil {
+			return fmt.Errorf("Unable to encrypt sync queue: %v", err)
+		}
+	}
+	if config.Trace {
+		log.Printf("Writing `%s`", filename)
+	}
+	// write to temporary file first so that readers never see partial content
+	temp := fmt.Sprintf("%s.%d.tmp", filename, os.Getpid())
+	err = ioutil.WriteFile(temp, data, 0600)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(temp, filename)
+	if err != nil {
+		os.Remove(temp)
+	}
+	return err
+}
+
+const syncQueueLockTimeout = 60 * time.Second
+
+// updateSyncQueue performs read-modify-write of the queue under lock file, as the queue is shared
+// by concurrent `hub deploy --hub-sync` and `hub api instance sync --pending` runs
+func updateSyncQueue(update func(*syncQueue) bool) error {
+	unlock, err := lockSyncQueue()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	queue, err := readSyncQueue()
+	if err != nil {
+		return err
+	}
+	if !update(queue) {
+		return nil
+	}
+	return writeSyncQueue(queue)
+}
+
+func lockSyncQueue() (func(), error) {
+	filename := syncQueueFilename()
+	if filename == "" {
+		return nil, fmt.Errorf("No cache file set, try --cache")
+	}
+	lock := filename + ".lock"
+	deadline := time.Now().Add(syncQueueLockTimeout)
+	for {
+		file, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("Unable to create `%s`: %v", lock, err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Unable to lock sync queue: `%s` exists for more than %v; remove it if no sync is in progress",
+				lock, syncQueueLockTimeout)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// EnqueueSync replaces pending patch of the instance with the latest one
+func EnqueueSync(selector string, patch StackInstancePatch, syncErr error) error {
+	pending := PendingSync{
+		ApiBaseUrl: config.ApiBaseUrl,
+		Instance:   selector,
+		Timestamp:  time.Now(),
+		Patch:      patch,
+	}
+	if syncErr != nil {
+		pending.Error = syncErr.Error()
+	}
+	return updateSyncQueue(func(queue *syncQueue) bool {
+		queued := false
+		for i, p := range queue.Pending {
+			if p.ApiBaseUrl == pending.ApiBaseUrl && p.Instance == selector {
+				queue.Pending[i] = pending
+				queued = true
+			}
+		}
+		if !queued {
+			queue.Pending = append(queue.Pending, pending)
+		}
+		return true
+	})
+}
+
+// DequeueSync removes pending patch of the instance, if any
+func DequeueSync(selector string) error {
+	return updateSyncQueue(func(queue *syncQueue) bool {
+		pending := make([]PendingSync, 0, len(queue.Pending))
+		for _, p := range queue.Pending {
+			if p.ApiBaseUrl != config.ApiBaseUrl || p.Instance != selector {
+				pending = append(pending, p)
+			}
+		}
+		if len(pending) == len(queue.Pending) {
+			return false
+		}
+		queue.Pending = pending
+		return true
+	})
+}
+
+// SyncPending replays queued patches of all instances, or of the selected instance only.
+// The queue is not locked while patches are sent; entries enqueued meanwhile are kept.
+func SyncPending(selector string) {
+	queue, err := readSyncQueue()
+	if err != nil {
+		log.Fatalf("Unable to read sync queue: %v", err)
+	}
+	synced := make([]PendingSync, 0)
+	failed := make([]PendingSync, 0)
+	errs := make([]error, 0)
+	for _, p := range queue.Pending {
+		if p.ApiBaseUrl != config.ApiBaseUrl || (selector != "" && p.Instance != selector) {
+			continue
+		}
+		if config.Verbose {
+			log.Printf("Syncing Stack Instance `%s` state queued at %v", p.Instance, p.Timestamp.Truncate(time.Second))
+		}
+		_, err := PatchStackInstance(p.Instance, p.Patch, true)
+		if err != nil {
+			p.Error = err.Error()
+			failed = append(failed, p)
+			errs = append(errs, fmt.Errorf("Stack Instance `%s`: %v", p.Instance, err))
+			continue
+		}
+		synced = append(synced, p)
+	}
+	// entry replaced by newer patch while syncing is left as is
+	same := func(a, b PendingSync) bool {
+		return a.ApiBaseUrl == b.ApiBaseUrl && a.Instance == b.Instance && a.Timestamp.Equal(b.Timestamp)
+	}
+	err = updateSyncQueue(func(queue *syncQueue) bool {
+		remaining := make([]PendingSync, 0, len(queue.Pending))
+	NEXT:
+		for _, p := range queue.Pending {
+			for _, s := range synced {
+				if same(p, s) {
+					continue NEXT
+				}
+			}
+			for _, f := range failed {
+				if same(p, f) {
+					p.Error = f.Error
+				}
+			}
+			remaining = append(remaining, p)
+		}
+		queue.Pending = remaining
+		return len(synced) > 0 || len(failed) > 0
+	})
+	if err != nil {
+		util.Warn("Unable to write sync queue: %v", err)
+	}
+	if config.Verbose {
+		log.Printf("Synced %d pending Stack Instance %s", len(synced), util.Plural(len(synced), "state"))
+	}
+	if len(errs) > 0 {
+		log.Fatalf("Unable to sync pending Stack Instance state:\n\t%s", util.Errors("\n\t", errs...))
+	}
+}
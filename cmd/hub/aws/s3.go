@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	awsaws "github.com/aws/aws-sdk-go/aws"
@@ -132,3 +133,50 @@ func WriteS3(s3path string, body []byte) error {
 	}
 	return nil
 }
+
+func ListS3(s3prefix string, object func(path string, size int64, modTime time.Time)) error {
+	location, err := url.Parse(s3prefix)
+	if err != nil {
+		return err
+	}
+	s3, err := awsBucketS3(location.Host)
+	if err != nil {
+		return err
+	}
+	prefix := strings.TrimLeft(location.Path, "/")
+	err = s3.ListObjectsV2Pages(
+		&awss3.ListObjectsV2Input{
+			Bucket: &location.Host,
+			Prefix: &prefix,
+		},
+		func(page *awss3.ListObjectsV2Output, last bool) bool {
+			for _, obj := range page.Contents {
+				object(fmt.Sprintf("s3://%s/%s", location.Host, *obj.Key), *obj.Size, *obj.LastModified)
+			}
+			return true
+		})
+	if err != nil {
+		return fmt.Errorf("Failed to list S3 objects `%s`: %v\n\t%s", s3prefix, err, optionsHelp)
+	}
+	return nil
+}
+
+func DeleteS3(s3path string) error {
+	location, err := url.Parse(s3path)
+	if err != nil {
+		return err
+	}
+	s3, err := awsBucketS3(location.Host)
+	if err != nil {
+		return err
+	}
+	_, err = s3.DeleteObject(
+		&awss3.DeleteObjectInput{
+			Bucket: &location.Host,
+			Key:    &location.Path,
+		})
+	if err != nil {
+		return fmt.Errorf("Failed to DELETE S3 object `%s`: %v\n\t%s", s3path, err, optionsHelp)
+	}
+	return nil
+}
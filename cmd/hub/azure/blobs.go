@@ -117,3 +117,62 @@ func WriteStorageBlob(path string, body []byte) error {
 	}
 	return nil
 }
+
+func ListStorageBlobs(prefix string, object func(path string, size int64, modTime time.Time)) error {
+	location, err := url.Parse(prefix)
+	if err != nil {
+		return err
+	}
+	parts := strings.SplitN(location.Path, "/", 3)
+	if len(parts) < 2 || parts[1] == "" {
+		return errors.New("Bad path format")
+	}
+	container := parts[1]
+	namePrefix := ""
+	if len(parts) == 3 {
+		namePrefix = parts[2]
+	}
+	blobClient, err := storageClient(location.Host)
+	if err != nil {
+		return err
+	}
+	containerRef := blobClient.GetContainerReference(container)
+	marker := ""
+	for {
+		list, err := containerRef.ListBlobs(storage.ListBlobsParameters{
+			Prefix:  namePrefix,
+			Marker:  marker,
+			Timeout: storageTimeoutSec,
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to list Azure storage blobs `%s`: %v", prefix, err)
+		}
+		for _, blob := range list.Blobs {
+			object(fmt.Sprintf("az://%s/%s/%s", location.Host, container, blob.Name),
+				blob.Properties.ContentLength, time.Time(blob.Properties.LastModified))
+		}
+		if list.NextMarker == "" {
+			break
+		}
+		marker = list.NextMarker
+	}
+	return nil
+}
+
+func DeleteStorageBlob(path string) error {
+	account, container, name, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	blobClient, err := storageClient(account)
+	if err != nil {
+		return err
+	}
+	containerRef := blobClient.GetContainerReference(container)
+	blobRef := containerRef.GetBlobReference(name)
+	err = blobRef.Delete(&storage.DeleteBlobOptions{Timeout: storageTimeoutSec})
+	if err != nil {
+		return fmt.Errorf("Failed to delete Azure storage blob `%s`: %v", path, err)
+	}
+	return nil
+}
@@ -103,59 +103,68 @@ var _bindata = map[string]*asset{
 	"meta/manifest.schema.json": &asset{
 		name: "manifest.schema.json",
 		data: "" +
-			"\xec\x5b\x4b\x8f\xdb\x36\x10\xbe\xef\xaf\x10\xd4\x1e\x9d\xb8\x3d\x15\xd8\x6b\x1f\xb7\x02\x05\x52" +
-			"\xf4\x12\xf8\x40\x49\x23\x9b\x59\xbe\x3a\x24\x9d\x18\xc5\xfe\xf7\x42\xb6\xd6\x4f\x3e\x2d\xcb\xf5" +
-			"\xd6\xce\x21\x58\x8b\x23\x3e\x86\x33\x1f\xe7\x1b\x8e\xfe\x79\x2a\x8a\xa2\x28\xbf\xa7\x4d\xf9\x5c" +
-			"\x94\xda\x2a\xc0\x85\xad\x3e\x52\x39\xe5\x44\xd0\x16\xb4\xf9\xa8\xeb\x05\x70\xf2\xf1\x8b\x96\xa2" +
-			"\x9c\xf4\xe2\x9b\x67\xdd\x2b\x0b\x63\xd4\xf3\x74\xda\xb5\x7e\xe8\x25\x25\xce\xa7\x0d\x92\xd6\x7c" +
-			"\xf8\xe1\xa7\xe9\xe6\xd9\x77\x6f\x6f\x1a\x6a\x18\x74\xef\xfd\xde\x77\xbf\x6d\x58\xa9\xee\xf9\xe7" +
-			"\x52\x56\x5f\xa0\x36\xe5\xa4\x28\x85\x65\xac\x9c\xf5\xed\xa4\x69\xa8\xa1\x52\x10\xf6\x07\x4a\x05" +
-			"\x68\x28\xe8\xf2\xb9\x68\x09\xd3\xd0\x8b\xa8\xfd\x86\xcd\xc2\xd6\xcf\x97\x80\x9a\x4a\x71\xf0\x70" +
-			"\xdd\x00\xc2\xf2\x6e\xcc\x83\xa7\xdd\xbf\x1f\x0f\x9e\xcc\xb6\xbf\x5e\x27\xbb\x5e\x5f\xa8\x68\x32" +
-			"\xba\x2c\xb5\x21\xf5\x4b\x39\x39\x6d\x20\x4a\x31\x5a\x93\x6e\x71\xae\xe6\x5a\x72\x25\x05\x08\xe3" +
-			"\x6a\x54\x04\x09\x07\x03\xa8\xcb\x84\x29\x73\x30\xe4\x74\xca\xbd\xe6\xb7\x8a\x3f\x6c\x45\xf8\xdb" +
-			"\x52\x84\xc6\xbd\x28\x41\x38\x1c\x8d\x7c\xf4\xbe\x67\x53\x0e\x7b\x70\xb5\x1c\xcc\x4d\x1b\xa4\x62" +
-			"\x5e\x9e\x08\xbd\x3a\x74\xd2\xa2\xe4\x9f\xd6\xca\xbe\x68\xb7\x6f\x96\x7b\xc1\x2e\x2b\xa4\xd0\x5e" +
-			"\xb6\xcb\x06\x74\x8d\x54\x19\x97\xbd\x0f\xea\xb8\x26\x06\xe6\x12\x57\x97\xed\xd5\xe7\x9a\xc7\x9d" +
-			"\x7e\x76\xb6\xf6\x7e\xb5\x1e\x6e\xe2\x97\x10\x96\x57\x80\xa5\x53\x60\x96\x34\x4d\x4e\x8c\x45\x6a" +
-			"\x02\x8b\xf7\xfa\xfd\x56\x62\x4e\x42\x73\xac\x3a\xd7\x0c\xb4\x13\xa6\x16\x64\xc8\x12\x18\xad\x41" +
-			"\xe8\x0b\x1b\xb0\x96\x16\xeb\x84\x3e\x7b\x68\x39\xed\xf3\xc9\xfd\x6b\x1f\xb4\xb6\xf8\xa7\xfd\xd0" +
-			"\x45\x10\xc9\xea\x18\xb9\xa8\x01\xee\x01\x9d\x20\xe4\x25\x1e\x37\xe9\x28\xb9\xc3\x39\xf7\x06\xbf" +
-			"\xa9\xf1\xa4\x71\xe6\x42\xfc\x30\x9e\xc6\x31\x35\x69\xb3\x3d\x1b\xde\x43\x8c\x02\xd1\xe8\xa4\x01" +
-			"\xfc\xfe\xb0\xd1\xb2\x63\xdf\x1c\xee\xcb\x58\xe9\x15\x99\x05\x9c\xc6\x6f\x01\xd9\xca\x38\xb5\xd6" +
-			"\x98\x9a\x22\xbe\x91\x66\x87\x67\xda\x63\x8e\xb5\xec\xf6\x95\x62\x54\x28\x4b\x5f\x01\xed\xec\x50" +
-			"\x91\x9a\xbc\x41\xa3\xaa\x1a\xa8\xb2\x74\x97\x76\xbc\xc1\xa5\x81\x32\x2a\x3c\x4b\x18\x3d\x63\xe3" +
-			"\x8e\xc7\x4f\x95\xcf\xde\xcb\xc4\x3d\xdd\x9b\x4f\x7b\x3b\x93\xd1\xb6\xfa\x25\xd1\xc0\xaf\x32\x1f" +
-			"\x26\x6b\xc2\xae\x33\xa3\xa7\x61\x12\xaf\xb9\x80\x78\xd6\x31\xdf\xbb\x9b\xff\x90\x77\xb0\x0f\xdf" +
-			"\xf1\xe1\x38\x32\x66\xf9\xc1\x81\x4b\xc7\xee\xb9\x2b\x94\x4b\xda\xbc\xd3\xb9\x33\x62\x5a\x89\x3c" +
-			"\x97\x17\xa6\xa3\x6b\x94\x02\x7a\xd5\x97\xc1\x02\x62\x91\x44\x20\x8a\xf0\xc0\x71\x42\xf4\x90\x16" +
-			"\x46\x85\xdd\xc1\xbd\x2b\x8c\xb6\x50\xaf\x6a\x07\xe1\xbc\xde\xb6\x54\x04\x61\x08\xe1\x21\x8c\xc9" +
-			"\xaf\x43\x28\xcb\x12\xb0\xba\x1f\xa3\x70\x28\x40\x62\x03\x78\xd7\x0a\x50\x1b\x5b\xbe\x67\x1d\x70" +
-			"\x22\x1a\x62\x52\x32\x2f\xff\x63\x25\x78\xa3\x83\x3c\x7e\x70\x06\x27\x48\x8d\xc4\xe3\xb6\x9a\xc7" +
-			"\x8d\x93\xf9\x71\x02\x47\x4e\x20\x1d\x89\x5c\x39\x9f\xff\x0d\x0f\x1e\x9d\xe6\x40\x9a\xd5\xcf\x52" +
-			"\x6c\x36\xf3\x1d\x9f\x11\xb7\x41\xfc\x85\xbe\x3e\xf1\xb7\xc8\xae\x3f\xe8\x57\x42\xcd\x27\xa8\x65" +
-			"\x2c\x7d\x75\x32\x38\x15\x06\xe6\xbe\x1c\x72\xea\xe8\x8a\x58\x0d\x23\x0e\x3f\x8a\xab\x6d\x60\xed" +
-			"\x96\x81\x17\x89\x68\x24\x4f\x4f\xf3\x25\x65\x90\x06\x64\x8f\x72\x73\x37\x65\xb5\x32\x39\x69\x9e" +
-			"\x2c\xa3\xb8\x15\x7a\x0f\xdf\x0c\x08\xed\xb4\xa4\x08\xa3\x89\xd1\x14\x2a\x6a\x66\x1b\xb8\xe7\xe8" +
-			"\xa8\x96\xa2\xa5\x73\x8b\x77\xad\x84\x06\x14\x93\xab\xa1\x38\x95\x0a\x3a\x15\xb4\x12\xe1\x11\xeb" +
-			"\x25\x22\x49\x28\xc0\x69\x0d\xe0\x43\x91\xe3\x9f\xe4\x56\x3c\x5c\xe4\xe1\x22\x0f\x17\xc9\x88\x5a" +
-			"\xf6\xca\xab\x6e\x26\xb5\x7f\xed\x9a\x85\x77\x52\x95\xb0\x2b\x93\x1b\x6d\x88\x25\x61\x76\xbd\x02" +
-			"\x7f\x65\x44\x4b\x2c\x33\x21\x11\xe0\x2a\x50\x46\x94\x96\x59\x8f\x65\xd7\xdd\x19\xf6\xd0\xca\x9c" +
-			"\xb5\x8c\x67\x4c\xca\x6a\xc0\x18\xb1\x32\x50\x2f\x62\x32\x8c\x8a\x97\x4b\xad\x2d\x5c\x64\x37\xd8" +
-			"\x28\x5a\x94\xfc\x57\xb1\x1c\x77\x80\xdf\x28\x1b\xd1\x75\x60\xcc\xe9\x07\x20\x34\xff\xf0\xba\xb1" +
-			"\x82\xa1\xb4\x4c\x42\x56\x21\x89\x07\x73\x73\x4e\xdc\xdc\xf4\x43\x14\x97\xcf\x3e\xa4\x6f\x25\xf9" +
-			"\x60\x80\x2b\x46\x0c\xe8\xff\xf0\x36\x35\x08\xb1\x09\xb7\xa9\xb5\x45\x16\xa4\xe7\xdc\x6a\x43\xea" +
-			"\x05\x84\x64\xe6\x72\xc8\x7d\x6c\x4b\x19\xdc\xf5\x7d\x6c\x43\x11\x6a\x23\x91\xde\xb7\x1a\xe0\x9b" +
-			"\x41\xf2\xb8\x73\x19\x74\xe7\x12\x8d\xb8\xf2\x22\xaf\x64\x98\xc8\x85\x8c\x24\xf8\x88\x07\x67\xa9" +
-			"\x57\x24\x61\x88\x39\x8f\xed\x66\x31\xde\x44\xd6\x5b\x24\x96\x71\xa6\xb3\xdf\x31\x0e\xd7\x49\xb4" +
-			"\xe0\x37\x0a\x67\x0f\x95\xdf\x60\x3c\x23\xad\x51\xd6\x3c\x72\x12\xb7\x9e\x93\xd8\x26\x0c\xe2\xb4" +
-			"\x67\xfb\x19\x53\x51\x56\x52\x32\x20\xa2\xfb\xf3\xed\x8a\x6f\xb2\xfd\x88\xe9\xd2\xbc\x7e\xc8\xf2" +
-			"\xfc\x45\x8a\x67\xa4\x0e\xb6\xe5\x33\x51\x36\x65\x35\x5c\x2a\x37\xd0\x71\xeb\x3f\xdb\xbf\x08\x8e" +
-			"\xa7\xa4\x91\xd3\x0f\x29\xdf\xfb\x15\x17\x2a\x55\xdd\x47\xa3\xa7\xcd\xff\xaf\x4f\xff\x06\x00\x00" +
-			"\xff\xff",
-		size: 15425,
+			"\xec\x5c\x4d\x6f\xe3\x36\x13\xbe\xfb\x57\x10\xdc\xbd\xad\x93\xbc\xef\xa9\x40\xae\xed\xf6\x56\xa0" +
+			"\x40\x8a\x5e\x02\x17\xa0\xa4\x91\xcd\x0d\x45\xaa\xfc\xf0\xae\x51\xe4\xbf\x17\x8a\x65\x3b\x8e\x29" +
+			"\x72\x68\x49\x8e\x53\x7b\x4f\x6b\x71\xc4\x21\x9f\x99\x79\x44\x0e\x87\xf9\x67\x42\x08\x21\xf4\x33" +
+			"\x2f\xe8\x3d\xa1\xc6\xd5\xa0\x17\x2e\xbb\xe5\xea\xae\x62\x92\x97\x60\xec\xad\xc9\x17\x50\xb1\xdb" +
+			"\x6f\x46\x49\x3a\x6d\xc5\xd7\xcf\x9a\x57\x16\xd6\xd6\xf7\x77\x77\x4d\xeb\x4d\x2b\xa9\xf4\xfc\xae" +
+			"\xd0\xac\xb4\x37\xff\xfb\xe9\x6e\xfd\xec\xd3\xe6\x4d\xcb\xad\x80\xe6\xbd\xdf\xda\xee\xb7\x0d\xab" +
+			"\xba\x79\xfe\x48\x55\xf6\x0d\x72\x4b\xa7\x84\x4a\x27\x04\x9d\xb5\xed\xac\x28\xb8\xe5\x4a\x32\xf1" +
+			"\xbb\x56\x35\x68\xcb\xc1\xd0\x7b\x52\x32\x61\xa0\x15\xa9\x5f\x37\xac\x27\x46\x08\x21\x74\x09\xda" +
+			"\x70\x25\xf7\x1e\x12\x42\x08\x05\xe9\xaa\x46\xe7\xde\x53\x42\x08\xf9\xff\xde\x93\xd9\xf6\xd7\xf3" +
+			"\x74\xd7\xeb\x13\x97\x45\x42\x97\xd4\x58\x96\x3f\xd1\xe9\x61\x03\xab\x6b\xc1\x73\xd6\x4c\xce\xd7" +
+			"\x9c\xab\xaa\x56\x12\xa4\xf5\x35\xd6\x4c\xb3\x0a\x2c\x68\x43\x11\x43\xae\xc0\xb2\xc3\x21\xb7\xc8" +
+			"\x6f\x81\xdf\x6f\xd5\xf0\xb7\xe3\x1a\x0a\xff\xa4\x24\xab\xe0\x8d\xe6\x37\xef\x77\x18\x65\xbf\x07" +
+			"\x5f\xcb\xde\xd8\x8c\xd5\x5c\xce\xe9\x81\xd0\xb3\x07\x93\x52\xab\xea\xe1\x05\xec\x41\xbb\xdd\x78" +
+			"\xee\x80\x5d\x66\x9a\x43\x39\x6c\x97\x05\x98\x5c\xf3\xda\xfa\xfc\xbd\x57\xc7\x39\xb3\x30\x57\x7a" +
+			"\x35\x6c\xaf\x5d\xa1\xf9\xb6\xd3\x47\x6f\x6b\x1b\x57\x2f\xea\xa6\xdd\x12\xd2\x55\x19\x68\xea\x15" +
+			"\x98\xa1\x86\x59\x31\xeb\x34\xb7\x81\xc9\x77\xc6\xfd\xe6\x1f\x9d\xb3\xd0\x18\x33\xb0\xc1\x76\x26" +
+			"\xea\x05\xeb\x33\x05\xc1\x73\x90\x66\x60\x07\x36\xca\xe9\x1c\xd1\x67\x4b\x2d\x87\x7d\x4e\xfc\xbf" +
+			"\x5e\xe9\xda\xf1\x9f\xe9\xa6\x2e\xa6\x35\x5b\xbd\x65\x2e\x6e\xa1\xea\x20\x9d\x20\xe5\x21\x3f\x37" +
+			"\x78\x96\xdc\xf1\xdc\xd4\xdf\xd6\xc2\x78\xd0\x38\xf3\x31\x7e\x98\x4f\xe3\x9c\x8a\x32\x76\x87\xc1" +
+			"\x5b\x8a\xa9\x41\x16\x06\xa5\xa0\x3b\x1e\x08\x21\x7e\xbb\x79\xc2\x57\x08\xda\x29\x32\xeb\x7e\x3b" +
+			"\xe0\x01\xc9\x60\x1c\x7a\x6b\x0c\xa6\x52\xe9\xaf\x2c\x5f\x0c\x01\x53\x94\xe2\xf0\x60\x76\x7a\x7c" +
+			"\x0a\xe4\x49\x38\x00\x16\x84\xf8\xd8\x12\xa3\x32\x25\x66\x76\x00\x95\x51\x99\x24\xa7\x09\x40\xb3" +
+			"\xf9\x47\x9f\x60\x35\x82\xd2\x81\xfc\x38\xc2\xf1\xe7\x67\xc1\x82\xeb\xd3\x9b\x70\xce\x6d\x9a\x52" +
+			"\x54\x20\xf6\x80\x0c\xff\x69\xf2\xbc\x51\x29\x0b\x34\x2a\x3c\x43\x68\x4f\x30\xdc\x5b\xfd\x58\xf9" +
+			"\x64\x5b\x22\x6d\xfa\x6a\x3c\xe5\xf9\x0c\xc6\xb8\xec\x17\xa4\x83\x9f\x64\x3c\x42\xe5\x4c\x9c\x66" +
+			"\x44\x93\x7e\x12\xb1\x10\xd6\x30\xe7\xc6\xea\x73\xa0\xe2\xa3\x16\xca\x6d\xa0\x77\x2f\x93\x1f\x27" +
+			"\xe8\x35\x83\x67\x05\x30\x4b\x5f\x5e\xfb\xb0\xf1\x8f\xbd\xd6\x6a\xc9\x8b\x0f\x3a\x76\xc1\x6c\xa9" +
+			"\x74\x95\x9a\x59\xc1\xf3\x7a\x34\x89\xd2\x09\x5f\x1c\xc6\x28\x9c\x01\x58\x23\x1f\x02\xc4\xfa\x1b" +
+			"\xb7\x11\x09\x87\x83\xdf\x2a\x82\x97\x90\xaf\x72\x4f\xca\xe6\x74\x66\xc9\x98\x86\x3e\x29\x03\x26" +
+			"\x84\xfa\xde\x67\xd3\xbf\x04\x9d\x5d\x8e\x53\x78\x00\x50\xba\x00\x7d\xd1\x00\xd4\x6b\x5f\xbe\x64" +
+			"\x0c\x2a\x26\x0b\x66\x31\xb9\xcb\xff\x30\x08\x9d\xab\x03\x1c\x2d\x1e\x41\x8f\x58\x9a\xc4\xfb\x2a" +
+			"\xde\x5c\x68\xb3\x21\xcc\x17\x31\x63\x82\x39\x93\xcc\xda\x6d\xde\x70\x0b\xd6\x1d\x58\xb1\xfa\x59" +
+			"\xc9\xb5\x31\x3f\xf0\x37\xe2\x3c\x52\x0e\xd2\x9c\x3e\xe5\xe0\xb4\x38\xbd\xd2\xef\x8c\xdb\x07\xc8" +
+			"\x55\x2c\x01\x7c\xa0\x9c\x4b\x0b\xf3\xae\x53\x18\xac\xf6\x9a\x39\x03\x23\xaa\x1f\x25\xd4\xd6\xb4" +
+			"\x76\xce\xc4\xab\x99\x2c\x54\x85\x4f\x94\xe3\x92\xc8\xc7\xe7\xad\x52\xb3\x46\x34\x5b\xd9\x94\x04" +
+			"\x53\x92\x53\x90\x78\x62\xe1\x24\xdb\x7b\xf8\x61\x41\x1a\xaf\x27\x45\x76\x34\xb1\x6d\x0a\x97\xb9" +
+			"\x70\x05\x5c\xf2\xea\x28\x57\xb2\xe4\x73\xa7\x2f\x1a\x84\x02\x6a\xa1\x56\x7d\x79\x0a\x4b\x3a\x19" +
+			"\x94\x4a\xc3\x75\xad\x87\x64\x92\xd0\x02\xa7\xb4\xa0\xaf\x40\x0e\x4c\xc9\x9e\x00\x71\xf2\x1a\x22" +
+			"\xd7\x10\xb9\x86\x88\xf7\x49\x47\x72\x7c\x57\xa0\x78\x36\xa9\xfd\x53\x57\xfd\x7c\x90\xba\x9e\x5d" +
+			"\xa1\xe9\x68\x2a\x96\x4c\xb8\x97\x19\x74\xd7\x16\x95\xcc\x09\x1b\x12\x81\xaa\xb6\xe1\x33\xba\x78" +
+			"\x66\x9d\x44\xb2\xeb\x24\xb9\xca\xc5\x5b\x0d\x7c\xc4\xa0\x9c\x01\x1d\xdb\x58\x59\xc8\x17\x31\x19" +
+			"\xc1\xe5\xd3\x50\x73\x0b\x97\xa9\xf6\x76\x8a\xa6\x5e\xf7\xab\x5c\x8e\xab\xe0\x57\x2e\x60\x5c\x0d" +
+			"\x2f\x25\xc7\x0f\x96\xd9\x34\x3d\xa1\x0c\x14\xb3\x16\xb4\x6c\x84\xff\xba\xfd\xf2\xe9\xf6\xcb\xe7" +
+			"\xb4\x51\xc1\x98\xa0\x7e\xb8\xb2\xa0\x0a\xf2\x05\x93\xdc\x54\xa7\x4f\x9a\x95\x31\xe7\x1b\x45\xab" +
+			"\xe0\x12\xde\x21\x47\x87\xad\x96\x79\x97\x62\xb6\xc0\x72\xc4\x37\xb6\x0f\x55\xbe\x8a\xcb\xca\x25" +
+			"\x95\x83\x75\xac\x5f\x90\x13\x20\xc7\xa4\xf2\xa2\x6b\x9c\xa3\x7d\x88\x9c\x49\x22\xcf\x42\xd5\x94" +
+			"\x8c\x80\x79\xc7\xca\x84\xe0\x72\x05\x51\x99\x90\x3b\x2d\x82\xa9\xae\xca\x35\xf7\x9d\x16\x10\x92" +
+			"\x99\xab\x3e\xb5\x0d\x0d\xa9\x5e\x74\x6d\x43\xc1\x35\xe4\x56\x69\x7e\xd9\x30\xc0\x0f\xab\xd9\xf5" +
+			"\xfc\xb2\x0f\xf1\xc6\x77\x2f\x78\x6a\x48\xa2\x89\x54\xca\x40\xd1\x47\x98\x4a\x22\xee\x94\x40\x31" +
+			"\x69\x6e\x96\xe4\x72\x48\xf7\x43\xb8\x62\xa2\x5b\x26\xc7\x68\x38\x5e\x53\xc0\xc6\xd0\xd9\x15\xf2" +
+			"\x34\xc8\x4f\xb2\x9e\x91\xca\xf2\xb2\xbd\xc0\x7c\x99\x59\xbe\xa6\x12\x63\xb0\x24\x5f\xac\xac\xa3" +
+			"\xd7\xae\x1d\x96\xde\x8b\x94\xe9\x71\x75\x26\x7b\x1f\xf4\xe7\xa8\xb9\x7e\xaf\x51\xd7\x6d\x4a\x2e" +
+			"\xb9\x59\x60\x24\x8d\xcb\x73\x30\x06\xd5\x29\xe3\xa2\x39\x5d\x46\x88\x6e\xd3\xb0\x37\x9b\x97\xc2" +
+			"\xfb\xaf\x81\x76\xc6\xe1\x52\x61\x72\xbd\xd3\xf9\x7a\xeb\x36\x5e\x80\x2e\x80\x15\xd8\xec\xc4\xf1" +
+			"\x6b\xc7\x71\xc1\x3b\xea\x2b\xa2\x9c\xad\x9d\xbd\x9e\x12\xf5\xff\x80\x8c\x7b\x4a\xb4\x3d\xc2\x89" +
+			"\xf3\xc4\xa6\xff\x29\xa1\x99\x52\x02\x98\xa4\xd3\x5d\x92\x71\xba\xfd\xc3\x0c\x43\x9f\xb4\xf4\x99" +
+			"\x5e\xf7\xb5\x91\xf4\xef\xce\xae\xa0\x39\x9a\x93\x73\x06\x86\x3a\xad\x69\xce\x22\xfe\x28\xff\x64" +
+			"\x7a\x3c\x90\x46\x3e\x10\xc2\xfc\x0d\x13\x32\xd0\xe5\xa1\x57\xbf\xd6\xff\x7b\x9e\x3c\x4f\xfe\x1d" +
+			"\x00",
+		size: 18709,
 		mode: 0644,
-		time: time.Unix(1610907043, 136070583),
+		time: time.Unix(1792368430, 269108351),
 	},
 	"cmd/hub/api/requests/aks-adapter-instance.json.template": &asset{
 		name: "aks-adapter-instance.json.template",
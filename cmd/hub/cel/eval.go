@@ -18,25 +18,25 @@ func Eval(expression, vars string, autoVars, yamlValue bool) {
 		log.Fatalf("Unable to parse variable bindings: %v\n", err)
 	}
 	activation := &verboseActivation{bindings, autoVars}
-	env, err := cel.NewEnv()
+	env, err := parameters.NewCelEnv()
 	if err != nil {
 		log.Fatalf("Unable to init CEL runtime: %v\n", err)
 	}
-	var out interface{}
+	var out string
 	if yamlValue {
 		out = yamlExpression(expression, env, activation)
 	} else {
 		out = plainExpression(expression, env, activation)
 	}
-	fmt.Printf("%+v\n", out)
+	fmt.Printf("%s\n", out)
 }
 
-func plainExpression(expression string, env *cel.Env, activation interpreter.Activation) interface{} {
-	ast, issues := env.Parse(expression)
-	if issues != nil && issues.Err() != nil {
-		log.Fatalf("CEL parse error: %s\n", issues.Err())
+func plainExpression(expression string, env *cel.Env, activation interpreter.Activation) string {
+	ast, err := parameters.CelParse(env, expression)
+	if err != nil {
+		log.Fatalf("CEL parse error: %s\n", err)
 	}
-	program, err := env.Program(ast)
+	program, err := env.Program(ast, parameters.CelProgramOptions(activation))
 	if err != nil {
 		log.Fatalf("CEL program construction error: %s\n", err)
 	}
@@ -44,7 +44,11 @@ func plainExpression(expression string, env *cel.Env, activation interpreter.Act
 	if err != nil {
 		log.Fatalf("CEL evaluation error: %v\n", err)
 	}
-	return out
+	str, err := parameters.CelString(out)
+	if err != nil {
+		log.Fatalf("CEL evaluation error: %v\n", err)
+	}
+	return str
 }
 
 func yamlExpression(yamlExpression string, env *cel.Env, activation interpreter.Activation) string {
@@ -54,8 +58,7 @@ func yamlExpression(yamlExpression string, env *cel.Env, activation interpreter.
 			if !isCel {
 				util.Warn("`%s` is not a CEL substitution", match)
 			}
-			result := plainExpression(expression, env, activation)
-			return fmt.Sprintf("%+v", result)
+			return plainExpression(expression, env, activation)
 		})
 	return expanded
 }
@@ -2,14 +2,20 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/agilestacks/hub/cmd/hub/api"
 	"github.com/agilestacks/hub/cmd/hub/compose"
+	"github.com/agilestacks/hub/cmd/hub/config"
 	"github.com/agilestacks/hub/cmd/hub/lifecycle"
+	"github.com/agilestacks/hub/cmd/hub/report"
+	"github.com/agilestacks/hub/cmd/hub/state"
 	"github.com/agilestacks/hub/cmd/hub/util"
 )
 
@@ -19,12 +25,20 @@ var (
 	backupRenameComponents      string
 	backupEraseComponents       string
 	backupIncludeOnlyComponents string
+	backupKeepLast              int
+	backupKeepDaily             int
+	backupRestoreElaborate      string
+	backupParallel              int
+	backupIncremental           string
+	backupSign                  bool
+	backupRequireSignature      bool
 )
 
 var backupCmd = &cobra.Command{
-	Use:   "backup <create | unbundle> ...",
+	Use:   "backup <create | unbundle | verify | list | prune | restore> ...",
 	Short: "Create and manage backups",
-	Long:  `Create backup of stack components; transform backup bundle into parameters manifest.`,
+	Long: `Create backup of stack components; transform backup bundle into parameters manifest;
+verify, list, and prune bundles; restore stack components from bundle.`,
 }
 
 var backupCreateCmd = &cobra.Command{
@@ -32,6 +46,14 @@ var backupCreateCmd = &cobra.Command{
 	Short: "Create backup bundle",
 	Long: `Create backup of stack component(s).
 Each stack component that supports 'backup' verb is invoked.
+With --parallel N, up to N components that do not depend on each other are invoked concurrently;
+components sharing source directory, such as forEach instances, run one at a time unless --workspace is set.
+With --incremental, previous bundle's component outputs are passed to the implementation
+as HUB_PREVIOUS_BACKUP_* environment variables so that an incremental snapshot could be taken,
+for example snapshot.id output becomes HUB_PREVIOUS_BACKUP_SNAPSHOT_ID, and also
+HUB_PREVIOUS_BACKUP_TIMESTAMP and HUB_PREVIOUS_BACKUP_KIND are set.
+Bundle carries content checksum; with --sign the checksum is also signed with a key
+from HUB_CRYPTO_* setup.
 Bundle can be saved into multiple files and also sent to S3.`,
 	Annotations: map[string]string{
 		"usage-metering": "tags",
@@ -67,6 +89,11 @@ func backupCreate(args []string, pipe io.WriteCloser) error {
 	stateManifests := util.SplitPaths(stateManifestExplicit)
 	bundleFiles := util.SplitPaths(outputFiles)
 	components := util.SplitPaths(componentName)
+	if reportFile != "" {
+		if _, err := report.Format(reportFile); err != nil {
+			return err
+		}
+	}
 
 	setOsEnvForNestedCli(manifests, stateManifests, componentsBaseDir)
 
@@ -78,12 +105,17 @@ func backupCreate(args []string, pipe io.WriteCloser) error {
 		Components:        components,
 		OsEnvironmentMode: osEnvironmentMode,
 		ComponentsBaseDir: componentsBaseDir,
+		Workspace:         useWorkspace || keepWorkspace,
+		KeepWorkspace:     keepWorkspace,
 		Environment:       hubEnvironment,
 		StackInstance:     hubStackInstance,
 		Application:       hubApplication,
+		Parallel:          backupParallel,
+		PreviousBackup:    util.SplitPaths(backupIncremental),
+		Report:            reportFile,
 	}
 
-	lifecycle.BackupCreate(request, bundleFiles, backupBundleInJson, backupAllowPartial, pipe)
+	lifecycle.BackupCreate(request, bundleFiles, backupBundleInJson, backupAllowPartial, backupSign, pipe)
 
 	return nil
 }
@@ -108,6 +140,160 @@ func backupUnbundle(args []string) error {
 	return nil
 }
 
+var backupVerifyCmd = &cobra.Command{
+	Use:   "verify bundle.yaml[,s3://bucket/bundle.yaml] [-m hub.yaml.elaborate -s hub.yaml.state]",
+	Short: "Verify backup bundle",
+	Long: `Verify backup bundle checksum and signature (if present or --require-signature is set).
+Signature is verified with a key from HUB_CRYPTO_* setup.
+If Stack Elaborate is specified, then each component that supports 'backup-verify' verb is invoked
+to confirm that the referenced snapshots still exist. Component's backup outputs are passed to
+the implementation as HUB_BACKUP_* environment variables, for example snapshot.id output becomes
+HUB_BACKUP_SNAPSHOT_ID, and also HUB_BACKUP_TIMESTAMP and HUB_BACKUP_KIND are set.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return backupVerify(args)
+	},
+}
+
+func backupVerify(args []string) error {
+	if len(args) != 1 {
+		return errors.New("Backup Verify command has only one argument - path to Backup Bundle file(s)")
+	}
+
+	manifests := util.SplitPaths(elaborateManifest)
+	stateManifests := util.SplitPaths(stateManifestExplicit)
+	if len(manifests) > 0 {
+		setOsEnvForNestedCli(manifests, stateManifests, componentsBaseDir)
+	}
+
+	request := &lifecycle.Request{
+		Verb:              "backup-verify",
+		ManifestFilenames: manifests,
+		StateFilenames:    stateManifests,
+		Components:        util.SplitPaths(componentName),
+		OsEnvironmentMode: osEnvironmentMode,
+		ComponentsBaseDir: componentsBaseDir,
+	}
+
+	lifecycle.BackupVerify(request, util.SplitPaths(args[0]), backupRequireSignature)
+
+	return nil
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list <prefix>",
+	Short: "List backup bundles",
+	Long: `List backup bundles under prefix, newest first.
+Prefix is a directory or a path prefix on any supported storage, for example:
+backups/, s3://bucket/backups/app-, gs://bucket/backups/, az://account/container/backups/`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return backupList(args)
+	},
+}
+
+var backupPruneCmd = &cobra.Command{
+	Use:   "prune <prefix> --keep-last N --keep-daily D",
+	Short: "Remove old backup bundles",
+	Long: `Remove backup bundles under prefix according to retention policy.
+The N most recent successful bundles are kept, plus the most recent successful bundle of each of
+the D most recent days. Unsuccessful (error, partial) bundles do not count toward retention and are
+kept only if newer than the most recent successful bundle.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return backupPrune(args)
+	},
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore bundle.yaml[,s3://bucket/bundle.yaml] hub.yaml [params.yaml ...] -s hub.yaml.state",
+	Short: "Restore stack components from backup bundle",
+	Long: `Restore stack components from backup bundle:
+1. transform bundle into parameters, as in 'backup unbundle',
+2. elaborate stack manifest with the restore parameters,
+3. deploy components present in the bundle.
+The restore is recorded in the state operations log.`,
+	Annotations: map[string]string{
+		"usage-metering": "tags",
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pipe := cmdContextPipe(cmd)
+		if pipe != nil {
+			defer pipe.Close()
+		}
+		return backupRestore(args, pipe)
+	},
+}
+
+func backupList(args []string) error {
+	if len(args) != 1 {
+		return errors.New("Backup List command has one argument - bundles location prefix")
+	}
+	state.ListBackups(args[0])
+	return nil
+}
+
+func backupPrune(args []string) error {
+	if len(args) != 1 {
+		return errors.New("Backup Prune command has one argument - bundles location prefix")
+	}
+	if backupKeepLast < 0 || backupKeepDaily < 0 {
+		return errors.New("--keep-last and --keep-daily must not be negative")
+	}
+	state.PruneBackups(args[0], backupKeepLast, backupKeepDaily, dryRun)
+	return nil
+}
+
+func backupRestore(args []string, pipe io.WriteCloser) error {
+	if len(args) < 2 {
+		return errors.New("Backup Restore command has two or more arguments - path to Backup Bundle file(s), to Stack Manifest file, and optionally to Parameters files")
+	}
+	if stateManifest == "" && !config.Force {
+		return errors.New("State file (-s) must be specified to restore components")
+	}
+
+	bundles := [][]string{strings.Split(args[0], ",")}
+	stackManifestFilename := args[1]
+	parametersFiles := args[2:]
+	rename := util.SplitPaths(backupRenameComponents)
+	erase := util.SplitPaths(backupEraseComponents)
+	includeOnly := util.SplitPaths(backupIncludeOnlyComponents)
+
+	components := compose.BackupComponents(bundles, rename, erase, includeOnly)
+	if len(componentName) > 0 {
+		only := util.SplitPaths(componentName)
+		selected := make([]string, 0, len(components))
+		for _, component := range components {
+			if util.Contains(only, component) {
+				selected = append(selected, component)
+			}
+		}
+		components = selected
+	}
+	if len(components) == 0 {
+		return fmt.Errorf("No components to restore from %v", args[0])
+	}
+
+	restoreParams, err := ioutil.TempFile("", "hub-restore-params-*.yaml")
+	if err != nil {
+		return fmt.Errorf("Unable to create restore parameters file: %v", err)
+	}
+	restoreParams.Close()
+	defer os.Remove(restoreParams.Name())
+
+	compose.BackupUnbundle(bundles, []string{restoreParams.Name()}, rename, erase, includeOnly)
+
+	elaborateManifests := util.SplitPaths(backupRestoreElaborate)
+	compose.Elaborate(stackManifestFilename, append(parametersFiles, restoreParams.Name()), environmentOverrides, "",
+		util.SplitPaths(stateManifest), false, elaborateManifests, componentsBaseDir, "", "", nil)
+
+	componentName = strings.Join(components, ",")
+	request, err := lifecycleRequest([]string{strings.Join(elaborateManifests, ",")}, "deploy")
+	if err != nil {
+		return err
+	}
+	request.Restore = bundles[0]
+	lifecycle.Execute(request, pipe)
+	return nil
+}
+
 var apiBackupCmd = &cobra.Command{
 	Use:   "backup <get | delete> ...",
 	Short: "List and manage Stack Instance backups",
@@ -166,8 +352,30 @@ func init() {
 		"A list of components to backup (in order, separated by comma)")
 	backupCreateCmd.Flags().BoolVarP(&backupAllowPartial, "allow-partial", "", false,
 		"Allow partial backups to succeed")
+	backupCreateCmd.Flags().IntVarP(&backupParallel, "parallel", "", 1,
+		"Backup up to N independent components concurrently")
+	backupCreateCmd.Flags().StringVarP(&backupIncremental, "incremental", "", "",
+		"Previous backup bundle file(s) to base incremental backup on, for example s3://bucket/bundle.yaml")
+	backupCreateCmd.Flags().StringVarP(&reportFile, "report", "", "",
+		"Write backup report to file: out.md, out.html, or junit.xml")
 	initCommonLifecycleFlags(backupCreateCmd, "backup")
 
+	backupCreateCmd.Flags().BoolVarP(&backupSign, "sign", "", false,
+		"Sign bundle with a key from HUB_CRYPTO_* setup")
+
+	backupVerifyCmd.Flags().BoolVarP(&backupRequireSignature, "require-signature", "", false,
+		"Fail if bundle is not signed")
+	backupVerifyCmd.Flags().StringVarP(&elaborateManifest, "elaborate", "m", "",
+		"Path to Stack Elaborate file to invoke components 'backup-verify' verb")
+	backupVerifyCmd.Flags().StringVarP(&stateManifestExplicit, "state", "s", "",
+		"Path to state file(s), for example hub.yaml.state,s3://bucket/hub.yaml.state")
+	backupVerifyCmd.Flags().StringVarP(&componentName, "components", "c", "",
+		"A list of components to verify (separated by comma)")
+	backupVerifyCmd.Flags().StringVarP(&componentsBaseDir, "base-dir", "b", "",
+		"Path to component sources base directory (default to manifest dir)")
+	backupVerifyCmd.Flags().StringVarP(&osEnvironmentMode, "os-environment", "", "no-tfvars",
+		"OS environment mode for child process, one of: everything, no-tfvars, strict")
+
 	backupUnbundleCmd.Flags().StringVarP(&outputFiles, "output", "o", "",
 		"Parameters output file(s), optionally write to S3 (default to stdout)")
 	backupUnbundleCmd.Flags().StringVarP(&backupRenameComponents, "rename", "r", "",
@@ -177,8 +385,29 @@ func init() {
 	backupUnbundleCmd.Flags().StringVarP(&backupIncludeOnlyComponents, "include-only", "i", "",
 		"Include only specified components, for example -i postgresql,postgresql-rds")
 
+	backupPruneCmd.Flags().IntVarP(&backupKeepLast, "keep-last", "", 0,
+		"Keep N most recent successful bundles")
+	backupPruneCmd.Flags().IntVarP(&backupKeepDaily, "keep-daily", "", 0,
+		"Keep the most recent successful bundle of each of D most recent days")
+	backupPruneCmd.Flags().BoolVarP(&dryRun, "dry", "y", false,
+		"Print bundles to remove, do not remove")
+
+	backupRestoreCmd.Flags().StringVarP(&backupRestoreElaborate, "elaborate", "m", "hub.yaml.elaborate",
+		"Path to Stack Elaborate file to write")
+	backupRestoreCmd.Flags().StringVarP(&backupRenameComponents, "rename", "", "",
+		"Components to rename, for example --rename pg1:postgresql,pg2:postgresql-rds")
+	backupRestoreCmd.Flags().StringVarP(&backupEraseComponents, "erase", "", "",
+		"Components to omit from restore, for example --erase etcd,vault")
+	backupRestoreCmd.Flags().StringVarP(&backupIncludeOnlyComponents, "include-only", "i", "",
+		"Restore only specified components, for example -i postgresql,postgresql-rds")
+	initDeployUndeployFlags(backupRestoreCmd, "restore")
+
 	backupCmd.AddCommand(backupCreateCmd)
 	backupCmd.AddCommand(backupUnbundleCmd)
+	backupCmd.AddCommand(backupVerifyCmd)
+	backupCmd.AddCommand(backupListCmd)
+	backupCmd.AddCommand(backupPruneCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
 	RootCmd.AddCommand(backupCmd)
 
 	apiBackupGetCmd.Flags().BoolVarP(&showLogs, "logs", "l", false,
@@ -27,6 +27,14 @@ pd-ssd
 
 $ hub cel -y '#{3 - int({"prime": "7"}[prime])}-${q}' prime=prime,q=x
 -4-x
+
+Hub CEL library adds s.lower(), s.upper(), list.join(sep), base64.encode(), base64.decode(),
+json.encode(), json.decode(), yaml.decode(), net.cidrSubnet(cidr, newbits, num), net.cidrHost(cidr, num),
+semver.compare(a, b), semver.matches(version, constraint), default(name, fallback), has(name),
+and lookup(output, component) to the standard string extensions.
+
+$ hub cel 'default(dns.name, net.cidrHost(cidr, 10))' cidr=10.0.1.0/24
+10.0.1.10
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return celEval(args)
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agilestacks/hub/cmd/hub/lifecycle"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+var (
+	checkVerb   string
+	checkRecord bool
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check hub.yaml.elaborate -s hub.yaml.state[,s3://bucket/hub.yaml.state]",
+	Short: "Detect drift between live resources and state",
+	Long: `Invoke 'check' (or 'plan') verb on each deployed component that implements it, with the same
+parameters and outputs that deploy would use. Outputs reported by the implementation are compared
+with outputs captured in state, and the drift is reported per component.
+Exit code is non-zero if any component drifted or failed to check.
+Use --record to save drift status of each component in state.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return check(args)
+	},
+}
+
+func check(args []string) error {
+	if len(args) != 1 {
+		return errors.New("Check command has one mandatory argument - path to Stack Elaborate file(s)")
+	}
+	if stateManifestExplicit == "" {
+		return errors.New("State file(s) must be specified by -s / --state")
+	}
+
+	manifests := util.SplitPaths(args[0])
+	stateManifests := util.SplitPaths(stateManifestExplicit)
+	setOsEnvForNestedCli(manifests, stateManifests, componentsBaseDir)
+
+	request := &lifecycle.Request{
+		Verb:              checkVerb,
+		ManifestFilenames: manifests,
+		StateFilenames:    stateManifests,
+		Components:        util.SplitPaths(componentName),
+		OsEnvironmentMode: osEnvironmentMode,
+		ComponentsBaseDir: componentsBaseDir,
+	}
+
+	lifecycle.CheckDrift(request, checkRecord)
+
+	return nil
+}
+
+func init() {
+	checkCmd.Flags().StringVarP(&stateManifestExplicit, "state", "s", "",
+		"Path to state file(s)")
+	checkCmd.Flags().StringVarP(&componentName, "components", "c", "",
+		"A list of components to check (separated by comma)")
+	checkCmd.Flags().StringVarP(&componentsBaseDir, "base-dir", "b", "",
+		"Path to component sources base directory (default to manifest dir)")
+	checkCmd.Flags().StringVarP(&osEnvironmentMode, "os-environment", "", "no-tfvars",
+		"OS environment mode for child process, one of: everything, no-tfvars, strict")
+	checkCmd.Flags().StringVarP(&checkVerb, "verb", "", "",
+		"Verb to invoke (default to 'check', then 'plan')")
+	checkCmd.Flags().BoolVarP(&checkRecord, "record", "", false,
+		"Record drift status in state")
+	RootCmd.AddCommand(checkCmd)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agilestacks/hub/cmd/hub/crypto"
+	"github.com/agilestacks/hub/cmd/hub/storage"
+)
+
+var cryptoCmd = &cobra.Command{
+	Use:   "crypto <rekey | keygen> ...",
+	Short: "Manage encryption of state and backup bundles",
+}
+
+var cryptoRekeyCmd = &cobra.Command{
+	Use:   "rekey <path | prefix/ | prefix*> ...",
+	Short: "Re-encrypt state and backup bundles with new key",
+	Long: `Decrypt state files and backup bundles with current key setup and re-encrypt them with new key setup.
+
+Current key is set by HUB_CRYPTO_PASSWORD, HUB_CRYPTO_AWS_KMS_KEY_ARN, HUB_CRYPTO_AZURE_KEYVAULT_KEY_ID,
+HUB_CRYPTO_GCP_KMS_KEY_NAME, or HUB_CRYPTO_AGE_IDENTITY.
+New key is set by the same variables with _NEW suffix, ie. HUB_CRYPTO_PASSWORD_NEW or HUB_CRYPTO_AGE_RECIPIENTS_NEW.
+Password can be rotated, or the data can be moved from password to a KMS or X25519 recipients, and back.
+
+Path that ends with / or * is a prefix to list files under, for example:
+
+	hub crypto rekey s3://bucket/stacks/ gs://bucket/backups/app-* hub.yaml.state
+
+Individually encrypted values, such as secrets in state, are re-encrypted too, even if the file
+itself is not encrypted. Files without encrypted data are skipped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cryptoRekey(args)
+	},
+}
+
+func cryptoRekey(args []string) error {
+	if len(args) == 0 {
+		return errors.New("Rekey command has one or more arguments - path(s) or prefix(es) of files to rekey")
+	}
+	setup := crypto.NewKeySetup()
+	if !setup.IsSet() && !dryRun {
+		return errors.New("Set HUB_CRYPTO_PASSWORD_NEW, HUB_CRYPTO_AWS_KMS_KEY_ARN_NEW, HUB_CRYPTO_AZURE_KEYVAULT_KEY_ID_NEW, HUB_CRYPTO_GCP_KMS_KEY_NAME_NEW, or HUB_CRYPTO_AGE_RECIPIENTS_NEW")
+	}
+
+	storage.RekeyFiles(args, setup, dryRun)
+
+	return nil
+}
+
+var cryptoKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate X25519 key pair",
+	Long: `Generate X25519 identity (private key) and recipient (public key) in age format.
+
+Data is encrypted to recipients and can only be decrypted with one of the identities:
+
+	HUB_CRYPTO_AGE_RECIPIENTS='age1...,age1...'     # CI
+	HUB_CRYPTO_AGE_IDENTITY='AGE-SECRET-KEY-1...'  # operator, or path to identity file
+
+Keys generated by age-keygen are also supported.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cryptoKeygen(args)
+	},
+}
+
+func cryptoKeygen(args []string) error {
+	if len(args) > 0 {
+		return errors.New("Keygen command has no arguments")
+	}
+	identity, recipient, err := crypto.GenerateX25519Identity()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("# public key: %s\n%s\n", recipient, identity)
+	return nil
+}
+
+func init() {
+	cryptoRekeyCmd.Flags().BoolVarP(&dryRun, "dry", "y", false,
+		"Report files to rekey, do not write")
+	cryptoCmd.AddCommand(cryptoRekeyCmd)
+	cryptoCmd.AddCommand(cryptoKeygenCmd)
+	RootCmd.AddCommand(cryptoCmd)
+}
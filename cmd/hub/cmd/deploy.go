@@ -11,6 +11,7 @@ import (
 
 	"github.com/agilestacks/hub/cmd/hub/config"
 	"github.com/agilestacks/hub/cmd/hub/lifecycle"
+	"github.com/agilestacks/hub/cmd/hub/report"
 	"github.com/agilestacks/hub/cmd/hub/util"
 )
 
@@ -30,6 +31,9 @@ var (
 	hubSaveStackInstanceOutputs   bool
 	hubSyncStackInstance          bool
 	hubSyncSkipParametersAndOplog bool
+	useWorkspace                  bool
+	keepWorkspace                 bool
+	reportFile                    string
 )
 
 var deployCmd = &cobra.Command{
@@ -102,6 +106,14 @@ func lifecycleRequest(args []string, verb string) (*lifecycle.Request, error) {
 	if (componentName != "" || offsetComponent != "") && stateManifest == "" && !config.Force {
 		return nil, errors.New("State file (-s) must be specified when component (-c or -o) is specified")
 	}
+	if reportFile != "" {
+		if stateManifest == "" {
+			return nil, errors.New("State file (-s) must be specified to write --report")
+		}
+		if _, err := report.Format(reportFile); err != nil {
+			return nil, err
+		}
+	}
 
 	manifests := util.SplitPaths(args[0])
 	stateManifests := util.SplitPaths(stateManifest)
@@ -134,6 +146,8 @@ func lifecycleRequest(args []string, verb string) (*lifecycle.Request, error) {
 		OsEnvironmentMode:          osEnvironmentMode,
 		EnvironmentOverrides:       environmentOverrides,
 		ComponentsBaseDir:          componentsBaseDir,
+		Workspace:                  useWorkspace || keepWorkspace,
+		KeepWorkspace:              keepWorkspace,
 		GitOutputs:                 gitOutputs,
 		GitOutputsStatus:           gitOutputsStatus,
 		Environment:                hubEnvironment,
@@ -141,6 +155,7 @@ func lifecycleRequest(args []string, verb string) (*lifecycle.Request, error) {
 		Application:                hubApplication,
 		SyncStackInstance:          hubSyncStackInstance,
 		SyncSkipParametersAndOplog: hubSyncSkipParametersAndOplog,
+		Report:                     reportFile,
 	}
 
 	return request, nil
@@ -174,6 +189,8 @@ func initDeployUndeployFlags(cmd *cobra.Command, verb string) {
 		"Sync Stack Instance state to SuperHub (--hub-stack-instance must be set)")
 	cmd.Flags().BoolVarP(&hubSyncSkipParametersAndOplog, "hub-sync-skip-parameters-and-oplog", "", false,
 		"Sync skip syncing Stack Instance parameters and operation log")
+	cmd.Flags().StringVarP(&reportFile, "report", "", "",
+		fmt.Sprintf("Write %s report to file: out.md, out.html, or junit.xml", verb))
 	initCommonLifecycleFlags(cmd, verb)
 	initCommonApiFlags(cmd)
 }
@@ -183,6 +200,10 @@ func initCommonLifecycleFlags(cmd *cobra.Command, verb string) {
 		"Path to component sources base directory (default to manifest dir)")
 	cmd.Flags().BoolVarP(&dryRun, "dry", "y", false,
 		fmt.Sprintf("Invoke %[1]s-test verb instead of %[1]s", verb))
+	cmd.Flags().BoolVarP(&useWorkspace, "workspace", "", false,
+		"Render templates and run implementation in ephemeral full copy of component directory (not a copy-on-write overlay)")
+	cmd.Flags().BoolVarP(&keepWorkspace, "keep-workspace", "", false,
+		"Do not remove ephemeral workspace after operation, for debugging (implies --workspace)")
 	cmd.Flags().StringVarP(&osEnvironmentMode, "os-environment", "", "no-tfvars",
 		"OS environment mode for child process, one of: everything, no-tfvars, strict")
 	cmd.Flags().BoolVarP(&config.SwitchKubeconfigContext, "switch-kube-context", "", false,
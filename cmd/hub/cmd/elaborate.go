@@ -14,6 +14,8 @@ var (
 	elaborateOutput                  string
 	elaboratePlatformProvides        string
 	elaborateUseStateStackParameters bool
+	elaborateEnv                     string
+	elaborateExplainParameter        string
 )
 
 var elaborateCmd = &cobra.Command{
@@ -21,6 +23,8 @@ var elaborateCmd = &cobra.Command{
 	Short: "Assemble hub.yaml.elaborate",
 	Long: `Assemble a complete Stack or Application deployment manifest by joining stack and components manifests.
 Parameters are injected from parameters manifest(s) and optionally are read from state file.
+With --env prod, params/common.yaml, params/prod.yaml, and params/prod/<component>.yaml overlays
+are layered first, in that order, and the winning file of each parameter is recorded as its source in elaborate.
 The resulted hub.yaml.elaborate can be used with deploy command.`,
 	Annotations: map[string]string{
 		"usage-metering": "tags",
@@ -48,7 +52,7 @@ func elaborate(args []string, pipe io.WriteCloser) error {
 	stateManifests := util.SplitPaths(stateManifestExplicit)
 	compose.Elaborate(manifest, parameters, environmentOverrides, elaboratePlatformProvides,
 		stateManifests, elaborateUseStateStackParameters, elaborateManifests, componentsBaseDir,
-		pipe)
+		elaborateEnv, elaborateExplainParameter, pipe)
 
 	return nil
 }
@@ -66,5 +70,9 @@ func init() {
 		"Path to state file(s) to load Platform stack outputs as input parameters, for example hub.yaml.state,s3://bucket/hub.yaml.state")
 	elaborateCmd.Flags().BoolVarP(&elaborateUseStateStackParameters, "state-stack-parameters", "", true,
 		"Also use stack parameters (from state) to load input parameters, otherwise only stack outputs are used")
+	elaborateCmd.Flags().StringVarP(&elaborateEnv, "env", "", "",
+		"Layer params/common.yaml, params/<env>.yaml, and params/<env>/<component>.yaml overlays before parameters file(s)")
+	elaborateCmd.Flags().StringVarP(&elaborateExplainParameter, "explain-parameter", "", "",
+		"Print parameter override chain, for example --explain-parameter dns.domain")
 	RootCmd.AddCommand(elaborateCmd)
 }
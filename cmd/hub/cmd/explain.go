@@ -12,21 +12,25 @@ import (
 )
 
 var (
-	explainGlobal bool
-	explainRaw    bool
-	explainOpLog  bool
-	explainInKv   bool
-	explainInSh   bool
-	explainInJson bool
-	explainInYaml bool
-	explainColor  bool
+	explainGlobal  bool
+	explainRaw     bool
+	explainOpLog   bool
+	explainInKv    bool
+	explainInSh    bool
+	explainInJson  bool
+	explainInYaml  bool
+	explainColor   bool
+	explainWhy     string
+	explainSecrets bool
 )
 
 var explainCmd = &cobra.Command{
 	Use:   "explain [hub.yaml.elaborate] hub.yaml.state[,s3://bucket/hub.yaml.state]",
 	Short: "Explain stack outputs, provides, and parameters",
 	Long: `Display stack outputs, component's parameters, outputs, and capabilities.
-Parameters and outputs are read from state file. Elaborate file is optional.`,
+Parameters and outputs are read from state file. Elaborate file is optional.
+Use --why <parameter> to show where parameter value came from.
+Secret parameters and outputs are masked unless --show-secrets is given.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return explain(args)
 	},
@@ -56,7 +60,8 @@ func explain(args []string) error {
 		format = "yaml"
 	}
 
-	state.Explain(elaborateManifests, stateManifests, explainOpLog, explainGlobal, componentName, explainRaw, format, explainColor)
+	state.Explain(elaborateManifests, stateManifests, explainOpLog, explainGlobal, componentName, explainRaw,
+		explainWhy, format, explainColor, explainSecrets)
 
 	return nil
 }
@@ -70,6 +75,8 @@ func init() {
 		"Display raw component outputs")
 	explainCmd.Flags().BoolVarP(&explainOpLog, "op-log", "l", false,
 		"Display operations log (only)")
+	explainCmd.Flags().StringVarP(&explainWhy, "why", "", "",
+		"Explain where parameter value came from: file, line, and mechanism")
 	explainCmd.Flags().BoolVarP(&explainInKv, "kv", "", false,
 		"key=value output")
 	explainCmd.Flags().BoolVarP(&explainInSh, "sh", "", false,
@@ -80,5 +87,7 @@ func init() {
 		"YAML output")
 	explainCmd.Flags().BoolVarP(&explainColor, "color", "", isatty.IsTerminal(os.Stdout.Fd()),
 		"Colorized output")
+	explainCmd.Flags().BoolVarP(&explainSecrets, "show-secrets", "", false,
+		"Display secret parameters and outputs values, decrypt if encrypted in state")
 	RootCmd.AddCommand(explainCmd)
 }
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agilestacks/hub/cmd/hub/fleet"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+var (
+	fleetStacks   string
+	fleetParallel int
+)
+
+var fleetCmd = &cobra.Command{
+	Use:   "fleet <deploy | undeploy | status> [hub-fleet.yaml]",
+	Short: "Deploy and manage a fleet of dependent stacks",
+	Long: `Deploy, undeploy, and show status of a fleet of stacks described by hub-fleet.yaml:
+
+    version: 1
+    kind: fleet
+    meta:
+      name: platform
+    stacks:
+    - name: network
+      manifest: network/hub.yaml          # default <name>/hub.yaml
+      parameters: [network/params.yaml]
+      state: s3://bucket/network.state    # default <manifest>.state
+    - name: cluster
+      depends: [network]
+    - name: apps
+      depends: [cluster, network]
+      platform: cluster                   # default the only dependency
+
+Stacks are elaborated and deployed in order of dependencies, stacks that do not depend on each other
+are deployed concurrently. Outputs of dependencies are wired into stack parameters with matching names
+that have no value. State of the platform stack is also passed to elaborate -s; set platform: when
+the stack has several dependencies, otherwise no platform state is used. Platform is implicitly a dependency.
+Undeploy goes in reverse order.`,
+}
+
+var fleetDeployCmd = &cobra.Command{
+	Use:   "deploy [hub-fleet.yaml]",
+	Short: "Deploy fleet stacks",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return fleetExecute(args, fleet.VerbDeploy)
+	},
+}
+
+var fleetUndeployCmd = &cobra.Command{
+	Use:   "undeploy [hub-fleet.yaml]",
+	Short: "Undeploy fleet stacks",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return fleetExecute(args, fleet.VerbUndeploy)
+	},
+}
+
+var fleetStatusCmd = &cobra.Command{
+	Use:   "status [hub-fleet.yaml]",
+	Short: "Show fleet stacks status",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filename, err := fleetFilename(args)
+		if err != nil {
+			return err
+		}
+		fleet.Status(filename, util.SplitPaths(fleetStacks))
+		return nil
+	},
+}
+
+func fleetFilename(args []string) (string, error) {
+	if len(args) > 1 {
+		return "", errors.New("Fleet command has one optional argument - path to hub-fleet.yaml")
+	}
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	return "hub-fleet.yaml", nil
+}
+
+func fleetExecute(args []string, verb string) error {
+	filename, err := fleetFilename(args)
+	if err != nil {
+		return err
+	}
+	if fleetParallel < 1 {
+		return errors.New("--parallel must be at least 1")
+	}
+	fleet.Execute(filename, verb, util.SplitPaths(fleetStacks), fleetParallel)
+	return nil
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{fleetDeployCmd, fleetUndeployCmd, fleetStatusCmd} {
+		cmd.Flags().StringVarP(&fleetStacks, "stacks", "", "",
+			"A list of fleet stacks to process (separated by comma), dependencies are expected to be deployed")
+	}
+	for _, cmd := range []*cobra.Command{fleetDeployCmd, fleetUndeployCmd} {
+		cmd.Flags().IntVarP(&fleetParallel, "parallel", "", 4,
+			"Process up to N independent stacks concurrently")
+	}
+	fleetCmd.AddCommand(fleetDeployCmd)
+	fleetCmd.AddCommand(fleetUndeployCmd)
+	fleetCmd.AddCommand(fleetStatusCmd)
+	RootCmd.AddCommand(fleetCmd)
+}
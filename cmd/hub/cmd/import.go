@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agilestacks/hub/cmd/hub/lifecycle"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+var importOutputs string
+
+var importCmd = &cobra.Command{
+	Use:   "import hub.yaml.elaborate -c component[,component] [--outputs outputs.txt]",
+	Short: "Import existing infrastructure into state",
+	Long: `Adopt resources that were deployed outside of Hub CLI by writing component state as if the
+component had been deployed. Component's 'import' verb is invoked with the same parameters that
+deploy would use and is expected to print the outputs. Alternatively, the outputs are read from
+a file supplied by --outputs in the same 'name = value' format.
+Parameters are locked, outputs are captured, and provides are recorded in state, so that
+subsequent 'deploy -c' and 'undeploy' work normally. A fresh state file is created if necessary.`,
+	Annotations: map[string]string{
+		"usage-metering": "tags",
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pipe := cmdContextPipe(cmd)
+		if pipe != nil {
+			defer pipe.Close()
+		}
+		return importComponents(args, pipe)
+	},
+}
+
+func importComponents(args []string, pipe io.WriteCloser) error {
+	if componentName == "" {
+		return errors.New("Component(s) to import must be specified by -c / --components")
+	}
+	components := util.SplitPaths(componentName)
+	if importOutputs != "" && len(components) != 1 {
+		return errors.New("Exactly one component must be specified when outputs are supplied by --outputs")
+	}
+	request, err := lifecycleRequest(args, "import")
+	if err != nil {
+		return err
+	}
+	request.Verb = "deploy"
+	request.Import = true
+	request.ImportOutputs = importOutputs
+	lifecycle.Execute(request, pipe)
+	return nil
+}
+
+func init() {
+	importCmd.Flags().StringVarP(&stateManifest, "state", "s", "hub.yaml.state",
+		"Path to state file(s), for example hub.yaml.state,s3://bucket/hub.yaml.state")
+	importCmd.Flags().StringVarP(&componentName, "components", "c", "",
+		"A list of components to import (separated by comma)")
+	importCmd.Flags().StringVarP(&importOutputs, "outputs", "", "",
+		"Path to file with component raw outputs, one `name = value` per line, instead of invoking 'import' verb")
+	importCmd.Flags().StringVarP(&environmentOverrides, "environment", "e", "",
+		"Set environment overrides: -e 'NAME=demo,INSTANCE=r4.large,...'")
+	importCmd.Flags().StringVarP(&componentsBaseDir, "base-dir", "b", "",
+		"Path to component sources base directory (default to manifest dir)")
+	importCmd.Flags().StringVarP(&osEnvironmentMode, "os-environment", "", "no-tfvars",
+		"OS environment mode for child process, one of: everything, no-tfvars, strict")
+	importCmd.Flags().StringVarP(&enabledClouds, "clouds", "", "",
+		"A list of enabled clouds: \"aws,azure,gcp\" (default to autodetect from environment)")
+	initCommonApiFlags(importCmd)
+	RootCmd.AddCommand(importCmd)
+}
@@ -23,6 +23,7 @@ var (
 	workerpoolVolumeSize     int
 	workerpoolDelete         bool
 	workerpoolInstanceType   string
+	syncPending              bool
 )
 
 var instanceCmd = &cobra.Command{
@@ -138,8 +139,12 @@ var instanceBackupCmd = &cobra.Command{
 }
 
 var instanceSyncCmd = &cobra.Command{
-	Use:   "sync <id | domain>",
+	Use:   "sync <id | domain> [status] | --pending [id | domain]",
 	Short: "Sync Stack Instance state from state file",
+	Long: `Sync Stack Instance state from state file, or set Instance status.
+
+With --pending, replay state syncs that failed during deploy / undeploy with --hub-sync
+and were queued next to the API cache file. Only the latest state per Instance is queued.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return syncInstance(args)
 	},
@@ -321,6 +326,17 @@ func backupInstance(args []string) error {
 }
 
 func syncInstance(args []string) error {
+	if syncPending {
+		if len(args) > 1 {
+			return errors.New("Sync Instance --pending command has one optional argument - id or full domain name of the Instance")
+		}
+		selector := ""
+		if len(args) > 0 {
+			selector = args[0]
+		}
+		api.SyncPending(selector)
+		return nil
+	}
 	if len(args) != 1 && len(args) != 2 {
 		return errors.New("Sync Instance command has one mandatory argument - id or full domain name of the Instance, and optionally Instance status")
 	}
@@ -506,6 +522,8 @@ func init() {
 		"Wait for backup and tail logs")
 	instanceSyncCmd.Flags().StringVarP(&stateManifestExplicit, "state", "s", "",
 		"Path to state files")
+	instanceSyncCmd.Flags().BoolVarP(&syncPending, "pending", "", false,
+		"Replay queued state syncs")
 	instanceKubeconfigCmd.Flags().StringVarP(&kubeconfigOutput, "output", "o", "",
 		"Set output filename, \"-\" for stdout (default to kubeconfig.<domain>.yaml)")
 	instanceLogsCmd.Flags().StringVarP(&logsOutput, "output", "o", "",
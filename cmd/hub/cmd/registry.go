@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/registry"
+)
+
+var componentPackageOutputDir string
+
+var registryComponentCmd = &cobra.Command{
+	Use:   "component <package | publish> ...",
+	Short: "Package and publish versioned components to registry",
+	Long: `Package component into versioned archive and publish it to registry.
+
+Registry is a plain directory or HTTP location with index.yaml, set by --registry or HUB_REGISTRY.
+Stack refers to published component via:
+
+	components:
+	- name: postgresql
+	  source:
+	    registry: postgresql@^1.2`,
+}
+
+var componentPackageCmd = &cobra.Command{
+	Use:   "package <dir> [-o output dir]",
+	Short: "Package component into versioned tar.gz archive",
+	Long: `Package component directory into <name>-<version>.tar.gz archive.
+Name and version are read from hub-component.yaml meta.name and meta.version.
+SHA256 checksum is written next to the archive into <archive>.sha256`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return componentPackage(args)
+	},
+}
+
+var componentPublishCmd = &cobra.Command{
+	Use:   "publish <archive.tar.gz>",
+	Short: "Publish component archive to registry",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return componentPublish(args)
+	},
+}
+
+func componentPackage(args []string) error {
+	if len(args) != 1 {
+		return errors.New("Package command has one mandatory argument - path to component directory")
+	}
+
+	_, _, err := registry.Package(args[0], componentPackageOutputDir)
+	return err
+}
+
+func componentPublish(args []string) error {
+	if len(args) != 1 {
+		return errors.New("Publish command has one mandatory argument - path to component archive")
+	}
+
+	return registry.Publish(args[0], config.Registry)
+}
+
+func init() {
+	componentPackageCmd.Flags().StringVarP(&componentPackageOutputDir, "output", "o", ".",
+		"Output directory")
+	registryComponentCmd.AddCommand(componentPackageCmd)
+	registryComponentCmd.AddCommand(componentPublishCmd)
+	RootCmd.AddCommand(registryComponentCmd)
+}
@@ -21,7 +21,12 @@ var (
 var renderCmd = &cobra.Command{
 	Use:   "render <template glob> ... [-a 'additional.parameter1=value,...']",
 	Short: "Render component templates",
-	Long:  `Render component templates with additional parameters during lifecycle operation.`,
+	Long: `Render component templates with additional parameters during lifecycle operation.
+
+Go templates (-k go) have Sprig functions plus Hub functions:
+output "component:name", param "name", secret "name", required "name" value,
+toYaml, fromYaml, b64file "path", kubeconfig ["context"], and include "partials/file.tpl" .
+Include and b64file paths are resolved from current (component) directory, then from stack directory.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return render(args)
 	},
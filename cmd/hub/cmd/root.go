@@ -14,6 +14,8 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/metrics"
+	"github.com/agilestacks/hub/cmd/hub/notify"
 	"github.com/agilestacks/hub/cmd/hub/util"
 )
 
@@ -82,7 +84,18 @@ func init() {
 
 	RootCmd.PersistentFlags().BoolVar(&config.Compressed, "compressed", true, "Write gzip compressed files")
 	RootCmd.PersistentFlags().StringVar(&config.EncryptionMode, "encrypted", "if-key-set",
-		"Write encrypted files if HUB_CRYPTO_PASSWORD, HUB_CRYPTO_AWS_KMS_KEY_ARN, HUB_CRYPTO_AZURE_KEYVAULT_KEY_ID is set. true / false")
+		"Write encrypted files if HUB_CRYPTO_PASSWORD, HUB_CRYPTO_AWS_KMS_KEY_ARN, HUB_CRYPTO_AZURE_KEYVAULT_KEY_ID, HUB_CRYPTO_GCP_KMS_KEY_NAME, HUB_CRYPTO_AGE_RECIPIENTS is set. true / false")
+	RootCmd.PersistentFlags().StringVar(&config.Registry, "registry", os.Getenv(envVarNameRegistry),
+		"Component registry directory or HTTP URL, HUB_REGISTRY")
+
+	tracingEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if tracingEndpoint == "" {
+		tracingEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	RootCmd.PersistentFlags().StringVar(&config.TracingEndpoint, "tracing-endpoint", tracingEndpoint,
+		"OpenTelemetry OTLP/HTTP endpoint to export lifecycle operations traces to, OTEL_EXPORTER_OTLP_ENDPOINT")
+	RootCmd.PersistentFlags().StringVar(&config.TracingFile, "tracing-file", os.Getenv(envVarNameTracingFile),
+		"Append lifecycle operations traces to file in OTLP JSON lines format, HUB_TRACING_FILE")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -147,4 +160,50 @@ func initConfig() {
 	if key := viper.GetString("crypto-azure-keyvault-key-id"); key != "" {
 		config.CryptoAzureKeyVaultKeyId = key
 	}
+	if key := viper.GetString("crypto-gcp-kms-key-name"); key != "" {
+		config.CryptoGcpKmsKeyName = key
+	}
+	if recipients := viper.GetString("crypto-age-recipients"); recipients != "" {
+		config.CryptoAgeRecipients = recipients
+	}
+	if identity := viper.GetString("crypto-age-identity"); identity != "" {
+		config.CryptoAgeIdentity = identity
+	}
+	if pass := viper.GetString("crypto-password-new"); pass != "" {
+		config.CryptoPasswordNew = pass
+	}
+	if key := viper.GetString("crypto-aws-kms-key-arn-new"); key != "" {
+		config.CryptoAwsKmsKeyArnNew = key
+	}
+	if key := viper.GetString("crypto-azure-keyvault-key-id-new"); key != "" {
+		config.CryptoAzureKeyVaultKeyIdNew = key
+	}
+	if key := viper.GetString("crypto-gcp-kms-key-name-new"); key != "" {
+		config.CryptoGcpKmsKeyNameNew = key
+	}
+	if recipients := viper.GetString("crypto-age-recipients-new"); recipients != "" {
+		config.CryptoAgeRecipientsNew = recipients
+	}
+	if identity := viper.GetString("crypto-age-identity-new"); identity != "" {
+		config.CryptoAgeIdentityNew = identity
+	}
+	if registry := viper.GetString("registry"); registry != "" && config.Registry == "" {
+		config.Registry = registry
+	}
+	if endpoint := viper.GetString("tracing-endpoint"); endpoint != "" && config.TracingEndpoint == "" {
+		config.TracingEndpoint = endpoint
+	}
+	if file := viper.GetString("tracing-file"); file != "" && config.TracingFile == "" {
+		config.TracingFile = file
+	}
+	if viper.IsSet("metrics-sinks") {
+		if err := viper.UnmarshalKey("metrics-sinks", &metrics.Sinks); err != nil {
+			util.Warn("Unable to parse `metrics-sinks` in %s: %v", viper.ConfigFileUsed(), err)
+		}
+	}
+	if viper.IsSet("notifications") {
+		if err := viper.UnmarshalKey("notifications", &notify.ConfigNotifications); err != nil {
+			util.Warn("Unable to parse `notifications` in %s: %v", viper.ConfigFileUsed(), err)
+		}
+	}
 }
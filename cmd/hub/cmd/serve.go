@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agilestacks/hub/cmd/hub/server"
+)
+
+var (
+	serveListen  string
+	serveWorkdir string
+	serveToken   string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [--listen 127.0.0.1:8080] [--workdir DIR] [--token TOKEN]",
+	Short: "Serve lifecycle operations over HTTP",
+	Long: `Start HTTP server to run elaborate, deploy, undeploy, and backup as asynchronous jobs,
+and to read state.
+
+	POST /elaborate, /deploy, /undeploy, /backup    start a job; JSON request body fields
+	    manifest, parameters, elaborate, state, components, offset, limit, baseDir, env,
+	    bundle, osEnvironment, dryRun mirror command-line arguments
+	GET  /jobs, /jobs/<id>                          list jobs, get job status and logs
+	GET  /jobs/<id>/logs                            stream job logs as Server-Sent Events
+	GET  /explain?state=&elaborate=&component=&global=&raw=    explain state in JSON
+	GET  /state?state=                              state in YAML
+
+Every request must carry Authorization: Bearer <token> header when --token or HUB_SERVE_TOKEN is set.
+The token is mandatory to listen on an address other than loopback.
+
+Paths are relative to --workdir (default to current directory); secrets are masked.
+Jobs are executed one at a time; a job is rejected with 409 Conflict if the stack state file
+is used by another queued or running job, or is locked by <state>.lock file.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return serve(args)
+	},
+}
+
+func serve(args []string) error {
+	if len(args) > 0 {
+		return errors.New("Serve command has no arguments")
+	}
+
+	server.Serve(serveListen, serveWorkdir, serveToken, setOsEnvForNestedCli)
+
+	return nil
+}
+
+func init() {
+	serveCmd.Flags().StringVarP(&serveListen, "listen", "", "127.0.0.1:8080",
+		"Address to listen on")
+	serveCmd.Flags().StringVarP(&serveToken, "token", "", os.Getenv(envVarNameServeToken),
+		"Bearer token required on every request, HUB_SERVE_TOKEN")
+	serveCmd.Flags().StringVarP(&serveWorkdir, "workdir", "", "",
+		"Working directory with stack manifests and state files")
+	RootCmd.AddCommand(serveCmd)
+}
@@ -14,16 +14,20 @@ import (
 
 	"github.com/agilestacks/hub/cmd/hub/lifecycle"
 	"github.com/agilestacks/hub/cmd/hub/metrics"
+	"github.com/agilestacks/hub/cmd/hub/mockapi"
 	"github.com/agilestacks/hub/cmd/hub/util"
 )
 
 var (
-	metricTags  []string
-	metricStdin bool
+	metricTags     []string
+	metricStdin    bool
+	mockApiListen  string
+	mockApiData    string
+	mockApiPersist bool
 )
 
 var utilCmd = &cobra.Command{
-	Use:   "util <otp | ...>",
+	Use:   "util <otp | mock-api | ...>",
 	Short: "Utility functions",
 }
 
@@ -84,6 +88,34 @@ Set 'host: ""' to send the counter but not the UUID.
 	},
 }
 
+var utilMockApiCmd = &cobra.Command{
+	Use:   "mock-api [--listen 127.0.0.1:8081] [--data mock-api.yaml [--persist]]",
+	Short: "Serve mock SuperHub API",
+	Long: `Serve in-memory SuperHub API for offline development and integration tests.
+
+Implemented are login, Environments, Stack Instances, Templates, their secrets and parameters,
+instance deploy / undeploy / backup that only update the status, and socket.io stream of changes
+for 'hub api logs' and --wait. Any username is accepted unless users are listed in data file.
+
+Data file is YAML with environments, instances, templates (as returned by the API), secrets, and users:
+
+	users:
+	- username: dev
+	  password: secret
+	environments:
+	- id: "1"
+	  name: dev
+	  parameters:
+	  - name: dns.domain
+	    value: dev.example.com
+
+With --persist the changes are saved back to data file.
+Point Hub CLI to the mock with HUB_API=http://localhost:8081 and any HUB_TOKEN.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return mockApi(args)
+	},
+}
+
 func otpEncode(args []string) error {
 	if len(args) != 1 && len(args) != 0 || (len(args) == 1 && args[0] != "encode") {
 		return errors.New("OTP command has only one optional argument - [encode]")
@@ -135,10 +167,28 @@ func putMetrics(args, tags []string) error {
 	return nil
 }
 
+func mockApi(args []string) error {
+	if len(args) > 0 {
+		return errors.New("Mock API command has no arguments")
+	}
+	if mockApiPersist && mockApiData == "" {
+		return errors.New("Data file (--data) must be specified with --persist")
+	}
+
+	mockapi.Serve(mockApiListen, mockApiData, mockApiPersist)
+
+	return nil
+}
+
 func init() {
+	utilMockApiCmd.Flags().StringVarP(&mockApiListen, "listen", "", "127.0.0.1:8081",
+		"Address to listen on; the mock accepts any username and serves stored secrets - keep it on loopback")
+	utilMockApiCmd.Flags().StringVarP(&mockApiData, "data", "", "", "Path to YAML data file")
+	utilMockApiCmd.Flags().BoolVarP(&mockApiPersist, "persist", "", false, "Save changes to data file")
 	utilMetricsCmd.Flags().StringSliceVarP(&metricTags, "tags", "t", nil, "Additional tags key:value,...")
 	utilMetricsCmd.Flags().BoolVar(&metricStdin, "tags-stdin", false, "Read additional tags from stdin, key:value per line")
 	utilCmd.AddCommand(utilOtpCmd)
 	utilCmd.AddCommand(utilMetricsCmd)
+	utilCmd.AddCommand(utilMockApiCmd)
 	RootCmd.AddCommand(utilCmd)
 }
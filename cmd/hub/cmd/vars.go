@@ -8,6 +8,9 @@ const (
 	envVarNameComponentsBaseDir = "HUB_COMPONENTS_BASEDIR"
 	envVarNameHubApi            = "HUB_API"
 	envVarNameDerefSecrets      = "HUB_API_DEREF_SECRETS"
+	envVarNameRegistry          = "HUB_REGISTRY"
+	envVarNameTracingFile       = "HUB_TRACING_FILE"
+	envVarNameServeToken        = "HUB_SERVE_TOKEN"
 	SuperHubIo                  = ".superhub.io"
 
 	mdpre = "```"
@@ -17,6 +17,7 @@ import (
 	"github.com/agilestacks/hub/cmd/hub/kube"
 	"github.com/agilestacks/hub/cmd/hub/manifest"
 	"github.com/agilestacks/hub/cmd/hub/parameters"
+	"github.com/agilestacks/hub/cmd/hub/registry"
 	"github.com/agilestacks/hub/cmd/hub/state"
 	"github.com/agilestacks/hub/cmd/hub/storage"
 	"github.com/agilestacks/hub/cmd/hub/util"
@@ -44,7 +45,7 @@ var environment map[string]string
 func Elaborate(manifestFilename string,
 	parametersFilenames []string, environmentOverrides, explicitProvides string,
 	stateManifests []string, useStateStackParameters bool, elaborateManifests []string, componentsBaseDir string,
-	pipe io.WriteCloser) {
+	envName, explainParameter string, pipe io.WriteCloser) {
 
 	if config.Verbose {
 		parametersFrom := ""
@@ -78,6 +79,13 @@ func Elaborate(manifestFilename string,
 		wellKnownKV[known.Name] = known
 	}
 
+	var overlays map[string]string
+	if envName != "" {
+		var envParametersFilenames []string
+		envParametersFilenames, overlays = scanEnvironmentParamsFiles(filepath.Dir(manifestFilename), envName)
+		parametersFilenames = append(envParametersFilenames, parametersFilenames...)
+	}
+
 	var st *state.StateManifest
 	if len(stateManifests) > 0 {
 		st = state.MustParseStateFiles(stateManifests)
@@ -99,8 +107,8 @@ func Elaborate(manifestFilename string,
 		return nil
 	}
 
-	stackManifest, componentsManifests := elaborate(manifestFilename, parametersFilenames, environment,
-		wellKnownKV, componentsBaseDir, []string{}, 0, extraKubernetesParams)
+	stackManifest, componentsManifests, provenance := elaborate(manifestFilename, parametersFilenames, overlays,
+		environment, wellKnownKV, componentsBaseDir, []string{}, 0, extraKubernetesParams)
 
 	if pipe != nil {
 		metricTags := fmt.Sprintf("stack:%s", stackManifest.Meta.Name)
@@ -123,13 +131,14 @@ func Elaborate(manifestFilename string,
 		// we might get in trouble here setting `dns.domain` from Kubernetes state on empty
 		// `kind: user` parameter with `fromEnv:`
 		// at least there will be a warning for mismatched values
-		setValuesFromState(stackManifest.Parameters, st, useStateStackParameters)
+		setValuesFromState(stackManifest.Parameters, st, stateManifests, useStateStackParameters)
 		stackManifest.Requires = connectStateProvides(stackManifest.Requires, st.Provides)
 		platformProvides = util.MergeUnique(platformProvides, util.SortedKeys2(st.Provides))
 	}
 	if len(platformProvides) > 0 {
 		stackManifest.Platform.Provides = util.MergeUnique(stackManifest.Platform.Provides, platformProvides)
 	}
+	recordFromStackState(stackManifest.Parameters)
 	warnNoValue(stackManifest.Parameters)
 	warnFromEnvValueMismatch(stackManifest.Parameters)
 
@@ -143,6 +152,13 @@ func Elaborate(manifestFilename string,
 
 	setDefaultLifecycleVerbs(componentsManifests)
 
+	if envName != "" && config.Verbose {
+		printParametersSources(stackManifest.Parameters)
+	}
+	if explainParameter != "" {
+		printParameterProvenance(explainParameter, provenance)
+	}
+
 	guessAndMarkSecrets(stackManifest.Outputs)
 	for i := range componentsManifests {
 		guessAndMarkSecrets(componentsManifests[i].Outputs)
@@ -154,10 +170,10 @@ func Elaborate(manifestFilename string,
 	}
 }
 
-func elaborate(manifestFilename string, parametersFilenames []string, overrides map[string]string,
-	wellKnown map[string]manifest.Parameter, componentsBaseDir string,
+func elaborate(manifestFilename string, parametersFilenames []string, overlays map[string]string,
+	overrides map[string]string, wellKnown map[string]manifest.Parameter, componentsBaseDir string,
 	excludedComponents []string, depth int,
-	maybeExtraParameters func(manifest.Manifest) []manifest.Parameter) (*manifest.Manifest, []manifest.Manifest) {
+	maybeExtraParameters func(manifest.Manifest) []manifest.Parameter) (*manifest.Manifest, []manifest.Manifest, parametersProvenance) {
 
 	stackManifest := parseManifest(manifestFilename)
 
@@ -172,6 +188,15 @@ func elaborate(manifestFilename string, parametersFilenames []string, overrides
 		log.Printf("Base directory for sources is `%s`", componentsBaseDirCurrent)
 	}
 
+	resolveRegistrySources(stackManifest.Components, excludedComponents, componentsBaseDirCurrent)
+
+	manifestsParameters := [][]manifest.Parameter{
+		manifest.FlattenParameters(stackManifest.Parameters, fmt.Sprintf("%s [%s]", stackManifest.Meta.Name, manifestFilename)),
+	}
+	manifestsParameters = append(manifestsParameters,
+		unwrapManifestsParameters(parametersManifests, parametersFilenamesRead, overlays)...)
+	manifestsParameters = expandForEach(stackManifest, manifestsParameters, overrides)
+
 	componentsManifests, err := manifest.ParseComponentsManifestsWithExclusion(stackManifest.Components, excludedComponents,
 		stackBaseDir, componentsBaseDirCurrent)
 	if err != nil {
@@ -185,6 +210,7 @@ func elaborate(manifestFilename string, parametersFilenames []string, overrides
 
 	fromStack := stackManifest.Meta.FromStack != ""
 	fromStackName := ""
+	fromStackFilename := ""
 	fromStackManifest := &manifest.Manifest{}
 	var fromStackComponentsManifests []manifest.Manifest
 
@@ -193,11 +219,11 @@ func elaborate(manifestFilename string, parametersFilenames []string, overrides
 			log.Fatalf("Application manifest %s cannot use `fromStack`", manifestFilename)
 		}
 		fromStackName = filepath.Base(stackManifest.Meta.FromStack)
-		fromStackFilename := filepath.Join(stackManifest.Meta.FromStack, "hub.yaml")
+		fromStackFilename = filepath.Join(stackManifest.Meta.FromStack, "hub.yaml")
 		fromStackParams := scanParamsFiles(stackManifest.Meta.FromStack)
 		fromStackExcludedComponents := append(excludedComponents, manifest.ComponentsNamesFromRefs(stackManifest.Components)...)
-		fromStackManifest, fromStackComponentsManifests = elaborate(fromStackFilename, fromStackParams, overrides,
-			wellKnown, componentsBaseDir, fromStackExcludedComponents, depth+1, nil)
+		fromStackManifest, fromStackComponentsManifests, _ = elaborate(fromStackFilename, fromStackParams, nil,
+			overrides, wellKnown, componentsBaseDir, fromStackExcludedComponents, depth+1, nil)
 	}
 
 	if config.Verbose {
@@ -215,10 +241,6 @@ func elaborate(manifestFilename string, parametersFilenames []string, overrides
 	if fromStack {
 		parameters = append(parameters, fromStackManifest.Parameters) // already flat
 	}
-	manifestsParameters := [][]manifest.Parameter{
-		manifest.FlattenParameters(stackManifest.Parameters, fmt.Sprintf("%s [%s]", stackManifest.Meta.Name, manifestFilename)),
-	}
-	manifestsParameters = append(manifestsParameters, unwrapManifestsParameters(parametersManifests, parametersFilenamesRead)...)
 	checkParameters(manifestsParameters)
 
 	var elaborated manifest.Manifest
@@ -241,11 +263,13 @@ func elaborate(manifestFilename string, parametersFilenames []string, overrides
 		elaborated.Outputs = mergeOutputs(fromStackManifest.Outputs, stackManifest.Outputs)
 		componentsManifests = mergeComponentsManifests(fromStackComponentsManifests, componentsManifests)
 		elaborated.Platform.Provides = util.MergeUnique(fromStackManifest.Platform.Provides, stackManifest.Platform.Provides)
+		elaborated.Notifications = append(fromStackManifest.Notifications, stackManifest.Notifications...)
 	} else {
 		elaborated.Components = stackManifest.Components
 		elaborated.Lifecycle = stackManifest.Lifecycle
 		elaborated.Outputs = stackManifest.Outputs
 		elaborated.Platform.Provides = stackManifest.Platform.Provides
+		elaborated.Notifications = stackManifest.Notifications
 	}
 	parametersManifestsOutputs := unwrapManifestsOutputs(parametersManifests)
 	if len(parametersManifestsOutputs) > 0 {
@@ -267,10 +291,20 @@ func elaborate(manifestFilename string, parametersFilenames []string, overrides
 		}
 	}
 	parameters = append(parameters, manifestsParameters...)
-	elaborated.Parameters = mergeParameters(parameters, overrides, wellKnown,
+	mergedParameters, provenance := mergeParameters(parameters, overrides, wellKnown,
 		manifest.ComponentsNamesFromRefs(elaborated.Components), nComponents, isApplication)
+	elaborated.Parameters = mergedParameters
+	checkForEachRefs(elaborated.Components, elaborated.Parameters, elaborated.Outputs, componentsManifests)
+	checkCelExpressions(elaborated.Parameters, elaborated.Outputs, elaborated.Lifecycle.ReadyConditions, componentsManifests)
+
+	for overlay, component := range overlays {
+		if manifest.ComponentRefByName(elaborated.Components, component) == nil &&
+			len(manifest.ComponentInstances(elaborated.Components, component)) == 0 {
+			util.Warn("Parameters overlay `%s` refers to component `%s` not found in stack", overlay, component)
+		}
+	}
 
-	return &elaborated, componentsManifests
+	return &elaborated, componentsManifests, provenance
 }
 
 func parseManifest(manifestFilename string) *manifest.Manifest {
@@ -369,6 +403,91 @@ func checkLifecycle(components []manifest.ComponentRef, lifecycle manifest.Lifec
 	// not checking Mandatory and Optional as they could contain components from parent stack
 }
 
+// checkCelExpressions type-checks CEL expressions and verifies that every variable refers to a parameter
+// or output known to the stack; `hub.*` names are provided at runtime
+func checkCelExpressions(params []manifest.Parameter, outputs []manifest.Output,
+	readyConditions []manifest.ReadyCondition, componentsManifests []manifest.Manifest) {
+
+	known := make(map[string]struct{})
+	for _, parameter := range params {
+		known[parameter.Name] = struct{}{}
+	}
+	for _, output := range outputs {
+		known[output.Name] = struct{}{}
+	}
+	for _, name := range kube.KubernetesParameters {
+		known[name] = struct{}{}
+	}
+	for _, component := range componentsManifests {
+		for _, parameter := range manifest.FlattenParameters(component.Parameters, component.Meta.Name) {
+			known[parameter.Name] = struct{}{}
+		}
+		for _, output := range component.Outputs {
+			known[output.Name] = struct{}{}
+		}
+	}
+	isKnown := func(name string) bool {
+		if strings.HasPrefix(name, "hub.") {
+			return true
+		}
+		parts := strings.Split(name, ".")
+		for i := len(parts); i > 0; i-- {
+			if _, exist := known[strings.Join(parts[:i], ".")]; exist {
+				return true
+			}
+		}
+		return false
+	}
+
+	errs := make([]error, 0)
+	check := func(what string, value interface{}) {
+		str, ok := value.(string)
+		if !ok || !parameters.RequireExpansion(str) {
+			return
+		}
+		for _, match := range parameters.CurlyReplacement.FindAllString(str, -1) {
+			expr, isCel := parameters.StripCurly(match)
+			if !isCel {
+				continue
+			}
+			idents, err := parameters.CelCheck(expr)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", what, err))
+				continue
+			}
+			for _, ident := range idents {
+				if !isKnown(ident) {
+					errs = append(errs, fmt.Errorf("%s: CEL expression `%s` refer to unknown parameter `%s`", what, expr, ident))
+				}
+			}
+		}
+	}
+	for _, parameter := range params {
+		check(fmt.Sprintf("Parameter `%s`", parameter.QName()), parameter.Value)
+	}
+	for _, output := range outputs {
+		check(fmt.Sprintf("Output `%s`", output.Name), output.Value)
+	}
+	for i, condition := range readyConditions {
+		what := fmt.Sprintf("Stack ready condition #%d", i+1)
+		check(what, condition.DNS)
+		check(what, condition.URL)
+	}
+	for _, component := range componentsManifests {
+		for _, output := range component.Outputs {
+			check(fmt.Sprintf("Component `%s` output `%s`", component.Meta.Name, output.Name), output.Value)
+		}
+		for i, condition := range component.Lifecycle.ReadyConditions {
+			what := fmt.Sprintf("Component `%s` ready condition #%d", component.Meta.Name, i+1)
+			check(what, condition.DNS)
+			check(what, condition.URL)
+		}
+	}
+	if len(errs) > 0 {
+		util.MaybeFatalf("Invalid CEL expression(s):\n\t%s", util.Errors("\n\t", errs...))
+	}
+}
+
 func checkParameters(parametersAssorti [][]manifest.Parameter) {
 	for _, parameters := range parametersAssorti {
 		for _, parameter := range parameters {
@@ -398,7 +517,7 @@ func findKubernetesProvider(st *state.StateManifest) []parameters.CapturedOutput
 		}
 		if util.Contains(kube.KubernetesParameters, name) {
 			apiParameters = append(apiParameters,
-				parameters.CapturedOutput{Name: name, Value: output.Value})
+				parameters.CapturedOutput{Name: name, Value: state.DecryptValue(output.Value)})
 		}
 	}
 	if len(apiParameters) >= 2 {
@@ -413,7 +532,7 @@ func findKubernetesProvider(st *state.StateManifest) []parameters.CapturedOutput
 	for _, providerName := range util.MergeUnique(providers, kube.KubernetesDefaultProviders) {
 		provider, exist := st.Components[providerName]
 		if exist && provider != nil && len(provider.CapturedOutputs) > 0 {
-			return provider.CapturedOutputs
+			return state.DecryptOutputs(provider.CapturedOutputs)
 		}
 	}
 	// then it's either `*platform*` or user-supplied parameters
@@ -421,7 +540,7 @@ func findKubernetesProvider(st *state.StateManifest) []parameters.CapturedOutput
 	for _, param := range st.StackParameters {
 		if util.Contains(kube.KubernetesParameters, param.Name) {
 			apiParameters = append(apiParameters,
-				parameters.CapturedOutput{Name: param.Name, Value: param.Value})
+				parameters.CapturedOutput{Name: param.Name, Value: state.DecryptValue(param.Value)})
 		}
 	}
 	if len(apiParameters) >= 1 {
@@ -430,7 +549,10 @@ func findKubernetesProvider(st *state.StateManifest) []parameters.CapturedOutput
 	return nil
 }
 
-func setValuesFromState(parameters []manifest.Parameter, st *state.StateManifest, useStateStackParameters bool) {
+func setValuesFromState(parameters []manifest.Parameter, st *state.StateManifest, stateFilenames []string,
+	useStateStackParameters bool) {
+
+	source := &manifest.ParameterSource{Mechanism: manifest.ParameterSourceState, File: strings.Join(stateFilenames, ",")}
 	stateStackOutputs := make(map[string]interface{})
 
 	// for apps installed on overlay stack we must look into
@@ -439,7 +561,7 @@ func setValuesFromState(parameters []manifest.Parameter, st *state.StateManifest
 		for _, parameter := range st.StackParameters {
 			// should we filter out `link` parameters?
 			if parameter.Component == "" && !util.Empty(parameter.Value) {
-				stateStackOutputs[parameter.Name] = parameter.Value
+				stateStackOutputs[parameter.Name] = state.DecryptValue(parameter.Value)
 			}
 		}
 	}
@@ -448,7 +570,7 @@ func setValuesFromState(parameters []manifest.Parameter, st *state.StateManifest
 		if i := strings.Index(name, ":"); i > 0 && i < len(name)-1 {
 			name = name[i+1:]
 		}
-		stateStackOutputs[name] = output.Value
+		stateStackOutputs[name] = state.DecryptValue(output.Value)
 	}
 
 	kubeOutputs := findKubernetesProvider(st)
@@ -461,6 +583,7 @@ func setValuesFromState(parameters []manifest.Parameter, st *state.StateManifest
 		if util.Empty(parameter.Value) {
 			value, exist := stateStackOutputs[parameter.Name]
 			if exist {
+				parameter.Source = source
 				if parameter.FromEnv == "" {
 					parameter.Value = value
 				} else {
@@ -484,6 +607,7 @@ func setValuesFromState(parameters []manifest.Parameter, st *state.StateManifest
 					for _, output := range kubeOutputs {
 						if output.Name == parameter.Name {
 							parameter.Value = output.Value
+							parameter.Source = source
 							break
 						}
 					}
@@ -493,9 +617,29 @@ func setValuesFromState(parameters []manifest.Parameter, st *state.StateManifest
 	}
 }
 
+// recordFromStackState checks `fromStackState:` references and records them as parameter source,
+// the value is resolved on deploy
+func recordFromStackState(params []manifest.Parameter) {
+	for i := range params {
+		parameter := &params[i]
+		if parameter.FromStackState == "" || !util.Empty(parameter.Value) {
+			continue
+		}
+		location, output, err := parameters.ParseStackStateRef(parameter.FromStackState)
+		if err != nil {
+			log.Fatalf("Parameter `%s` %v", parameter.QName(), err)
+		}
+		parameter.Source = &manifest.ParameterSource{Mechanism: manifest.ParameterSourceFromStackState,
+			File: location, Ref: output}
+	}
+}
+
 func warnNoValue(parameters []manifest.Parameter) {
 	for _, parameter := range parameters {
 		if parameter.Value == nil {
+			if parameter.FromStackState != "" {
+				continue
+			}
 			who := "Parameter"
 			noDefault := ""
 			if parameter.Kind == "user" {
@@ -623,10 +767,20 @@ func unwrapComponentsParameters(componentsManifests []manifest.Manifest) [][]man
 	return parameters
 }
 
-func unwrapManifestsParameters(parametersManifests []*manifest.ParametersManifest, parametersFilenames []string) [][]manifest.Parameter {
+func unwrapManifestsParameters(parametersManifests []*manifest.ParametersManifest, parametersFilenames []string,
+	overlays map[string]string) [][]manifest.Parameter {
+
 	parameters := make([][]manifest.Parameter, 0, len(parametersManifests))
 	for i, parametersManifest := range parametersManifests {
-		parameters = append(parameters, manifest.FlattenParameters(parametersManifest.Parameters, parametersFilenames[i]))
+		flattened := manifest.FlattenParameters(parametersManifest.Parameters, parametersFilenames[i])
+		if component, exist := overlays[parametersFilenames[i]]; exist {
+			for j := range flattened {
+				if flattened[j].Component == "" {
+					flattened[j].Component = component
+				}
+			}
+		}
+		parameters = append(parameters, flattened)
 	}
 	return parameters
 }
@@ -663,9 +817,17 @@ func mergeParameters(parametersAssorti [][]manifest.Parameter,
 	overrides map[string]string,
 	wellKnown map[string]manifest.Parameter,
 	allComponentsNames []string, nComponents int,
-	isApplication bool) []manifest.Parameter {
+	isApplication bool) ([]manifest.Parameter, parametersProvenance) {
 
 	kv := make(map[string]manifest.Parameter)
+	provenance := make(parametersProvenance)
+	// provenance is a chain of sources that supplied the value, the last one wins
+	trackProvenance := func(qName string, over, merged manifest.Parameter) {
+		overridden := merged.Source != nil && merged.Source.Mechanism == manifest.ParameterSourceOverride
+		if overridden || !util.Empty(over.Value) {
+			provenance.add(qName, merged.Source)
+		}
+	}
 	for docIndex, parameters := range parametersAssorti {
 		isComponentManifest := docIndex < nComponents
 		for _, parameter := range parameters {
@@ -719,6 +881,7 @@ func mergeParameters(parametersAssorti [][]manifest.Parameter,
 					if !exist {
 						if i == 0 { // plain parameter name
 							kv[qName] = parameter
+							trackProvenance(qName, parameter, parameter)
 						}
 					} else {
 						if i != 0 {
@@ -730,6 +893,7 @@ func mergeParameters(parametersAssorti [][]manifest.Parameter,
 							}
 						}
 						kv[qName] = mergeParameter(p, parameter, overrides, false)
+						trackProvenance(qName, parameter, kv[qName])
 					}
 				}
 			} else {
@@ -740,11 +904,12 @@ func mergeParameters(parametersAssorti [][]manifest.Parameter,
 				} else {
 					kv[qName] = mergeParameter(p, parameter, overrides, false)
 				}
+				trackProvenance(qName, parameter, kv[qName])
 			}
 		}
 	}
 
-	return sortedParameters(kv)
+	return sortedParameters(kv), provenance
 }
 
 func updateKindIfFrom(parameter manifest.Parameter, warning bool) manifest.Parameter {
@@ -810,12 +975,15 @@ func mergeParameter(base, over manifest.Parameter, overrides map[string]string,
 	env := mergeField(base.Env, over.Env)
 	fromEnv := mergeField(base.FromEnv, over.FromEnv)
 	fromFile := mergeField(base.FromFile, over.FromFile)
+	fromStackState := mergeField(base.FromStackState, over.FromStackState)
 	defaultValue := mergeValue(base.Default, over.Default)
 	value := mergeValue(base.Value, over.Value)
+	source := mergeSource(base, over)
 	if fromEnv != "" && overrides != nil {
 		envValue, exist := overrides[fromEnv]
 		if exist {
 			value = envValue
+			source = &manifest.ParameterSource{Mechanism: manifest.ParameterSourceOverride, Ref: fromEnv}
 		}
 	}
 	// TODO process fromFile?
@@ -824,17 +992,19 @@ func mergeParameter(base, over manifest.Parameter, overrides map[string]string,
 		empty = ""
 	}
 	merged := manifest.Parameter{
-		Name:        over.Name,
-		Component:   base.Component,
-		Kind:        kind,
-		Brief:       brief,
-		Description: description,
-		Default:     defaultValue,
-		Env:         env,
-		FromEnv:     fromEnv,
-		FromFile:    fromFile,
-		Value:       value,
-		Empty:       empty,
+		Name:           over.Name,
+		Component:      base.Component,
+		Kind:           kind,
+		Brief:          brief,
+		Description:    description,
+		Default:        defaultValue,
+		Env:            env,
+		FromEnv:        fromEnv,
+		FromFile:       fromFile,
+		FromStackState: fromStackState,
+		Value:          value,
+		Empty:          empty,
+		Source:         source,
 	}
 	if config.Trace {
 		log.Printf("Parameters merged:\n\t--- %+v\n\t+++ %+v\n\t=== %+v", base, over, merged)
@@ -842,6 +1012,19 @@ func mergeParameter(base, over manifest.Parameter, overrides map[string]string,
 	return merged
 }
 
+// mergeSource chooses source of the parameter that supplied the value
+func mergeSource(base, over manifest.Parameter) *manifest.ParameterSource {
+	overSupplied := !util.Empty(over.Value) || (util.Empty(base.Value) && !util.Empty(over.Default))
+	baseSupplied := !util.Empty(base.Value) || !util.Empty(base.Default)
+	if (overSupplied || !baseSupplied) && over.Source != nil {
+		return over.Source
+	}
+	if base.Source != nil {
+		return base.Source
+	}
+	return over.Source
+}
+
 func mergeField(base string, over string) string {
 	if over != "" {
 		return over
@@ -1214,6 +1397,9 @@ func mergeComponentsRefs(parentBaseDir, componentsBaseDir string,
 		} else if ref.Source.Git.Remote != "" && parentBaseDir == componentsBaseDir {
 			ref.Source.Git.LocalDir = filepath.Join(parentBaseDir, manifest.ComponentSourceDirNameFromRef(&ref))
 		}
+		if ref.Source.Dir == "" && ref.Source.Registry != "" && parentBaseDir == componentsBaseDir {
+			ref.Source.Dir = filepath.Join(parentBaseDir, manifest.ComponentSourceDirNameFromRef(&ref))
+		}
 		refs = append(refs, ref)
 	}
 	for _, ref := range child {
@@ -1232,6 +1418,24 @@ func mergeComponentsRefs(parentBaseDir, componentsBaseDir string,
 	return refs
 }
 
+func resolveRegistrySources(components []manifest.ComponentRef, excludedComponents []string, componentsBaseDir string) {
+	for i := range components {
+		ref := &components[i]
+		if ref.Source.Registry == "" || ref.Source.Dir != "" || ref.Source.Git.Remote != "" ||
+			util.Contains(excludedComponents, manifest.ComponentQualifiedNameFromRef(ref)) {
+			continue
+		}
+		dir := filepath.Join(componentsBaseDir, manifest.ComponentSourceDirNameFromRef(ref))
+		version, err := registry.Resolve(ref.Source.Registry, config.Registry, dir)
+		if err != nil {
+			log.Fatalf("Unable to resolve component `%s` registry source `%s`: %v",
+				manifest.ComponentQualifiedNameFromRef(ref), ref.Source.Registry, err)
+		}
+		name, _, _ := registry.ParseSpec(ref.Source.Registry)
+		ref.Source.Registry = fmt.Sprintf("%s@%s", name, version)
+	}
+}
+
 func mergeComponentsManifests(parent, child []manifest.Manifest) []manifest.Manifest {
 	manifests := make([]manifest.Manifest, 0, len(parent)+len(child))
 	manifests = append(manifests, parent...)
@@ -0,0 +1,360 @@
+package compose
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/manifest"
+	"github.com/agilestacks/hub/cmd/hub/parameters"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+type eachItem struct {
+	key   string
+	value interface{}
+}
+
+var forEachKeyRegexp = regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9_.-]*$")
+
+// expandForEach replaces components with `forEach:` by instances named `<name>-<key>`,
+// one per item of a list or map - given literally or by stack parameter.
+// Lifecycle, `depends`, component-qualified parameters, and stack outputs are rewritten to refer to instances,
+// `each.key` and `each.value` parameters are added for every instance. A `${app:x}` reference in a parameter
+// of an instance is rewritten to the instance of `app` with the same key, see checkForEachRefs for the rest.
+func expandForEach(stackManifest *manifest.Manifest, params [][]manifest.Parameter,
+	overrides map[string]string) [][]manifest.Parameter {
+
+	instances := make(map[string][]string)
+	eachParams := make([]manifest.Parameter, 0)
+	components := make([]manifest.ComponentRef, 0, len(stackManifest.Components))
+	for _, ref := range stackManifest.Components {
+		if ref.ForEach == nil {
+			components = append(components, ref)
+			continue
+		}
+		name := manifest.ComponentQualifiedNameFromRef(&ref)
+		items, err := forEachItems(ref.ForEach, params, overrides)
+		if err != nil {
+			log.Fatalf("Component `%s` `forEach`: %v", name, err)
+		}
+		names := make([]string, 0, len(items))
+		for _, item := range items {
+			instance := ref
+			instance.Name = fmt.Sprintf("%s-%s", name, item.key)
+			instance.ForEach = nil
+			instance.Each = &manifest.ComponentEach{Of: name, Key: item.key}
+			components = append(components, instance)
+			names = append(names, instance.Name)
+			source := &manifest.ParameterSource{Mechanism: manifest.ParameterSourceForEach, Ref: name}
+			eachParams = append(eachParams,
+				manifest.Parameter{Name: "each.key", Component: instance.Name, Value: item.key, Source: source},
+				manifest.Parameter{Name: "each.value", Component: instance.Name, Value: item.value, Source: source})
+		}
+		if len(names) == 0 {
+			util.Warn("Component `%s` `forEach` is empty - no instances generated", name)
+		} else if config.Verbose {
+			log.Printf("Component `%s` expanded into: %s", name, strings.Join(names, ", "))
+		}
+		instances[name] = names
+	}
+	if len(instances) == 0 {
+		return params
+	}
+
+	expand := func(names []string) []string {
+		expanded := make([]string, 0, len(names))
+		for _, name := range names {
+			if names, exist := instances[name]; exist {
+				expanded = append(expanded, names...)
+			} else {
+				expanded = append(expanded, name)
+			}
+		}
+		return expanded
+	}
+	for i := range components {
+		components[i].Depends = expand(components[i].Depends)
+	}
+	stackManifest.Components = components
+	lifecycle := &stackManifest.Lifecycle
+	lifecycle.Order = expand(lifecycle.Order)
+	lifecycle.Mandatory = expand(lifecycle.Mandatory)
+	lifecycle.Optional = expand(lifecycle.Optional)
+	optional := make([]string, 0, len(lifecycle.Requires.Optional))
+	for _, req := range lifecycle.Requires.Optional {
+		i := strings.Index(req, ":")
+		if i > 0 && i < len(req)-1 {
+			if names, exist := instances[req[i+1:]]; exist {
+				for _, name := range names {
+					optional = append(optional, fmt.Sprintf("%s:%s", req[:i], name))
+				}
+				continue
+			}
+		}
+		optional = append(optional, req)
+	}
+	lifecycle.Requires.Optional = optional
+	stackManifest.Outputs = expandForEachOutputs(stackManifest.Outputs, instances)
+
+	expanded := make([][]manifest.Parameter, 0, len(params))
+	for _, list := range params {
+		parameters := make([]manifest.Parameter, 0, len(list))
+		for _, parameter := range list {
+			if names, exist := instances[parameter.Component]; exist {
+				for _, name := range names {
+					instance := parameter
+					instance.Component = name
+					if value, ok := parameter.Value.(string); ok {
+						key := strings.TrimPrefix(name, parameter.Component+"-")
+						instance.Value = expandForEachRefs(value, key, instances)
+					}
+					parameters = append(parameters, instance)
+				}
+			} else {
+				parameters = append(parameters, parameter)
+			}
+		}
+		expanded = append(expanded, parameters)
+	}
+	if len(expanded) > 0 {
+		expanded[0] = append(expanded[0], eachParams...)
+	}
+	return expanded
+}
+
+// expandForEachOutputs replaces stack outputs that refer to `forEach` component by outputs of every instance:
+// `app:url` becomes `app-us:url`, while `name` with value `${app:url}` becomes `name-us` = `${app-us:url}`
+func expandForEachOutputs(outputs []manifest.Output, instances map[string][]string) []manifest.Output {
+	expanded := make([]manifest.Output, 0, len(outputs))
+	for _, output := range outputs {
+		if i := strings.Index(output.Name, ":"); i > 0 {
+			if names, exist := instances[output.Name[:i]]; exist {
+				for _, name := range names {
+					instance := output
+					instance.Name = name + output.Name[i:]
+					expanded = append(expanded, instance)
+				}
+				continue
+			}
+		}
+		value, ok := output.Value.(string)
+		if !ok || !parameters.RequireExpansion(value) {
+			expanded = append(expanded, output)
+			continue
+		}
+		components := forEachRefs(value, func(component string) bool {
+			_, exist := instances[component]
+			return exist
+		})
+		if len(components) == 0 {
+			expanded = append(expanded, output)
+			continue
+		}
+		if len(components) > 1 {
+			log.Fatalf("Stack output `%s = %s` refer to outputs of several `forEach` components: %s",
+				output.Name, value, strings.Join(components, ", "))
+		}
+		component := components[0]
+		for _, name := range instances[component] {
+			instance := output
+			instance.Name = fmt.Sprintf("%s-%s", output.Name, strings.TrimPrefix(name, component+"-"))
+			instance.Value = parameters.CurlyReplacement.ReplaceAllStringFunc(value,
+				func(match string) string {
+					variable, isCel := parameters.StripCurly(match)
+					if isCel || !strings.HasPrefix(variable, component+":") {
+						return match
+					}
+					return fmt.Sprintf("${%s%s}", name, variable[len(component):])
+				})
+			expanded = append(expanded, instance)
+		}
+	}
+	return expanded
+}
+
+// forEachRefs returns components referred by `${component:output}` in value that match forEach
+func forEachRefs(value string, forEach func(string) bool) []string {
+	components := make([]string, 0)
+	if !parameters.RequireExpansion(value) {
+		return components
+	}
+	for _, match := range parameters.CurlyReplacement.FindAllString(value, -1) {
+		variable, isCel := parameters.StripCurly(match)
+		if isCel {
+			continue
+		}
+		if i := strings.Index(variable, ":"); i > 0 {
+			if component := variable[:i]; forEach(component) && !util.Contains(components, component) {
+				components = append(components, component)
+			}
+		}
+	}
+	return components
+}
+
+// expandForEachRefs rewrites `${app:x}` to `${app-<key>:x}` when `app` has an instance with the key
+func expandForEachRefs(value, key string, instances map[string][]string) string {
+	if !parameters.RequireExpansion(value) {
+		return value
+	}
+	return parameters.CurlyReplacement.ReplaceAllStringFunc(value,
+		func(match string) string {
+			variable, isCel := parameters.StripCurly(match)
+			i := strings.Index(variable, ":")
+			if isCel || i <= 0 {
+				return match
+			}
+			instance := fmt.Sprintf("%s-%s", variable[:i], key)
+			if !util.Contains(instances[variable[:i]], instance) {
+				return match
+			}
+			return fmt.Sprintf("${%s%s}", instance, variable[i:])
+		})
+}
+
+// checkForEachRefs fails elaboration if parameters or outputs still refer to `forEach` component
+// that is expanded into instances - there is no way to tell which instance was meant.
+// That covers references from stack-level and other components parameters, component manifests,
+// and from `fromStack` stack to `forEach` component of the parent stack.
+func checkForEachRefs(components []manifest.ComponentRef, params []manifest.Parameter, outputs []manifest.Output,
+	componentsManifests []manifest.Manifest) {
+
+	forEach := func(component string) bool {
+		return len(manifest.ComponentInstances(components, component)) > 0
+	}
+	errs := make([]string, 0)
+	check := func(what string, value interface{}) {
+		if str, ok := value.(string); ok {
+			if refs := forEachRefs(str, forEach); len(refs) > 0 {
+				errs = append(errs, fmt.Sprintf("%s = %s refer to `forEach` component(s): %s",
+					what, str, strings.Join(refs, ", ")))
+			}
+		}
+	}
+	qualified := func(parameter *manifest.Parameter) string {
+		if parameter.Component != "" {
+			return fmt.Sprintf("%s|%s", parameter.Name, parameter.Component)
+		}
+		return parameter.Name
+	}
+	for i := range params {
+		parameter := &params[i]
+		if forEach(parameter.Component) {
+			errs = append(errs, fmt.Sprintf("Parameter `%s` refer to `forEach` component `%s` from parent stack",
+				qualified(parameter), parameter.Component))
+		}
+		check(fmt.Sprintf("Parameter `%s`", qualified(parameter)), parameter.Value)
+		check(fmt.Sprintf("Parameter `%s` default", qualified(parameter)), parameter.Default)
+	}
+	for _, output := range outputs {
+		if i := strings.Index(output.Name, ":"); i > 0 && forEach(output.Name[:i]) {
+			errs = append(errs, fmt.Sprintf("Stack output `%s` refer to `forEach` component `%s` from parent stack",
+				output.Name, output.Name[:i]))
+		}
+		check(fmt.Sprintf("Stack output `%s`", output.Name), output.Value)
+	}
+	for _, componentManifest := range componentsManifests {
+		for _, parameter := range manifest.FlattenParameters(componentManifest.Parameters, componentManifest.Meta.Name) {
+			check(fmt.Sprintf("Component `%s` parameter `%s`", componentManifest.Meta.Name, parameter.Name),
+				parameter.Value)
+		}
+	}
+	if len(errs) > 0 {
+		log.Fatalf("Unable to resolve references to `forEach` component(s) expanded into instances:\n\t%s\n"+
+			"Refer to an instance, ie. `${<component>-<key>:<output>}`, instead",
+			strings.Join(errs, "\n\t"))
+	}
+}
+
+// forEachItems returns items of a literal list or map, or of a stack parameter value
+func forEachItems(forEach interface{}, params [][]manifest.Parameter, overrides map[string]string) ([]eachItem, error) {
+	if name, ok := forEach.(string); ok {
+		value, err := forEachParameterValue(name, params, overrides)
+		if err != nil {
+			return nil, err
+		}
+		forEach = value
+	}
+	items := make([]eachItem, 0)
+	switch list := forEach.(type) {
+	case []interface{}:
+		for _, value := range list {
+			items = append(items, eachItem{key: fmt.Sprintf("%v", value), value: value})
+		}
+	case map[interface{}]interface{}:
+		keys := make([]string, 0, len(list))
+		values := make(map[string]interface{})
+		for key, value := range list {
+			str := fmt.Sprintf("%v", key)
+			keys = append(keys, str)
+			values[str] = value
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			items = append(items, eachItem{key: key, value: values[key]})
+		}
+	case string:
+		for _, value := range util.SplitPaths(list) {
+			value = strings.TrimSpace(value)
+			items = append(items, eachItem{key: value, value: value})
+		}
+	default:
+		return nil, fmt.Errorf("must be a parameter name, a list, or a map; got `%v`", forEach)
+	}
+
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		switch item.value.(type) {
+		case []interface{}, map[interface{}]interface{}:
+			return nil, fmt.Errorf("item `%v` must be a scalar value", item.value)
+		}
+		if !forEachKeyRegexp.MatchString(item.key) {
+			return nil, fmt.Errorf("key `%s` cannot be used in component name", item.key)
+		}
+		if util.Contains(keys, item.key) {
+			return nil, fmt.Errorf("duplicate key `%s`", item.key)
+		}
+		keys = append(keys, item.key)
+	}
+	return items, nil
+}
+
+// forEachParameterValue finds stack-level parameter value; parameters files take precedence
+// over stack manifest, fromEnv is resolved via environment overrides or OS environment
+func forEachParameterValue(name string, params [][]manifest.Parameter, overrides map[string]string) (interface{}, error) {
+	var found *manifest.Parameter
+	for i := range params {
+		for j := range params[i] {
+			parameter := &params[i][j]
+			if parameter.Name != name || parameter.Component != "" {
+				continue
+			}
+			if found == nil || !util.Empty(parameter.Value) || parameter.FromEnv != "" {
+				found = parameter
+			}
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no stack parameter `%s` found", name)
+	}
+	if !util.Empty(found.Value) {
+		return found.Value, nil
+	}
+	if found.FromEnv != "" {
+		if value, exist := overrides[found.FromEnv]; exist {
+			return value, nil
+		}
+		if value, exist := os.LookupEnv(found.FromEnv); exist {
+			return value, nil
+		}
+	}
+	if !util.Empty(found.Default) {
+		return found.Default, nil
+	}
+	return nil, fmt.Errorf("stack parameter `%s` has no value", name)
+}
@@ -0,0 +1,105 @@
+package compose
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/manifest"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+const paramsDir = "params"
+
+// scanEnvironmentParamsFiles returns environment layers in order of precedence:
+// params/common.yaml, params/<env>.yaml, and params/<env>/<component>.yaml overlays.
+// Overlays are mapped to the component they apply to.
+func scanEnvironmentParamsFiles(baseDir, env string) ([]string, map[string]string) {
+	dir := filepath.Join(baseDir, paramsDir)
+	exists := make([]string, 0)
+	for _, filename := range []string{"common.yaml", env + ".yaml"} {
+		path := filepath.Join(dir, filename)
+		_, err := os.Stat(path)
+		if err != nil {
+			if !util.NoSuchFile(err) {
+				log.Fatalf("Unable to stat `%s`: %v", path, err)
+			}
+		} else {
+			exists = append(exists, path)
+		}
+	}
+	overlays := make(map[string]string)
+	overlayDir := filepath.Join(dir, env)
+	files, err := ioutil.ReadDir(overlayDir)
+	if err != nil && !util.NoSuchFile(err) {
+		log.Fatalf("Unable to read `%s` directory: %v", overlayDir, err)
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".yaml") {
+			continue
+		}
+		path := filepath.Join(overlayDir, file.Name())
+		exists = append(exists, path)
+		overlays[path] = strings.TrimSuffix(file.Name(), ".yaml")
+	}
+	if len(exists) == 0 {
+		log.Fatalf("No parameters found for environment `%s` in `%s`", env, dir)
+	}
+	if config.Verbose {
+		log.Printf("Environment `%s` parameters layers: %s", env, strings.Join(exists, ", "))
+	}
+	return exists, overlays
+}
+
+type parametersProvenance map[string][]*manifest.ParameterSource
+
+func (provenance parametersProvenance) add(qName string, source *manifest.ParameterSource) {
+	if source == nil {
+		return
+	}
+	chain := provenance[qName]
+	if len(chain) > 0 && *chain[len(chain)-1] == *source {
+		return
+	}
+	provenance[qName] = append(chain, source)
+}
+
+func printParametersSources(parameters []manifest.Parameter) {
+	for _, parameter := range parameters {
+		if parameter.Source != nil && (!util.Empty(parameter.Value) || !util.Empty(parameter.Default)) {
+			log.Printf("Parameter `%s` value from %s", parameter.QName(), parameter.Source.String())
+		}
+	}
+}
+
+func printParameterProvenance(name string, provenance parametersProvenance) {
+	names := make([]string, 0, len(provenance))
+	for qName := range provenance {
+		names = append(names, qName)
+	}
+	sort.Strings(names)
+	found := false
+	for _, qName := range names {
+		if qName != name && !strings.HasPrefix(qName, name+"|") {
+			continue
+		}
+		found = true
+		fmt.Printf("%s:\n", qName)
+		chain := provenance[qName]
+		for i, source := range chain {
+			marker := "overridden"
+			if i == len(chain)-1 {
+				marker = "wins"
+			}
+			fmt.Printf("\t%d. %s (%s)\n", i+1, source.String(), marker)
+		}
+	}
+	if !found {
+		fmt.Printf("Parameter `%s` has no value set in any of the sources\n", name)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v2"
@@ -68,6 +69,19 @@ func BackupUnbundle(bundlesFilenames [][]string, parametersFiles []string,
 	}
 }
 
+// BackupComponents returns names of components with a valid backup in bundle(s), after renames and filters are applied
+func BackupComponents(bundlesFilenames [][]string, rename, erase, includeOnly []string) []string {
+	renames := prepareComponentRenames(rename)
+	bundles := parseBundles(bundlesFilenames)
+	backups := selectBackups(extractBackups(bundles, renames, erase, includeOnly))
+	components := make([]string, 0, len(backups))
+	for componentName := range backups {
+		components = append(components, componentName)
+	}
+	sort.Strings(components)
+	return components
+}
+
 func prepareComponentRenames(rename []string) map[string]string {
 	renames := make(map[string]string)
 	for _, ren := range rename {
@@ -91,7 +105,13 @@ func prepareComponentRenames(rename []string) map[string]string {
 func parseBundles(bundlesFilenames [][]string) []*state.BackupManifest {
 	bundles := make([]*state.BackupManifest, 0, len(bundlesFilenames))
 	for _, filename := range bundlesFilenames {
-		bundles = append(bundles, state.MustParseBackupBundles(filename))
+		bundle := state.MustParseBackupBundles(filename)
+		if bundle.Checksum != "" {
+			if err := state.VerifyBackupBundleChecksum(bundle); err != nil {
+				util.MaybeFatalf("`%s`: %v", bundle.Source, err)
+			}
+		}
+		bundles = append(bundles, bundle)
 	}
 	return bundles
 }
@@ -42,6 +42,21 @@ var (
 	CryptoPassword           string
 	CryptoAwsKmsKeyArn       string
 	CryptoAzureKeyVaultKeyId string
+	CryptoGcpKmsKeyName      string
+	CryptoAgeRecipients      string
+	CryptoAgeIdentity        string
+
+	CryptoPasswordNew           string
+	CryptoAwsKmsKeyArnNew       string
+	CryptoAzureKeyVaultKeyIdNew string
+	CryptoGcpKmsKeyNameNew      string
+	CryptoAgeRecipientsNew      string
+	CryptoAgeIdentityNew        string
+
+	Registry string
+
+	TracingEndpoint string
+	TracingFile     string
 
 	GitBinDefault = "/usr/bin/git"
 )
@@ -65,14 +80,14 @@ func Update() {
 
 	switch EncryptionMode {
 	case "true":
-		if CryptoPassword == "" && CryptoAwsKmsKeyArn == "" && CryptoAzureKeyVaultKeyId == "" {
-			log.Fatal("For --encrypted=true, set HUB_CRYPTO_PASSWORD='random password' or HUB_CRYPTO_AWS_KMS_KEY_ARN='arn:aws:kms:...' or HUB_CRYPTO_AZURE_KEYVAULT_KEY_ID='https://*.vault.azure.net/keys/...'")
+		if !cryptoKeySet() {
+			log.Fatal("For --encrypted=true, set HUB_CRYPTO_PASSWORD='random password' or HUB_CRYPTO_AWS_KMS_KEY_ARN='arn:aws:kms:...' or HUB_CRYPTO_AZURE_KEYVAULT_KEY_ID='https://*.vault.azure.net/keys/...' or HUB_CRYPTO_GCP_KMS_KEY_NAME='projects/*/locations/*/keyRings/*/cryptoKeys/*' or HUB_CRYPTO_AGE_RECIPIENTS='age1...'")
 		}
 		Encrypted = true
 	case "false":
 		Encrypted = false
 	case "if-key-set":
-		Encrypted = CryptoPassword != "" || CryptoAwsKmsKeyArn != "" || CryptoAzureKeyVaultKeyId != ""
+		Encrypted = cryptoKeySet()
 	default:
 		log.Fatalf("Unknown --encrypted `%s`", EncryptionMode)
 	}
@@ -90,3 +105,8 @@ func Update() {
 		log.Fatalf("Unknown --tty `%s`", TtyMode)
 	}
 }
+
+func cryptoKeySet() bool {
+	return CryptoPassword != "" || CryptoAwsKmsKeyArn != "" || CryptoAzureKeyVaultKeyId != "" ||
+		CryptoGcpKmsKeyName != "" || CryptoAgeRecipients != "" || CryptoAgeIdentity != ""
+}
@@ -0,0 +1,295 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// X25519 recipients use age key encoding so that keys could be generated by `age-keygen` or `hub crypto keygen`:
+// recipient (public key) is age1..., identity (private key) is AGE-SECRET-KEY-1...
+// For each recipient the data key is wrapped into a stanza of ephemeral public key and
+// ChaCha20-Poly1305 encrypted data key, with wrapping key derived from X25519 shared secret by HKDF-SHA256.
+
+const (
+	x25519KeySize       = curve25519.ScalarSize
+	x25519MacLen        = 16 // Poly1305
+	x25519StanzaLen     = x25519KeySize + aes256KeySize + x25519MacLen
+	x25519MaxRecipients = 255
+
+	ageRecipientHrp = "age"
+	ageIdentityHrp  = "age-secret-key-"
+)
+
+var x25519WrapLabel = []byte("hub-crypto/v5/X25519")
+
+func GenerateX25519Identity() (string, string, error) {
+	identity := make([]byte, x25519KeySize)
+	_, err := rand.Read(identity)
+	if err != nil {
+		return "", "", err
+	}
+	recipient, err := curve25519.X25519(identity, curve25519.Basepoint)
+	if err != nil {
+		return "", "", err
+	}
+	encodedIdentity, err := bech32Encode(ageIdentityHrp, identity)
+	if err != nil {
+		return "", "", err
+	}
+	encodedRecipient, err := bech32Encode(ageRecipientHrp, recipient)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.ToUpper(encodedIdentity), encodedRecipient, nil
+}
+
+func parseX25519Recipients(recipients string) ([][]byte, error) {
+	keys := make([][]byte, 0)
+	for _, recipient := range strings.FieldsFunc(recipients, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' }) {
+		hrp, key, err := bech32Decode(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to decode recipient `%s`: %v", recipient, err)
+		}
+		if hrp != ageRecipientHrp || len(key) != x25519KeySize {
+			return nil, fmt.Errorf("`%s` is not an X25519 recipient", recipient)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("No X25519 recipients")
+	}
+	if len(keys) > x25519MaxRecipients {
+		return nil, fmt.Errorf("Too many X25519 recipients: %d, max %d", len(keys), x25519MaxRecipients)
+	}
+	return keys, nil
+}
+
+// identity is either AGE-SECRET-KEY-1... or a path to a file with one identity per line
+func parseX25519Identities(identity string) ([][]byte, error) {
+	lines := []string{identity}
+	if !strings.HasPrefix(strings.ToLower(identity), ageIdentityHrp) {
+		content, err := ioutil.ReadFile(identity)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read identity file: %v", err)
+		}
+		lines = strings.Split(string(content), "\n")
+	}
+	keys := make([][]byte, 0)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hrp, key, err := bech32Decode(line)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to decode identity: %v", err)
+		}
+		if hrp != ageIdentityHrp || len(key) != x25519KeySize {
+			return nil, errors.New("Not an X25519 identity")
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("No X25519 identities")
+	}
+	return keys, nil
+}
+
+func x25519Recipient(identity []byte) ([]byte, error) {
+	return curve25519.X25519(identity, curve25519.Basepoint)
+}
+
+func x25519WrapKey(shared, ephemeral, recipient []byte) ([]byte, error) {
+	salt := make([]byte, 0, 2*x25519KeySize)
+	salt = append(salt, ephemeral...)
+	salt = append(salt, recipient...)
+	wrapKey := make([]byte, chacha20poly1305.KeySize)
+	_, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, x25519WrapLabel), wrapKey)
+	return wrapKey, err
+}
+
+// x25519Key generates a new data key wrapped for each recipient if no blob is supplied,
+// otherwise the blob is unwrapped with one of the identities
+func x25519Key(recipients, identities [][]byte, blob []byte) ([]byte, []byte, error) {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	if len(blob) == 0 {
+		key := make([]byte, aes256KeySize)
+		_, err := rand.Read(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		blob = make([]byte, 0, 1+len(recipients)*x25519StanzaLen)
+		blob = append(blob, byte(len(recipients)))
+		for _, recipient := range recipients {
+			ephemeralSecret := make([]byte, x25519KeySize)
+			_, err := rand.Read(ephemeralSecret)
+			if err != nil {
+				return nil, nil, err
+			}
+			ephemeral, err := curve25519.X25519(ephemeralSecret, curve25519.Basepoint)
+			if err != nil {
+				return nil, nil, err
+			}
+			shared, err := curve25519.X25519(ephemeralSecret, recipient)
+			if err != nil {
+				return nil, nil, err
+			}
+			wrapKey, err := x25519WrapKey(shared, ephemeral, recipient)
+			if err != nil {
+				return nil, nil, err
+			}
+			aead, err := chacha20poly1305.New(wrapKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			blob = append(blob, ephemeral...)
+			blob = aead.Seal(blob, nonce, key, nil)
+		}
+		return key, blob, nil
+	}
+
+	if len(blob) < 1 || len(blob) != 1+int(blob[0])*x25519StanzaLen {
+		return nil, nil, errors.New("Bad X25519 recipients blob")
+	}
+	for _, identity := range identities {
+		recipient, err := x25519Recipient(identity)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := 0; i < int(blob[0]); i++ {
+			stanza := blob[1+i*x25519StanzaLen : 1+(i+1)*x25519StanzaLen]
+			ephemeral := stanza[:x25519KeySize]
+			shared, err := curve25519.X25519(identity, ephemeral)
+			if err != nil {
+				continue
+			}
+			wrapKey, err := x25519WrapKey(shared, ephemeral, recipient)
+			if err != nil {
+				return nil, nil, err
+			}
+			aead, err := chacha20poly1305.New(wrapKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			key, err := aead.Open(nil, nonce, stanza[x25519KeySize:], nil)
+			if err == nil {
+				return key, blob, nil
+			}
+		}
+	}
+	return nil, nil, errors.New("No identity matches any of the data recipients")
+}
+
+// Bech32 as specified by BIP 173, without 90 characters length limit - same as age
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var bech32Generator = []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+func bech32Polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= bech32Generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HrpExpand(hrp string) []byte {
+	h := []byte(hrp)
+	expanded := make([]byte, 0, len(h)*2+1)
+	for _, c := range h {
+		expanded = append(expanded, c>>5)
+	}
+	expanded = append(expanded, 0)
+	for _, c := range h {
+		expanded = append(expanded, c&31)
+	}
+	return expanded
+}
+
+func bech32ConvertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	var acc uint32
+	var bits uint
+	maxv := uint32(1)<<to - 1
+	converted := make([]byte, 0, len(data)*int(from)/int(to)+1)
+	for _, b := range data {
+		if uint32(b)>>from != 0 {
+			return nil, errors.New("Invalid data range")
+		}
+		acc = acc<<from | uint32(b)
+		bits += from
+		for bits >= to {
+			bits -= to
+			converted = append(converted, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			converted = append(converted, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, errors.New("Invalid padding")
+	}
+	return converted, nil
+}
+
+func bech32Encode(hrp string, data []byte) (string, error) {
+	values, err := bech32ConvertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	polymod := bech32Polymod(append(append(bech32HrpExpand(hrp), values...), 0, 0, 0, 0, 0, 0)) ^ 1
+	var encoded strings.Builder
+	encoded.WriteString(hrp)
+	encoded.WriteByte('1')
+	for _, v := range values {
+		encoded.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		encoded.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+	return encoded.String(), nil
+}
+
+func bech32Decode(encoded string) (string, []byte, error) {
+	if strings.ToLower(encoded) != encoded && strings.ToUpper(encoded) != encoded {
+		return "", nil, errors.New("Mixed case")
+	}
+	encoded = strings.ToLower(encoded)
+	pos := strings.LastIndex(encoded, "1")
+	if pos < 1 || pos+7 > len(encoded) {
+		return "", nil, errors.New("Separator '1' at invalid position")
+	}
+	hrp := encoded[:pos]
+	values := make([]byte, 0, len(encoded)-pos-1)
+	for _, c := range encoded[pos+1:] {
+		v := strings.IndexRune(bech32Charset, c)
+		if v < 0 {
+			return "", nil, fmt.Errorf("Invalid character '%c'", c)
+		}
+		values = append(values, byte(v))
+	}
+	if bech32Polymod(append(bech32HrpExpand(hrp), values...)) != 1 {
+		return "", nil, errors.New("Invalid checksum")
+	}
+	data, err := bech32ConvertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
@@ -6,14 +6,17 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha1"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"sync"
 
 	"golang.org/x/crypto/pbkdf2"
 
 	"github.com/agilestacks/hub/cmd/hub/aws"
 	"github.com/agilestacks/hub/cmd/hub/azure"
 	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/gcp"
 	"github.com/agilestacks/hub/cmd/hub/util"
 )
 
@@ -24,51 +27,157 @@ const (
 	encryptionV1MarkerByte1      = '\x01'
 	encryptionV2MarkerByte1      = '\x02'
 	encryptionV3MarkerByte1      = '\x03'
+	encryptionV4MarkerByte1      = '\x04'
+	encryptionV5MarkerByte1      = '\x05'
 	encryptionV1SaltLen          = 8
 	encryptionNonceLen           = 12
 	encryptionV2EncryptedBlobLen = 184 // encrypted AES256 key and 152 bytes of fixed-size AWS KMS meta
 	encryptionV3EncryptedBlobLen = 256 // RSA-OAEP-256
+	encryptionV4BlobLenLen       = 2   // GCP KMS ciphertext size is not fixed, the blob is prefixed by uint16 length
+	encryptionV4MaxBlobLen       = 1024
+	encryptionV5BlobLenLen       = 1 // number of X25519 recipients stanzas
 	encryptionMacLen             = 16
 
 	EncryptionV1Overhead = 2 + encryptionV1SaltLen + encryptionNonceLen + encryptionMacLen
 	EncryptionV2Overhead = 2 + encryptionV2EncryptedBlobLen + encryptionNonceLen + encryptionMacLen
 	EncryptionV3Overhead = 2 + encryptionV3EncryptedBlobLen + encryptionNonceLen + encryptionMacLen
+	// minimal overhead, plus GCP KMS ciphertext size
+	EncryptionV4Overhead = 2 + encryptionV4BlobLenLen + encryptionNonceLen + encryptionMacLen
+	// minimal overhead, plus size of stanza per recipient
+	EncryptionV5Overhead = 2 + encryptionV5BlobLenLen + encryptionNonceLen + encryptionMacLen
 
 	helpPassword      = "HUB_CRYPTO_PASSWORD='random password'"
 	helpAwsKms        = "HUB_CRYPTO_AWS_KMS_KEY_ARN='arn:aws:kms:...'"
 	helpAzukeKeyvault = "HUB_CRYPTO_AZURE_KEYVAULT_KEY_ID='https://*.vault.azure.net/keys/...'"
+	helpGcpKms        = "HUB_CRYPTO_GCP_KMS_KEY_NAME='projects/*/locations/*/keyRings/*/cryptoKeys/*'"
+	helpAgeRecipients = "HUB_CRYPTO_AGE_RECIPIENTS='age1...,age1...'"
+	helpAgeIdentity   = "HUB_CRYPTO_AGE_IDENTITY='AGE-SECRET-KEY-1...' or path to identity file"
 )
 
 var (
 	encryptionVer  byte
 	encryptionBlob []byte
 	encryptionKey  []byte
+
+	// data keys are cached to not call KMS or derive the key for every encrypted value in state
+	decryptionKeys     = make(map[decryptionKeyId][]byte)
+	decryptionKeysLock sync.Mutex
 )
 
+type decryptionKeyId struct {
+	setup KeySetup
+	ver   byte
+	blob  string
+}
+
 func IsEncryptedData(data []byte) bool {
-	return (len(data) > EncryptionV1Overhead || len(data) > EncryptionV2Overhead || len(data) > EncryptionV3Overhead) &&
+	return (len(data) > EncryptionV1Overhead || len(data) > EncryptionV4Overhead || len(data) > EncryptionV5Overhead) &&
 		data[0] == encryptionMarkerByte0 &&
-		(data[1] == encryptionV1MarkerByte1 || data[1] == encryptionV2MarkerByte1 || data[1] == encryptionV3MarkerByte1)
+		(data[1] == encryptionV1MarkerByte1 || data[1] == encryptionV2MarkerByte1 || data[1] == encryptionV3MarkerByte1 ||
+			data[1] == encryptionV4MarkerByte1 || data[1] == encryptionV5MarkerByte1)
+}
+
+// EncryptionOverhead returns exact size difference between encrypted data and clear data
+// as recorded in the encryption header
+func EncryptionOverhead(encrypted []byte) (int, error) {
+	if !IsEncryptedData(encrypted) {
+		return 0, errors.New("Not an encrypted data")
+	}
+	blobLen, err := encryptionBlobLen(encrypted)
+	if err != nil {
+		return 0, err
+	}
+	return 2 + blobLen + encryptionNonceLen + encryptionMacLen, nil
+}
+
+// encryptionBlobLen returns length of the blob that follows the version marker
+func encryptionBlobLen(encrypted []byte) (int, error) {
+	switch encrypted[1] {
+	case encryptionV1MarkerByte1:
+		return encryptionV1SaltLen, nil
+	case encryptionV2MarkerByte1:
+		return encryptionV2EncryptedBlobLen, nil
+	case encryptionV3MarkerByte1:
+		return encryptionV3EncryptedBlobLen, nil
+	case encryptionV4MarkerByte1:
+		if len(encrypted) < 2+encryptionV4BlobLenLen {
+			return 0, errors.New("Insufficient ciphertext length")
+		}
+		return encryptionV4BlobLenLen + int(binary.BigEndian.Uint16(encrypted[2:])), nil
+	case encryptionV5MarkerByte1:
+		if len(encrypted) < 2+encryptionV5BlobLenLen {
+			return 0, errors.New("Insufficient ciphertext length")
+		}
+		return encryptionV5BlobLenLen + int(encrypted[2])*x25519StanzaLen, nil
+	}
+	return 0, fmt.Errorf("Unknown encryption version %d", encrypted[1])
+}
+
+type KeySetup struct {
+	Password           string
+	AwsKmsKeyArn       string
+	AzureKeyVaultKeyId string
+	GcpKmsKeyName      string
+	AgeRecipients      string
+	AgeIdentity        string
+}
+
+func (setup KeySetup) IsSet() bool {
+	return setup.Password != "" || setup.AwsKmsKeyArn != "" || setup.AzureKeyVaultKeyId != "" ||
+		setup.GcpKmsKeyName != "" || setup.AgeRecipients != "" || setup.AgeIdentity != ""
+}
+
+// CurrentKeySetup is HUB_CRYPTO_* setup used to encrypt and decrypt data
+func CurrentKeySetup() KeySetup {
+	return KeySetup{
+		Password:           config.CryptoPassword,
+		AwsKmsKeyArn:       config.CryptoAwsKmsKeyArn,
+		AzureKeyVaultKeyId: config.CryptoAzureKeyVaultKeyId,
+		GcpKmsKeyName:      config.CryptoGcpKmsKeyName,
+		AgeRecipients:      config.CryptoAgeRecipients,
+		AgeIdentity:        config.CryptoAgeIdentity,
+	}
+}
+
+// NewKeySetup is HUB_CRYPTO_*_NEW setup to rekey data into
+func NewKeySetup() KeySetup {
+	return KeySetup{
+		Password:           config.CryptoPasswordNew,
+		AwsKmsKeyArn:       config.CryptoAwsKmsKeyArnNew,
+		AzureKeyVaultKeyId: config.CryptoAzureKeyVaultKeyIdNew,
+		GcpKmsKeyName:      config.CryptoGcpKmsKeyNameNew,
+		AgeRecipients:      config.CryptoAgeRecipientsNew,
+		AgeIdentity:        config.CryptoAgeIdentityNew,
+	}
 }
 
 // for password based key the blob is salt
-// for AWS KMS and Azure Key Vault the blob is encrypted data key
+// for AWS KMS, Azure Key Vault, and GCP KMS the blob is encrypted data key
+// for X25519 recipients the blob is data key wrapped for each recipient
 // if no blob is supplied then a new key is requested
 // if ver is supplied then it must match envionment setup
-func encryptionKeyInit(ver byte, blob []byte) (byte, []byte, []byte, error) {
-	if ver == encryptionV1MarkerByte1 && config.CryptoPassword == "" {
+func encryptionKeyInit(setup KeySetup, ver byte, blob []byte) (byte, []byte, []byte, error) {
+	if ver == encryptionV1MarkerByte1 && setup.Password == "" {
 		return 0, nil, nil,
 			fmt.Errorf("Set %s", helpPassword)
 	}
-	if ver == encryptionV2MarkerByte1 && config.CryptoAwsKmsKeyArn == "" {
+	if ver == encryptionV2MarkerByte1 && setup.AwsKmsKeyArn == "" {
 		return 0, nil, nil,
 			fmt.Errorf("Set %s", helpAwsKms)
 	}
-	if ver == encryptionV3MarkerByte1 && config.CryptoAzureKeyVaultKeyId == "" {
+	if ver == encryptionV3MarkerByte1 && setup.AzureKeyVaultKeyId == "" {
 		return 0, nil, nil,
 			fmt.Errorf("Set %s", helpAzukeKeyvault)
 	}
-	if config.CryptoPassword != "" && (ver == 0 || ver == encryptionV1MarkerByte1) {
+	if ver == encryptionV4MarkerByte1 && setup.GcpKmsKeyName == "" {
+		return 0, nil, nil,
+			fmt.Errorf("Set %s", helpGcpKms)
+	}
+	if ver == encryptionV5MarkerByte1 && setup.AgeIdentity == "" {
+		return 0, nil, nil,
+			fmt.Errorf("Set %s", helpAgeIdentity)
+	}
+	if setup.Password != "" && (ver == 0 || ver == encryptionV1MarkerByte1) {
 		salt := blob
 		if len(salt) == 0 {
 			salt = make([]byte, encryptionV1SaltLen)
@@ -77,35 +186,98 @@ func encryptionKeyInit(ver byte, blob []byte) (byte, []byte, []byte, error) {
 				return 0, nil, nil, err
 			}
 		}
-		key := pbkdf2.Key([]byte(config.CryptoPassword), salt, 4096, aes256KeySize, sha1.New)
+		key := pbkdf2.Key([]byte(setup.Password), salt, 4096, aes256KeySize, sha1.New)
 		return encryptionV1MarkerByte1, salt, key, nil
 	}
-	if config.CryptoAwsKmsKeyArn != "" && (ver == 0 || ver == encryptionV2MarkerByte1) {
-		clearKey, encryptedKey, err := aws.KmsKey(config.CryptoAwsKmsKeyArn, blob)
+	if setup.AwsKmsKeyArn != "" && (ver == 0 || ver == encryptionV2MarkerByte1) {
+		clearKey, encryptedKey, err := aws.KmsKey(setup.AwsKmsKeyArn, blob)
 		if err != nil {
 			return 0, nil, nil, err
 		}
 		return encryptionV2MarkerByte1, encryptedKey, clearKey, nil
 	}
-	if config.CryptoAzureKeyVaultKeyId != "" && (ver == 0 || ver == encryptionV3MarkerByte1) {
-		clearKey, encryptedKey, err := azure.KeyvaultKey(config.CryptoAzureKeyVaultKeyId, blob)
+	if setup.AzureKeyVaultKeyId != "" && (ver == 0 || ver == encryptionV3MarkerByte1) {
+		clearKey, encryptedKey, err := azure.KeyvaultKey(setup.AzureKeyVaultKeyId, blob)
 		if err != nil {
 			return 0, nil, nil, err
 		}
 		return encryptionV3MarkerByte1, encryptedKey, clearKey, nil
 	}
+	if setup.GcpKmsKeyName != "" && (ver == 0 || ver == encryptionV4MarkerByte1) {
+		var kmsBlob []byte
+		if len(blob) > 0 {
+			kmsBlob = blob[encryptionV4BlobLenLen:]
+		}
+		clearKey, encryptedKey, err := gcp.KmsKey(setup.GcpKmsKeyName, kmsBlob)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		if len(encryptedKey) > encryptionV4MaxBlobLen {
+			return 0, nil, nil, fmt.Errorf("GCP KMS ciphertext size %d is over %d", len(encryptedKey), encryptionV4MaxBlobLen)
+		}
+		prefixed := make([]byte, encryptionV4BlobLenLen, encryptionV4BlobLenLen+len(encryptedKey))
+		binary.BigEndian.PutUint16(prefixed, uint16(len(encryptedKey)))
+		return encryptionV4MarkerByte1, append(prefixed, encryptedKey...), clearKey, nil
+	}
+	if (setup.AgeRecipients != "" || setup.AgeIdentity != "") && (ver == 0 || ver == encryptionV5MarkerByte1) {
+		var recipients, identities [][]byte
+		var err error
+		if setup.AgeIdentity != "" {
+			identities, err = parseX25519Identities(setup.AgeIdentity)
+			if err != nil {
+				return 0, nil, nil, err
+			}
+		}
+		if len(blob) == 0 {
+			if setup.AgeRecipients != "" {
+				recipients, err = parseX25519Recipients(setup.AgeRecipients)
+				if err != nil {
+					return 0, nil, nil, err
+				}
+			} else {
+				// encrypt to self
+				for _, identity := range identities {
+					recipient, err := x25519Recipient(identity)
+					if err != nil {
+						return 0, nil, nil, err
+					}
+					recipients = append(recipients, recipient)
+				}
+			}
+		}
+		clearKey, wrappedKey, err := x25519Key(recipients, identities, blob)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		return encryptionV5MarkerByte1, wrappedKey, clearKey, nil
+	}
 	return 0, nil, nil,
-		fmt.Errorf("Set %s or %s or %s", helpPassword, helpAwsKms, helpAzukeKeyvault)
+		fmt.Errorf("Set %s or %s or %s or %s or %s", helpPassword, helpAwsKms, helpAzukeKeyvault, helpGcpKms, helpAgeRecipients)
 }
 
 func maybeEncryptionKeyInit() (byte, []byte, []byte, error) {
 	var err error
 	if len(encryptionKey) == 0 {
-		encryptionVer, encryptionBlob, encryptionKey, err = encryptionKeyInit(0, nil)
+		encryptionVer, encryptionBlob, encryptionKey, err = encryptionKeyInit(CurrentKeySetup(), 0, nil)
 	}
 	return encryptionVer, encryptionBlob, encryptionKey, err
 }
 
+func decryptionKey(setup KeySetup, ver byte, blob []byte) ([]byte, error) {
+	id := decryptionKeyId{setup: setup, ver: ver, blob: string(blob)}
+	decryptionKeysLock.Lock()
+	defer decryptionKeysLock.Unlock()
+	if key, exist := decryptionKeys[id]; exist {
+		return key, nil
+	}
+	_, _, key, err := encryptionKeyInit(setup, ver, blob)
+	if err != nil {
+		return nil, err
+	}
+	decryptionKeys[id] = key
+	return key, nil
+}
+
 func Encrypt(data []byte) ([]byte, error) {
 	if len(data) == 0 {
 		return data, nil
@@ -114,6 +286,10 @@ func Encrypt(data []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	return encrypt(data, ver, blob, key)
+}
+
+func encrypt(data []byte, ver byte, blob, key []byte) ([]byte, error) {
 	if ver == encryptionV2MarkerByte1 && len(blob) != encryptionV2EncryptedBlobLen {
 		util.WarnOnce("AWS KMS `CiphertextBlob` size %d doesn't match built-in size %d",
 			len(blob), encryptionV2EncryptedBlobLen)
@@ -150,6 +326,10 @@ func Encrypt(data []byte) ([]byte, error) {
 }
 
 func Decrypt(encrypted []byte) ([]byte, error) {
+	return decrypt(CurrentKeySetup(), encrypted)
+}
+
+func decrypt(setup KeySetup, encrypted []byte) ([]byte, error) {
 	if len(encrypted) == 0 {
 		return encrypted, nil
 	}
@@ -157,17 +337,13 @@ func Decrypt(encrypted []byte) ([]byte, error) {
 		return nil, errors.New("Bad ciphertext marker")
 	}
 
-	overhead := EncryptionV1Overhead
-	blobLen := encryptionV1SaltLen
 	ver := encrypted[1]
-	if ver == encryptionV2MarkerByte1 {
-		overhead = EncryptionV2Overhead
-		blobLen = encryptionV2EncryptedBlobLen
-	} else if ver == encryptionV3MarkerByte1 {
-		overhead = EncryptionV3Overhead
-		blobLen = encryptionV3EncryptedBlobLen
-	}
-	if len(encrypted) < overhead+aes.BlockSize {
+	blobLen, err := encryptionBlobLen(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	overhead := 2 + blobLen + encryptionNonceLen + encryptionMacLen
+	if len(encrypted) < overhead {
 		return nil, errors.New("Insufficient ciphertext length")
 	}
 
@@ -175,7 +351,7 @@ func Decrypt(encrypted []byte) ([]byte, error) {
 	blob := encrypted[:blobLen]
 	rest := encrypted[blobLen:]
 
-	_, _, key, err := encryptionKeyInit(ver, blob)
+	key, err := decryptionKey(setup, ver, blob)
 	if err != nil {
 		return nil, err
 	}
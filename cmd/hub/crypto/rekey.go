@@ -0,0 +1,90 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var (
+	rekeySetup KeySetup
+	rekeyVer   byte
+	rekeyBlob  []byte
+	rekeyKey   []byte
+)
+
+// Rekey decrypts data with current key setup and encrypts it with new key setup.
+// The result is decrypted back to make sure the new key is usable before data is overwritten,
+// unless the new setup is X25519 recipients without identity.
+func Rekey(encrypted []byte, setup KeySetup) ([]byte, error) {
+	data, err := Decrypt(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decrypt with current key: %v", err)
+	}
+	return EncryptWithKeySetup(data, setup)
+}
+
+// EncryptWithKeySetup encrypts data with new key setup and verifies it could be decrypted, see Rekey
+func EncryptWithKeySetup(data []byte, setup KeySetup) ([]byte, error) {
+	var err error
+	if len(rekeyKey) == 0 || rekeySetup != setup {
+		rekeyVer, rekeyBlob, rekeyKey, err = encryptionKeyInit(setup, 0, nil)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to initialize new key: %v", err)
+		}
+		rekeySetup = setup
+	}
+	rekeyed, err := encrypt(data, rekeyVer, rekeyBlob, rekeyKey)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to encrypt with new key: %v", err)
+	}
+	// data encrypted to X25519 recipients cannot be decrypted back without identity
+	if rekeyVer == encryptionV5MarkerByte1 && setup.AgeIdentity == "" {
+		return rekeyed, nil
+	}
+	check, err := decrypt(setup, rekeyed)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decrypt with new key: %v", err)
+	}
+	if !bytes.Equal(data, check) {
+		return nil, errors.New("Data encrypted with new key doesn't match original data")
+	}
+	return rekeyed, nil
+}
+
+// EncryptionMode returns key setup kind the data is encrypted with
+func EncryptionMode(encrypted []byte) string {
+	if !IsEncryptedData(encrypted) {
+		return "none"
+	}
+	switch encrypted[1] {
+	case encryptionV1MarkerByte1:
+		return "password"
+	case encryptionV2MarkerByte1:
+		return "aws-kms"
+	case encryptionV3MarkerByte1:
+		return "azure-keyvault"
+	case encryptionV4MarkerByte1:
+		return "gcp-kms"
+	case encryptionV5MarkerByte1:
+		return "age"
+	}
+	return "unknown"
+}
+
+// Mode returns key setup kind that is used to encrypt with, in order of priority
+func (setup KeySetup) Mode() string {
+	switch {
+	case setup.Password != "":
+		return "password"
+	case setup.AwsKmsKeyArn != "":
+		return "aws-kms"
+	case setup.AzureKeyVaultKeyId != "":
+		return "azure-keyvault"
+	case setup.GcpKmsKeyName != "":
+		return "gcp-kms"
+	case setup.AgeRecipients != "" || setup.AgeIdentity != "":
+		return "age"
+	}
+	return "none"
+}
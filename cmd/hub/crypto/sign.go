@@ -0,0 +1,57 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+const signatureMacLen = sha256.Size
+
+var signatureKeyLabel = []byte("hub-signature")
+
+// Sign returns HMAC-SHA256 of data keyed by current crypto setup key.
+// Key blob - salt or encrypted data key - is embedded into signature so that it could be verified later.
+func Sign(data []byte) (string, error) {
+	ver, blob, key, err := maybeEncryptionKeyInit()
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 0, 2+len(blob)+signatureMacLen)
+	signature = append(signature, encryptionMarkerByte0, ver)
+	signature = append(signature, blob...)
+	signature = append(signature, mac(key, data)...)
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+func Verify(data []byte, signature string) error {
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("Unable to decode signature: %v", err)
+	}
+	if len(raw) < 2+signatureMacLen || raw[0] != encryptionMarkerByte0 {
+		return errors.New("Bad signature format")
+	}
+	ver := raw[1]
+	blob := raw[2 : len(raw)-signatureMacLen]
+	expected := raw[len(raw)-signatureMacLen:]
+	_, _, key, err := encryptionKeyInit(CurrentKeySetup(), ver, blob)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(expected, mac(key, data)) {
+		return errors.New("Signature mismatch")
+	}
+	return nil
+}
+
+func mac(key, data []byte) []byte {
+	// derive signing key to not use encryption key directly
+	derived := hmac.New(sha256.New, key)
+	derived.Write(signatureKeyLabel)
+	m := hmac.New(sha256.New, derived.Sum(nil))
+	m.Write(data)
+	return m.Sum(nil)
+}
@@ -0,0 +1,116 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// encrypted individual values, like secret parameters and outputs in state, are base64 strings with prefix;
+// values that are not strings are marshalled to YAML before encryption
+const (
+	encryptedValuePrefix     = "encrypted:"
+	encryptedYamlValuePrefix = encryptedValuePrefix + "yaml:"
+)
+
+func IsEncryptedValue(value string) bool {
+	return strings.HasPrefix(value, encryptedValuePrefix)
+}
+
+func isEncryptedYamlValue(value string) bool {
+	return strings.HasPrefix(value, encryptedYamlValuePrefix)
+}
+
+func EncryptValue(value string) (string, error) {
+	if value == "" || IsEncryptedValue(value) {
+		return value, nil
+	}
+	return encryptValue(encryptedValuePrefix, []byte(value))
+}
+
+// EncryptYamlValue encrypts value of any type, for example a map, to be restored by DecryptAnyValue
+func EncryptYamlValue(value interface{}) (string, error) {
+	bytes, err := yaml.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("Unable to marshal value to YAML: %v", err)
+	}
+	return encryptValue(encryptedYamlValuePrefix, bytes)
+}
+
+func encryptValue(prefix string, data []byte) (string, error) {
+	encrypted, err := Encrypt(data)
+	if err != nil {
+		return "", err
+	}
+	return prefix + base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+// decodeValue returns encrypted data and prefix of the value
+func decodeValue(value string) ([]byte, string, error) {
+	prefix := encryptedValuePrefix
+	if isEncryptedYamlValue(value) {
+		prefix = encryptedYamlValuePrefix
+	}
+	encrypted, err := base64.StdEncoding.DecodeString(value[len(prefix):])
+	if err != nil {
+		return nil, "", fmt.Errorf("Unable to decode encrypted value: %v", err)
+	}
+	return encrypted, prefix, nil
+}
+
+// DecryptValue returns clear-text value, values encrypted by EncryptYamlValue are returned as YAML
+func DecryptValue(value string) (string, error) {
+	if !IsEncryptedValue(value) {
+		return value, nil
+	}
+	encrypted, _, err := decodeValue(value)
+	if err != nil {
+		return "", err
+	}
+	decrypted, err := Decrypt(encrypted)
+	if err != nil {
+		return "", err
+	}
+	return string(decrypted), nil
+}
+
+// DecryptAnyValue returns clear-text value, values encrypted by EncryptYamlValue are unmarshalled
+func DecryptAnyValue(value string) (interface{}, error) {
+	decrypted, err := DecryptValue(value)
+	if err != nil || !isEncryptedYamlValue(value) {
+		return decrypted, err
+	}
+	var unmarshalled interface{}
+	err = yaml.Unmarshal([]byte(decrypted), &unmarshalled)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to unmarshal decrypted YAML value: %v", err)
+	}
+	return unmarshalled, nil
+}
+
+// RekeyValue re-encrypts encrypted value with new key setup
+func RekeyValue(value string, setup KeySetup) (string, error) {
+	encrypted, prefix, err := decodeValue(value)
+	if err != nil {
+		return "", err
+	}
+	rekeyed, err := Rekey(encrypted, setup)
+	if err != nil {
+		return "", err
+	}
+	return prefix + base64.StdEncoding.EncodeToString(rekeyed), nil
+}
+
+// ValueEncryptionMode returns key setup kind the value is encrypted with
+func ValueEncryptionMode(value string) string {
+	if !IsEncryptedValue(value) {
+		return "none"
+	}
+	encrypted, _, err := decodeValue(value)
+	if err != nil {
+		return "unknown"
+	}
+	return EncryptionMode(encrypted)
+}
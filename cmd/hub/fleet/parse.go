@@ -0,0 +1,151 @@
+package fleet
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+func ParseFleet(filename string) (*Fleet, error) {
+	yamlBytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read fleet manifest: %v", err)
+	}
+	var fleet Fleet
+	err = yaml.UnmarshalStrict(yamlBytes, &fleet)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse `%s`: %v", filename, err)
+	}
+	if fleet.Kind != "fleet" {
+		return nil, fmt.Errorf("Fleet manifest `%s` kind = `%s` but it must be `fleet`", filename, fleet.Kind)
+	}
+	if fleet.Version != 1 {
+		return nil, fmt.Errorf("Fleet manifest `%s` version = `%d` but it must be `1`; update Hub CLI", filename, fleet.Version)
+	}
+	if len(fleet.Stacks) == 0 {
+		return nil, fmt.Errorf("No stacks in fleet manifest `%s`", filename)
+	}
+	fleet.dir = filepath.Dir(util.MustAbs(filename))
+
+	names := make([]string, 0, len(fleet.Stacks))
+	for i := range fleet.Stacks {
+		stack := &fleet.Stacks[i]
+		if stack.Name == "" {
+			return nil, fmt.Errorf("Fleet stack #%d has no `name`", i+1)
+		}
+		if util.Contains(names, stack.Name) {
+			return nil, fmt.Errorf("Fleet stack `%s` is declared more than once", stack.Name)
+		}
+		names = append(names, stack.Name)
+
+		if stack.Manifest == "" {
+			stack.Manifest = filepath.Join(stack.Name, "hub.yaml")
+		}
+		stack.Manifest = fleet.path(stack.Manifest)
+		for j, params := range stack.Parameters {
+			stack.Parameters[j] = fleet.path(params)
+		}
+		if stack.State == "" {
+			stack.State = stack.Manifest + ".state"
+		} else {
+			states := util.SplitPaths(stack.State)
+			for j, st := range states {
+				states[j] = fleet.path(st)
+			}
+			stack.State = strings.Join(states, ",")
+		}
+		if stack.Elaborate == "" {
+			stack.Elaborate = stack.Manifest + ".elaborate"
+		} else {
+			stack.Elaborate = fleet.path(stack.Elaborate)
+		}
+	}
+	for i := range fleet.Stacks {
+		stack := &fleet.Stacks[i]
+		if stack.Platform == "" {
+			if len(stack.Depends) == 1 {
+				stack.Platform = stack.Depends[0]
+			} else if len(stack.Depends) > 1 {
+				util.Warn("Fleet stack `%s` has several dependencies but no `platform:` - no platform stack state is passed to elaborate",
+					stack.Name)
+			}
+		} else if !util.Contains(stack.Depends, stack.Platform) {
+			stack.Depends = append(stack.Depends, stack.Platform)
+		}
+		for _, dependency := range stack.Depends {
+			if !util.Contains(names, dependency) {
+				return nil, fmt.Errorf("Fleet stack `%s` depends on unknown stack `%s`", stack.Name, dependency)
+			}
+		}
+	}
+	if _, err := fleet.waves(); err != nil {
+		return nil, err
+	}
+	return &fleet, nil
+}
+
+func (fleet *Fleet) path(path string) string {
+	if strings.Contains(path, "://") || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(fleet.dir, path)
+}
+
+func (fleet *Fleet) stack(name string) *Stack {
+	for i := range fleet.Stacks {
+		if fleet.Stacks[i].Name == name {
+			return &fleet.Stacks[i]
+		}
+	}
+	return nil
+}
+
+// waves returns stacks grouped by dependency level: stacks of the same wave
+// do not depend on each other and could be deployed concurrently
+func (fleet *Fleet) waves() ([][]string, error) {
+	level := make(map[string]int)
+	visiting := make(map[string]bool)
+	var visit func(name string, path []string) (int, error)
+	visit = func(name string, path []string) (int, error) {
+		if l, exist := level[name]; exist {
+			return l, nil
+		}
+		if visiting[name] {
+			return 0, fmt.Errorf("Fleet stacks dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		}
+		visiting[name] = true
+		stackLevel := 0
+		for _, dependency := range fleet.stack(name).Depends {
+			dependencyLevel, err := visit(dependency, append(append([]string{}, path...), name))
+			if err != nil {
+				return 0, err
+			}
+			if dependencyLevel >= stackLevel {
+				stackLevel = dependencyLevel + 1
+			}
+		}
+		visiting[name] = false
+		level[name] = stackLevel
+		return stackLevel, nil
+	}
+
+	waves := make([][]string, 0)
+	for _, stack := range fleet.Stacks {
+		stackLevel, err := visit(stack.Name, nil)
+		if err != nil {
+			return nil, err
+		}
+		for len(waves) <= stackLevel {
+			waves = append(waves, make([]string, 0))
+		}
+	}
+	for _, stack := range fleet.Stacks {
+		waves[level[stack.Name]] = append(waves[level[stack.Name]], stack.Name)
+	}
+	return waves, nil
+}
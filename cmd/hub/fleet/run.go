@@ -0,0 +1,344 @@
+package fleet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/manifest"
+	"github.com/agilestacks/hub/cmd/hub/state"
+	"github.com/agilestacks/hub/cmd/hub/storage"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+const (
+	VerbDeploy   = "deploy"
+	VerbUndeploy = "undeploy"
+)
+
+// Execute deploys or undeploys fleet stacks in order of dependencies, each stack is elaborated
+// and deployed by Hub CLI sub-process; stacks that do not depend on each other are processed
+// concurrently, up to parallel at a time
+func Execute(filename, verb string, only []string, parallel int) {
+	fleet, err := ParseFleet(filename)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	for _, name := range only {
+		if fleet.stack(name) == nil {
+			log.Fatalf("Fleet `%s` has no stack `%s`", fleet.Meta.Name, name)
+		}
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+	bin, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Unable to determine path to Hub CLI executable: %v", err)
+	}
+
+	waves, _ := fleet.waves()
+	if verb == VerbUndeploy {
+		for i, j := 0, len(waves)-1; i < j; i, j = i+1, j-1 {
+			waves[i], waves[j] = waves[j], waves[i]
+		}
+	}
+	// stack is not processed if a stack it is waiting for failed:
+	// on deploy - a dependency, on undeploy - a dependent stack
+	waitsFor := func(name string) []string {
+		if verb == VerbDeploy {
+			return fleet.stack(name).Depends
+		}
+		dependents := make([]string, 0)
+		for _, stack := range fleet.Stacks {
+			if util.Contains(stack.Depends, name) {
+				dependents = append(dependents, stack.Name)
+			}
+		}
+		return dependents
+	}
+
+	if config.Verbose {
+		log.Printf("%s fleet `%s` stacks: %s", strings.Title(verb), fleet.Meta.Name, formatWaves(waves, only))
+	}
+
+	start := time.Now()
+	output := &lockedWriter{out: os.Stderr}
+	failed := make([]string, 0)
+	skipped := make([]string, 0)
+	completed := make([]string, 0)
+WAVES:
+	for _, wave := range waves {
+		stacks := make([]*Stack, 0, len(wave))
+		for _, name := range wave {
+			if len(only) > 0 && !util.Contains(only, name) {
+				continue
+			}
+			if blocked := intersect(waitsFor(name), append(failed, skipped...)); len(blocked) > 0 {
+				util.Warn("Skip fleet stack `%s` %s due to failed %s", name, verb, strings.Join(blocked, ", "))
+				skipped = append(skipped, name)
+				continue
+			}
+			stacks = append(stacks, fleet.stack(name))
+		}
+
+		errs := make([]error, len(stacks))
+		semaphore := make(chan struct{}, parallel)
+		var wg sync.WaitGroup
+		for i, stack := range stacks {
+			wg.Add(1)
+			semaphore <- struct{}{}
+			go func(i int, stack *Stack) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				errs[i] = fleet.run(bin, verb, stack, output)
+			}(i, stack)
+		}
+		wg.Wait()
+
+		for i, stack := range stacks {
+			if errs[i] != nil {
+				util.Warn("Fleet stack `%s` failed to %s: %v", stack.Name, verb, errs[i])
+				failed = append(failed, stack.Name)
+			} else {
+				completed = append(completed, stack.Name)
+			}
+		}
+		if len(failed) > 0 && !config.Force {
+			break WAVES
+		}
+	}
+
+	if config.Verbose {
+		log.Printf("Fleet `%s` %s took %v; completed: %s", fleet.Meta.Name, verb,
+			time.Since(start).Round(time.Second), formatList(completed))
+		if len(skipped) > 0 {
+			log.Printf("Skipped: %s", strings.Join(skipped, ", "))
+		}
+	}
+	if len(failed) > 0 {
+		util.MaybeFatalf("Fleet stack(s) failed to %s: %s", verb, strings.Join(failed, ", "))
+	}
+}
+
+func (fleet *Fleet) run(bin, verb string, stack *Stack, output io.Writer) error {
+	out := &prefixWriter{prefix: fmt.Sprintf("[%s] ", stack.Name), out: output}
+	defer out.Flush()
+
+	if verb == VerbDeploy || !fileExist(stack.Elaborate) {
+		if err := fleet.elaborate(bin, stack, out); err != nil {
+			return err
+		}
+	}
+	return hub(bin, out, verb, stack.Elaborate, "-s", stack.State)
+}
+
+func (fleet *Fleet) elaborate(bin string, stack *Stack, out io.Writer) error {
+	args := []string{"elaborate", stack.Manifest}
+	wired, err := fleet.wireOutputs(stack)
+	if err != nil {
+		return err
+	}
+	if wired != "" {
+		defer os.Remove(wired)
+		// explicitly specified parameters files take precedence over outputs of dependencies
+		args = append(args, wired)
+	}
+	args = append(args, stack.Parameters...)
+	args = append(args, "-o", stack.Elaborate)
+	if stack.Platform != "" {
+		// platform stack state connects provides and Kubernetes parameters
+		args = append(args, "-s", fleet.stack(stack.Platform).State)
+	}
+	if stack.Environment != "" {
+		args = append(args, "-e", stack.Environment)
+	}
+	return hub(bin, out, args...)
+}
+
+// wireOutputs writes a temporary parameters file with dependencies stack outputs
+// that match by name stack parameters which has no value
+func (fleet *Fleet) wireOutputs(stack *Stack) (string, error) {
+	if len(stack.Depends) == 0 {
+		return "", nil
+	}
+	stackManifest, _, _, err := manifest.ParseManifest([]string{stack.Manifest})
+	if err != nil {
+		return "", err
+	}
+
+	outputs := make(map[string]interface{})
+	from := make(map[string]string)
+	for _, dependency := range stack.Depends {
+		st, err := fleet.stack(dependency).parseState()
+		if err != nil {
+			return "", fmt.Errorf("Unable to load `%s` stack state: %v", dependency, err)
+		}
+		for _, output := range st.StackOutputs {
+			name := output.Name
+			if i := strings.Index(name, ":"); i > 0 && i < len(name)-1 {
+				name = name[i+1:]
+			}
+			if prev, exist := from[name]; exist && prev != dependency {
+				util.Warn("Fleet stack `%s` output `%s` overrides output from `%s`", dependency, name, prev)
+			}
+			outputs[name] = state.DecryptValue(output.Value)
+			from[name] = dependency
+		}
+	}
+
+	wired := make([]manifest.Parameter, 0)
+	for _, parameter := range manifest.FlattenParameters(stackManifest.Parameters, stack.Manifest) {
+		if strings.HasPrefix(parameter.Name, "hub.") || !util.Empty(parameter.Value) ||
+			parameter.FromEnv != "" || parameter.FromFile != "" {
+			continue
+		}
+		if value, exist := outputs[parameter.Name]; exist && !util.Empty(value) {
+			wired = append(wired, manifest.Parameter{Name: parameter.Name, Component: parameter.Component, Value: value})
+			if config.Debug {
+				log.Printf("Fleet stack `%s` parameter `%s` <= `%s` stack output", stack.Name, parameter.QName(), from[parameter.Name])
+			}
+		}
+	}
+	if len(wired) == 0 {
+		return "", nil
+	}
+
+	yamlBytes, err := yaml.Marshal(&manifest.ParametersManifest{Parameters: wired})
+	if err != nil {
+		return "", fmt.Errorf("Unable to marshal parameters into YAML: %v", err)
+	}
+	file, err := ioutil.TempFile("", fmt.Sprintf("hub-fleet-%s-params-*.yaml", util.PlainName(stack.Name)))
+	if err != nil {
+		return "", fmt.Errorf("Unable to create wired parameters file: %v", err)
+	}
+	_, err = file.Write(yamlBytes)
+	file.Close()
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("Unable to write wired parameters file: %v", err)
+	}
+	return file.Name(), nil
+}
+
+func (stack *Stack) parseState() (*state.StateManifest, error) {
+	stateFiles, errs := storage.Check(util.SplitPaths(stack.State), "state")
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", util.Errors2(errs...))
+	}
+	return state.ParseState(stateFiles)
+}
+
+func hub(bin string, out io.Writer, args ...string) error {
+	if config.Trace {
+		args = append(args, "--trace")
+	} else if config.Debug {
+		args = append(args, "--debug")
+	} else if !config.Verbose {
+		args = append(args, "--verbose=false")
+	}
+	if config.Force {
+		args = append(args, "--force")
+	}
+	if config.Debug {
+		log.Printf("Executing %s %s", bin, strings.Join(args, " "))
+	}
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("`hub %s` failed: %v", args[0], err)
+	}
+	return nil
+}
+
+func fileExist(path string) bool {
+	if strings.Contains(path, "://") {
+		return true
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func formatWaves(waves [][]string, only []string) string {
+	parts := make([]string, 0, len(waves))
+	for _, wave := range waves {
+		selected := wave
+		if len(only) > 0 {
+			selected = intersect(wave, only)
+		}
+		if len(selected) > 0 {
+			parts = append(parts, strings.Join(selected, " | "))
+		}
+	}
+	return strings.Join(parts, " -> ")
+}
+
+func intersect(list, list2 []string) []string {
+	common := make([]string, 0)
+	for _, value := range list {
+		if util.Contains(list2, value) {
+			common = append(common, value)
+		}
+	}
+	return common
+}
+
+func formatList(list []string) string {
+	if len(list) == 0 {
+		return "(none)"
+	}
+	return strings.Join(list, ", ")
+}
+
+type lockedWriter struct {
+	out  io.Writer
+	lock sync.Mutex
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.out.Write(p)
+}
+
+// prefixWriter prefixes each line of sub-process output with stack name
+// so that output of concurrent stacks could be told apart
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := w.out.Write(append([]byte(w.prefix), w.buf[:i+1]...)); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.out.Write(append([]byte(w.prefix), append(w.buf, '\n')...))
+		w.buf = nil
+	}
+}
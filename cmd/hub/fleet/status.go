@@ -0,0 +1,45 @@
+package fleet
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+// Status prints fleet stacks status from their states, in order of dependencies
+func Status(filename string, only []string) {
+	fleet, err := ParseFleet(filename)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	waves, _ := fleet.waves()
+
+	for _, wave := range waves {
+		for _, name := range wave {
+			if len(only) > 0 && !util.Contains(only, name) {
+				continue
+			}
+			stack := fleet.stack(name)
+			status, updated, message := "(no state)", "", ""
+			st, err := stack.parseState()
+			if err == nil {
+				status = st.Status
+				if !st.Timestamp.IsZero() {
+					updated = st.Timestamp.Local().Format(time.RFC3339)
+				}
+				message = st.Message
+			} else if !util.NoSuchFile(err) && err != os.ErrNotExist {
+				status = "(error)"
+				message = err.Error()
+			}
+			if i := strings.Index(message, "\n"); i >= 0 {
+				message = message[:i] + "..."
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\t%s\n", name, status, updated, strings.Join(stack.Depends, ","), message)
+		}
+	}
+}
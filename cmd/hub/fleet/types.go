@@ -0,0 +1,29 @@
+package fleet
+
+type Metadata struct {
+	Name        string
+	Description string `yaml:",omitempty"`
+}
+
+type Stack struct {
+	Name        string
+	Manifest    string   `yaml:",omitempty"` // default <name>/hub.yaml
+	Parameters  []string `yaml:",omitempty"`
+	State       string   `yaml:",omitempty"` // default <manifest>.state, comma-separated list of locations
+	Elaborate   string   `yaml:",omitempty"` // default <manifest>.elaborate
+	Environment string   `yaml:",omitempty"` // elaborate -e overrides: NAME=demo,...
+	Depends     []string `yaml:",omitempty"`
+	Platform    string   `yaml:",omitempty"` // stack which state is passed to elaborate -s, default the only dependency
+}
+
+// Fleet is `hub-fleet.yaml` - a set of stacks deployed in order of dependencies,
+// outputs of a stack are wired into parameters of dependent stacks
+type Fleet struct {
+	Version int
+	Kind    string
+	Meta    Metadata `yaml:",omitempty"`
+	Stacks  []Stack
+
+	// directory of hub-fleet.yaml, stacks paths are relative to it
+	dir string
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 
 	"github.com/agilestacks/hub/cmd/hub/config"
@@ -116,3 +117,46 @@ func WriteGCS(path string, body []byte) error {
 	}
 	return nil
 }
+
+func ListGCS(prefix string, object func(path string, size int64, modTime time.Time)) error {
+	location, err := url.Parse(prefix)
+	if err != nil {
+		return err
+	}
+	bucket, err := gcsBucket(location.Host)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), gcsTimeout)
+	defer cancel()
+	it := bucket.Objects(ctx, &storage.Query{Prefix: noRoot(location.Path)})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to list GCS objects `%s`: %v", prefix, err)
+		}
+		object(fmt.Sprintf("gs://%s/%s", location.Host, attrs.Name), attrs.Size, attrs.Updated)
+	}
+	return nil
+}
+
+func DeleteGCS(path string) error {
+	location, err := url.Parse(path)
+	if err != nil {
+		return err
+	}
+	bucket, err := gcsBucket(location.Host)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), gcsTimeout)
+	defer cancel()
+	err = bucket.Object(noRoot(location.Path)).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to delete GCS object `%s`: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package gcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+)
+
+const aes256KeySize = 32
+
+var kmsTimeout = time.Duration(30 * time.Second)
+
+// KmsKey generates a new data key encrypted with Cloud KMS key if no blob is supplied,
+// otherwise the blob is decrypted to obtain the data key.
+// Key name is projects/*/locations/*/keyRings/*/cryptoKeys/*
+func KmsKey(name string, blob []byte) ([]byte, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), kmsTimeout)
+	defer cancel()
+	opts := []option.ClientOption{option.WithScopes(cloudkms.CloudkmsScope)}
+	if config.GcpCredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(config.GcpCredentialsFile))
+	}
+	service, err := cloudkms.NewService(ctx, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := service.Projects.Locations.KeyRings.CryptoKeys
+
+	// new data key for encryption
+	if len(blob) == 0 {
+		key := make([]byte, aes256KeySize)
+		_, err := rand.Read(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err := keys.Encrypt(name,
+			&cloudkms.EncryptRequest{Plaintext: base64.StdEncoding.EncodeToString(key)}).Context(ctx).Do()
+		if err != nil {
+			return nil, nil, err
+		}
+		encrypted, err := base64.StdEncoding.DecodeString(resp.Ciphertext)
+		if err != nil {
+			return nil, nil, err
+		}
+		return key, encrypted, nil
+	}
+	// decrypt data key for decryption
+	resp, err := keys.Decrypt(name,
+		&cloudkms.DecryptRequest{Ciphertext: base64.StdEncoding.EncodeToString(blob)}).Context(ctx).Do()
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(resp.Plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, blob, nil
+}
@@ -1,12 +1,14 @@
 package kube
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 
@@ -17,9 +19,9 @@ import (
 )
 
 func Kubeconfig(filenames []string, providers []string, context string, keepPems bool) {
-	state := state.MustParseStateFiles(filenames)
+	st := state.MustParseStateFiles(filenames)
 
-	providerState, provider := findState(state, providers)
+	providerState, provider := findState(st, providers)
 	if providerState == nil && config.Verbose {
 		// TODO (1) find a provider with kubernetes.api.* outputs
 		// (2) write `provides` into state and search by that
@@ -28,12 +30,13 @@ func Kubeconfig(filenames []string, providers []string, context string, keepPems
 
 	outputs := make(parameters.CapturedOutputs)
 	if providerState != nil {
-		for _, o := range providerState.CapturedOutputs {
+		for _, o := range state.DecryptOutputs(providerState.CapturedOutputs) {
 			outputs[o.QName()] = o
 		}
 	}
 	params := make(parameters.LockedParameters)
-	for _, p := range state.StackParameters {
+	for _, p := range st.StackParameters {
+		p.Value = state.DecryptValue(p.Value)
 		params[p.QName()] = p
 	}
 	SetupKubernetes(params, provider, outputs, context, config.Force, keepPems)
@@ -186,3 +189,54 @@ func addHeptioUser(configFilename, user, cluster string) {
 		log.Fatalf("%v", err)
 	}
 }
+
+// KubeconfigContent renders self-contained Kubeconfig with embedded certificates from
+// kubernetes.api.* values found via lookup; context defaults to dns.domain.
+func KubeconfigContent(lookup func(string) (string, bool), context string) (string, error) {
+	domain, exist := lookup(kubernetesDomainOutput)
+	if !exist || domain == "" {
+		return "", fmt.Errorf("`%s` is not set", kubernetesDomainOutput)
+	}
+	if context == "" {
+		context = domain
+	}
+	endpoint := domain
+	if value, exist := lookup(kubernetesApiEndpointOutput); exist && value != "" {
+		endpoint = value
+	}
+	if !strings.HasPrefix(endpoint, "https://") && !strings.HasPrefix(endpoint, "http://") {
+		endpoint = "https://" + endpoint
+	}
+	cluster := map[string]string{"server": endpoint}
+	if ca, exist := lookup(kubernetesApiCaCertOutput); exist && ca != "" {
+		cluster["certificate-authority-data"] = base64.StdEncoding.EncodeToString([]byte(ca))
+	}
+	user := make(map[string]string)
+	if token, exist := lookup(kubernetesApiTokenOutput); exist && token != "" {
+		user["token"] = token
+	} else {
+		cert, certExist := lookup(kubernetesApiClientCertOutput)
+		key, keyExist := lookup(kubernetesApiClientKeyOutput)
+		if !certExist || !keyExist || cert == "" || key == "" {
+			return "", fmt.Errorf("Neither `%s`, nor `%s` and `%s` are set",
+				kubernetesApiTokenOutput, kubernetesApiClientCertOutput, kubernetesApiClientKeyOutput)
+		}
+		user["client-certificate-data"] = base64.StdEncoding.EncodeToString([]byte(cert))
+		user["client-key-data"] = base64.StdEncoding.EncodeToString([]byte(key))
+	}
+	userName := "admin@" + domain
+	kubeconfig := map[string]interface{}{
+		"apiVersion":      "v1",
+		"kind":            "Config",
+		"current-context": context,
+		"clusters":        []interface{}{map[string]interface{}{"name": domain, "cluster": cluster}},
+		"users":           []interface{}{map[string]interface{}{"name": userName, "user": user}},
+		"contexts": []interface{}{map[string]interface{}{"name": context,
+			"context": map[string]string{"cluster": domain, "user": userName}}},
+	}
+	content, err := yaml.Marshal(kubeconfig)
+	if err != nil {
+		return "", fmt.Errorf("Unable to marshall Kubeconfig: %v", err)
+	}
+	return string(content), nil
+}
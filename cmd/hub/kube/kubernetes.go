@@ -339,7 +339,7 @@ func mustOutput(params parameters.LockedParameters,
 	} else {
 		log.Printf("No %v stack parameter(s) are found", candidates)
 	}
-	os.Exit(1)
+	util.Exit(1)
 	return ""
 }
 
@@ -17,7 +17,7 @@ import (
 
 func AskParameter(parameter manifest.Parameter,
 	environment map[string]string, hubEnvironment, hubStackInstance, hubApplication string,
-	isDeploy bool) (interface{}, error) {
+	isDeploy bool) (interface{}, *manifest.ParameterSource, error) {
 
 	qName := parameter.QName()
 
@@ -25,11 +25,11 @@ func AskParameter(parameter manifest.Parameter,
 		key := parameter.FromEnv
 		if environment != nil {
 			if v, exist := environment[key]; exist {
-				return v, nil
+				return v, &manifest.ParameterSource{Mechanism: manifest.ParameterSourceOverride, Ref: key}, nil
 			}
 		}
 		if v, exist := os.LookupEnv(key); exist {
-			return v, nil
+			return v, &manifest.ParameterSource{Mechanism: manifest.ParameterSourceFromEnv, Ref: key}, nil
 		}
 	}
 	if parameter.FromFile != "" {
@@ -54,31 +54,31 @@ func AskParameter(parameter manifest.Parameter,
 		if filename != "" {
 			bytes, err := ioutil.ReadFile(filename)
 			if err != nil {
-				return "(error)", fmt.Errorf("Error reading `%s`: %v", filename, err)
+				return "(error)", nil, fmt.Errorf("Error reading `%s`: %v", filename, err)
 			}
-			return string(bytes), nil
+			return string(bytes), &manifest.ParameterSource{Mechanism: manifest.ParameterSourceFromFile, File: filename}, nil
 		}
 	}
 
 	if hubEnvironment != "" || hubStackInstance != "" || hubApplication != "" {
 		found, v, errs := api.GetParameterOrMaybeCreateSecret(hubEnvironment, hubStackInstance, hubApplication,
 			parameter.Name, parameter.Component, isDeploy && parameter.Empty != "allow")
+		where := make([]string, 0, 3)
+		if hubEnvironment != "" {
+			where = append(where, fmt.Sprintf("environment `%s`", hubEnvironment))
+		}
+		if hubStackInstance != "" {
+			where = append(where, fmt.Sprintf("stack instance `%s`", hubStackInstance))
+		}
+		if hubApplication != "" {
+			where = append(where, fmt.Sprintf("application `%s`", hubApplication))
+		}
 		if len(errs) > 0 {
-			where := make([]string, 0, 3)
-			if hubEnvironment != "" {
-				where = append(where, fmt.Sprintf("environment `%s`", hubEnvironment))
-			}
-			if hubStackInstance != "" {
-				where = append(where, fmt.Sprintf("stack instance `%s`", hubStackInstance))
-			}
-			if hubApplication != "" {
-				where = append(where, fmt.Sprintf("application `%s`", hubApplication))
-			}
 			util.Warn("Error query parameter `%s` in %s:\n\t%s",
 				qName, strings.Join(where, ", "), util.Errors("\n\t", errs...))
 		}
 		if found && v != "" {
-			return v, nil
+			return v, &manifest.ParameterSource{Mechanism: manifest.ParameterSourceSuperHub, Ref: strings.Join(where, ", ")}, nil
 		}
 	}
 
@@ -99,14 +99,14 @@ func AskParameter(parameter manifest.Parameter,
 		read, err := fmt.Scanln(&value)
 		if read > 0 {
 			if err != nil {
-				return "(error)", fmt.Errorf("Error reading input: %v (read %d items)", err, read)
+				return "(error)", nil, fmt.Errorf("Error reading input: %v (read %d items)", err, read)
 			}
-			return value, nil
+			return value, &manifest.ParameterSource{Mechanism: manifest.ParameterSourcePrompt}, nil
 		}
 	}
 
 	if !util.Empty(parameter.Default) {
-		return parameter.Default, nil
+		return parameter.Default, nil, nil
 	}
 
 	if parameter.Env != "" && parameter.FromEnv == "" {
@@ -116,8 +116,8 @@ func AskParameter(parameter manifest.Parameter,
 		if config.Debug {
 			log.Printf("Empty parameter `%s` value allowed", qName)
 		}
-		return "", nil
+		return "", nil, nil
 	}
 
-	return "(unknown)", fmt.Errorf("Parameter `%s` has no value nor default assigned", qName)
+	return "(unknown)", nil, fmt.Errorf("Parameter `%s` has no value nor default assigned", qName)
 }
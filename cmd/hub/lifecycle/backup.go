@@ -1,26 +1,33 @@
 package lifecycle
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/yaml.v2"
 
 	"github.com/agilestacks/hub/cmd/hub/config"
 	"github.com/agilestacks/hub/cmd/hub/manifest"
+	"github.com/agilestacks/hub/cmd/hub/metrics"
+	"github.com/agilestacks/hub/cmd/hub/notify"
 	"github.com/agilestacks/hub/cmd/hub/parameters"
+	"github.com/agilestacks/hub/cmd/hub/report"
 	"github.com/agilestacks/hub/cmd/hub/state"
 	"github.com/agilestacks/hub/cmd/hub/storage"
+	"github.com/agilestacks/hub/cmd/hub/tracing"
 	"github.com/agilestacks/hub/cmd/hub/util"
 )
 
-func BackupCreate(request *Request, bundles []string, jsonOutput, allowPartial bool, pipe io.WriteCloser) {
+func BackupCreate(request *Request, bundles []string, jsonOutput, allowPartial, sign bool, pipe io.WriteCloser) {
 	if len(request.StateFilenames) == 0 {
 		log.Fatal("Backup without state file(s) is not implemented; try --state")
 	}
@@ -39,6 +46,15 @@ func BackupCreate(request *Request, bundles []string, jsonOutput, allowPartial b
 		log.Fatalf("Unable to create backup: %v", err)
 	}
 
+	ctx, operationSpan := tracing.StartOperation(context.Background(),
+		fmt.Sprintf("%s %s", request.Verb, stackManifest.Meta.Name))
+	operationSpan.SetAttribute("hub.verb", request.Verb)
+	operationSpan.SetAttribute("hub.stack", stackManifest.Meta.Name)
+	defer util.Done()
+	metrics.StartOperation()
+	operationStart := time.Now()
+	operationTags := map[string]string{"stack": stackManifest.Meta.Name, "verb": request.Verb}
+
 	if pipe != nil {
 		metricTags := fmt.Sprintf("stack:%s", stackManifest.Meta.Name)
 		pipe.Write([]byte(metricTags))
@@ -60,6 +76,7 @@ func BackupCreate(request *Request, bundles []string, jsonOutput, allowPartial b
 	checkComponentsManifests(components, componentsManifests)
 	order := stackManifest.Lifecycle.Order
 	if len(request.Components) > 0 {
+		request.Components = manifest.ExpandComponentsNames(components, request.Components)
 		manifest.CheckComponentsExist(components, request.Components...)
 		components = make([]manifest.ComponentRef, 0, len(request.Components))
 		for _, componentName := range request.Components {
@@ -94,7 +111,7 @@ func BackupCreate(request *Request, bundles []string, jsonOutput, allowPartial b
 				log.Printf("Component `%s` does not implement `%s` verb", componentName, verb)
 			}
 		}
-		os.Exit(1)
+		util.Exit(1)
 	}
 
 	optionalRequires := parseRequiresTunning(stackManifest.Lifecycle.Requires)
@@ -136,88 +153,180 @@ func BackupCreate(request *Request, bundles []string, jsonOutput, allowPartial b
 	}
 	failedComponents := make([]string, 0)
 
-	for componentIndex, componentName := range implementsBackup {
-		if config.Verbose {
-			log.Printf("%s ***%s*** (%d/%d)", verb, componentName, componentIndex+1, len(implementsBackup))
+	var previousBundle *state.BackupManifest
+	if len(request.PreviousBackup) > 0 {
+		previousFiles, errs := storage.Check(request.PreviousBackup, "backup bundle")
+		if len(errs) > 0 {
+			util.MaybeFatalf("Unable to check previous backup bundle files: %v", util.Errors2(errs...))
 		}
+		previousBundle, err = state.ParseBackupBundle(previousFiles)
+		if err != nil {
+			log.Fatalf("Unable to load previous backup bundle %v: %v", request.PreviousBackup, err)
+		}
+		bundle.Previous = previousBundle.Source
+	}
 
-		component := manifest.ComponentRefByName(components, componentName)
-		componentManifest := manifest.ComponentManifestByRef(componentsManifests, component)
+	parallel := request.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	// pagination redirects global log output and is not safe for concurrent sub-processes
+	paginate := parallel == 1
+
+	type backupJob struct {
+		componentName       string
+		component           *manifest.ComponentRef
+		componentManifest   *manifest.Manifest
+		componentParameters parameters.LockedParameters
+		outputs             parameters.CapturedOutputs
+		dir                 string
+		osEnv               []string
+	}
 
-		// TODO Should we reload new parameters from elaborate to allow for component's source mismatch?
-		// Or it will encourage bad practice?
-		stackParameters := make(parameters.LockedParameters)
-		allOutputs := make(parameters.CapturedOutputs)
-		provides := util.CopyMap2(stackProvides)
-		if parsedState != nil {
-			state.MergeParsedState(parsedState,
-				componentName, component.Depends, stackManifest.Lifecycle.Order, false,
-				stackParameters, allOutputs, provides)
-		}
+	notifier := notify.New(request.Verb, stackManifest.Meta.Name, request.StackInstance, "",
+		stackManifest.Notifications)
+	notifier.Start(implementsBackup)
+
+	var reportComponents []report.Component
+	if request.Report != "" {
+		writeReportAtDone(request.Report, func() *report.Report {
+			return &report.Report{
+				Verb:       verb,
+				Stack:      stackManifest.Meta.Name,
+				Status:     bundle.Status,
+				Timestamp:  operationStart,
+				Duration:   time.Since(operationStart),
+				Components: reportComponents,
+			}
+		})
+	}
 
-		expandedComponentParameters, errs := parameters.ExpandParameters(componentName, componentManifest.Meta.Kind, component.Depends,
-			stackParameters, allOutputs,
-			manifest.FlattenParameters(componentManifest.Parameters, componentManifest.Meta.Name))
-		if len(errs) > 0 {
-			util.MaybeFatalf("Component `%s` parameters expansion failed:\n\t%s",
-				componentName, util.Errors("\n\t", errs...))
-		}
-		componentParameters := parameters.MergeParameters(make(parameters.LockedParameters), expandedComponentParameters)
+	componentIndex := 0
+WAVES:
+	for _, wave := range backupWaves(implementsBackup, components, parallel) {
+		jobs := make([]backupJob, 0, len(wave))
+		for _, componentName := range wave {
+			componentIndex++
+			if config.Verbose {
+				log.Printf("%s ***%s*** (%d/%d)", verb, componentName, componentIndex, len(implementsBackup))
+			}
 
-		if config.Debug {
-			log.Print("Component parameters:")
-			parameters.PrintLockedParameters(componentParameters)
-		}
+			component := manifest.ComponentRefByName(components, componentName)
+			componentManifest := manifest.ComponentManifestByRef(componentsManifests, component)
+
+			// TODO Should we reload new parameters from elaborate to allow for component's source mismatch?
+			// Or it will encourage bad practice?
+			stackParameters := make(parameters.LockedParameters)
+			allOutputs := make(parameters.CapturedOutputs)
+			provides := util.CopyMap2(stackProvides)
+			if parsedState != nil {
+				state.MergeParsedState(parsedState,
+					componentName, component.Depends, stackManifest.Lifecycle.Order, false,
+					stackParameters, allOutputs, provides)
+			}
 
-		prepareComponentRequires(provides, componentManifest, stackParameters, allOutputs, optionalRequires, request.EnabledClouds)
+			expandedComponentParameters, errs := parameters.ExpandParameters(componentName, componentManifest.Meta.Kind, component.Depends,
+				stackParameters, allOutputs,
+				manifest.FlattenParameters(componentManifest.Parameters, componentManifest.Meta.Name))
+			if len(errs) > 0 {
+				util.MaybeFatalf("Component `%s` parameters expansion failed:\n\t%s",
+					componentName, util.Errors("\n\t", errs...))
+			}
+			componentParameters := parameters.MergeParameters(make(parameters.LockedParameters), expandedComponentParameters)
 
-		dir := manifest.ComponentSourceDirFromRef(component, stackBaseDir, componentsBaseDir)
-		stdout, _, err := delegate(verb, component, componentManifest, componentParameters, dir, osEnv, "")
+			if config.Debug {
+				log.Print("Component parameters:")
+				parameters.PrintLockedParameters(componentParameters)
+			}
 
-		var rawOutputs parameters.RawOutputs
-		if len(stdout) > 0 {
-			rawOutputs = parseTextOutput(stdout)
-		}
-		status := "error"
-		if err != nil || len(rawOutputs) == 0 {
-			if err == nil {
-				err = errors.New("no outputs emited by the component")
+			prepareComponentRequires(provides, componentManifest, stackParameters, allOutputs, optionalRequires, request.EnabledClouds)
+
+			componentOsEnv := osEnv
+			if previousBundle != nil {
+				previousEnv := previousBackupEnv(previousBundle, componentName)
+				if len(previousEnv) > 0 {
+					componentOsEnv = mergeOsEnviron(osEnv, previousEnv)
+				} else if config.Verbose {
+					log.Printf("No successful `%s` backup in previous bundle `%s`; full %s", componentName, previousBundle.Source, verb)
+				}
 			}
-			log.Printf("Component `%s` failed to %s: %v", componentName, verb, err)
-			failedComponents = append(failedComponents, componentName)
-			if !allowPartial {
-				break
+
+			jobs = append(jobs, backupJob{
+				componentName:       componentName,
+				component:           component,
+				componentManifest:   componentManifest,
+				componentParameters: componentParameters,
+				outputs:             allOutputs,
+				dir:                 manifest.ComponentSourceDirFromRef(component, stackBaseDir, componentsBaseDir),
+				osEnv:               componentOsEnv,
+			})
+		}
+
+		results := make([]state.ComponentBackup, len(jobs))
+		resultErrs := make([]error, len(jobs))
+		resultDurations := make([]time.Duration, len(jobs))
+		semaphore := make(chan struct{}, parallel)
+		// components sharing source dir, ie. `forEach` instances, render templates into the same
+		// files, so they are serialized unless each gets its own workspace
+		dirLocks := make(map[string]*sync.Mutex)
+		for _, job := range jobs {
+			if _, exist := dirLocks[job.dir]; !exist {
+				dirLocks[job.dir] = &sync.Mutex{}
 			}
-		} else {
-			status = "success"
-			log.Printf("Component `%s` completed %s", componentName, verb)
-		}
-		kind, exist := rawOutputs["kind"]
-		if !exist || kind == "" {
-			kind = componentName
-		}
-		timestamp := time.Now()
-		timestampStr, exist := rawOutputs["timestamp"]
-		if exist && timestampStr != "" {
-			timestamp2, err := time.Parse(time.RFC3339, timestampStr)
-			if err != nil {
-				util.Warn("Unable to parse timestamp `%s` emited by component `%s`: %v; using current time",
-					timestampStr, componentName, err)
+		}
+		var wg sync.WaitGroup
+		for i, job := range jobs {
+			wg.Add(1)
+			semaphore <- struct{}{}
+			go func(i int, job backupJob) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				defer util.RecoverAbort()
+				if !request.Workspace {
+					dirLock := dirLocks[job.dir]
+					dirLock.Lock()
+					defer dirLock.Unlock()
+				}
+				componentStart := time.Now()
+				componentCtx, componentSpan := tracing.Start(ctx, job.componentName)
+				componentSpan.SetAttribute("hub.component", job.componentName)
+				componentSpan.SetAttribute("hub.verb", verb)
+				results[i], resultErrs[i] = backupComponent(componentCtx, request, verb, job.component, job.componentManifest,
+					job.componentParameters, job.outputs, job.dir, stackBaseDir, job.osEnv, paginate)
+				componentSpan.EndWithError(resultErrs[i])
+				resultDurations[i] = time.Since(componentStart)
+				componentTags := metricTags(operationTags, "component", job.componentName)
+				status := "success"
+				if resultErrs[i] != nil {
+					status = "error"
+					metrics.Count("component.failures", 1, componentTags)
+				}
+				metrics.Timing("component.duration", resultDurations[i], metricTags(componentTags, "status", status))
+			}(i, job)
+		}
+		wg.Wait()
+		util.CheckAbort()
+
+		failed := false
+		for i, job := range jobs {
+			reportComponent := report.Component{Name: job.componentName, Status: results[i].Status,
+				Result: report.ResultSuccess, Duration: resultDurations[i]}
+			if err := resultErrs[i]; err != nil {
+				msg := fmt.Sprintf("Component `%s` failed to %s: %v", job.componentName, verb, err)
+				log.Print(msg)
+				notifier.ComponentFailed(job.componentName, msg)
+				failedComponents = append(failedComponents, job.componentName)
+				failed = true
+				reportComponent.Result = report.ResultFailure
+				reportComponent.Message = err.Error()
 			} else {
-				timestamp = timestamp2
+				log.Printf("Component `%s` completed %s", job.componentName, verb)
 			}
+			reportComponents = append(reportComponents, reportComponent)
+			bundle.Components[job.componentName] = results[i]
 		}
-		delete(rawOutputs, "kind")
-		delete(rawOutputs, "timestamp")
-		outputs := make([]parameters.CapturedOutput, 0, len(rawOutputs))
-		for name, value := range rawOutputs {
-			outputs = append(outputs, parameters.CapturedOutput{Name: name, Value: value})
-		}
-		bundle.Components[componentName] = state.ComponentBackup{
-			Timestamp: timestamp,
-			Status:    status,
-			Kind:      kind,
-			Outputs:   outputs,
+		if failed && !allowPartial {
+			break WAVES
 		}
 	}
 
@@ -232,6 +341,10 @@ func BackupCreate(request *Request, bundles []string, jsonOutput, allowPartial b
 		bundle.Status = "success"
 	}
 	bundle.Timestamp = time.Now()
+	err = state.SignBackupBundle(&bundle, sign)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
 
 	format := "yaml"
 	marshall := yaml.Marshal
@@ -253,11 +366,152 @@ func BackupCreate(request *Request, bundles []string, jsonOutput, allowPartial b
 		os.Stdout.Write(bytes)
 	}
 
+	notifier.Finish(bundle.Status, "", nil)
+
+	operationSpan.SetAttribute("hub.status", bundle.Status)
+	if len(failedComponents) > 0 {
+		operationSpan.SetError(fmt.Errorf("Component(s) failed to %s: %s", verb, strings.Join(failedComponents, ", ")))
+	}
+	operationSpan.End()
+	metrics.Timing("operation.duration", time.Since(operationStart), metricTags(operationTags, "status", bundle.Status))
+
 	if config.Verbose {
 		printBackupEndBlurb(request, stackManifest)
 	}
 }
 
+// backupWaves groups components into waves that can run concurrently: a component is placed
+// into a wave after all the waves of components it depends on, directly or via components
+// without backup verb. Serial backup runs one component per wave.
+func backupWaves(order []string, components []manifest.ComponentRef, parallel int) [][]string {
+	waves := make([][]string, 0, len(order))
+	if parallel <= 1 {
+		for _, componentName := range order {
+			waves = append(waves, []string{componentName})
+		}
+		return waves
+	}
+	level := make(map[string]int)
+	var componentLevel func(string, []string) int
+	componentLevel = func(componentName string, path []string) int {
+		if l, exist := level[componentName]; exist {
+			return l
+		}
+		l := 0
+		component := manifest.ComponentRefByName(components, componentName)
+		if component == nil || util.Contains(path, componentName) {
+			return l
+		}
+		for _, dependency := range component.Depends {
+			dependencyLevel := componentLevel(dependency, append(path, componentName))
+			if util.Contains(order, dependency) {
+				dependencyLevel++
+			}
+			if dependencyLevel > l {
+				l = dependencyLevel
+			}
+		}
+		level[componentName] = l
+		return l
+	}
+	for _, componentName := range order {
+		l := componentLevel(componentName, nil)
+		for len(waves) <= l {
+			waves = append(waves, make([]string, 0))
+		}
+		waves[l] = append(waves[l], componentName)
+	}
+	return waves
+}
+
+func backupComponent(ctx context.Context, request *Request, verb string, component *manifest.ComponentRef, componentManifest *manifest.Manifest,
+	componentParameters parameters.LockedParameters, outputs parameters.CapturedOutputs,
+	dir, stackDir string, osEnv []string, paginate bool) (state.ComponentBackup, error) {
+
+	componentName := manifest.ComponentQualifiedNameFromRef(component)
+	workDir := dir
+	if request.Workspace {
+		workspace, removeWorkspace, err := prepareWorkspace(componentName, dir, request.KeepWorkspace)
+		if err != nil {
+			return state.ComponentBackup{Timestamp: time.Now(), Status: "error", Kind: componentName}, err
+		}
+		defer removeWorkspace()
+		workDir = workspace
+	}
+	stdout, _, err := delegateWithOutput(ctx, verb, component, componentManifest, componentParameters, outputs,
+		workDir, stackDir, osEnv, "", paginate)
+
+	var rawOutputs parameters.RawOutputs
+	if len(stdout) > 0 {
+		rawOutputs = parseTextOutput(stdout)
+	}
+	status := "success"
+	if err != nil || len(rawOutputs) == 0 {
+		if err == nil {
+			err = errors.New("no outputs emited by the component")
+		}
+		status = "error"
+	}
+	kind, exist := rawOutputs["kind"]
+	if !exist || kind == "" {
+		kind = componentName
+	}
+	timestamp := time.Now()
+	timestampStr, exist := rawOutputs["timestamp"]
+	if exist && timestampStr != "" {
+		timestamp2, err := time.Parse(time.RFC3339, timestampStr)
+		if err != nil {
+			util.Warn("Unable to parse timestamp `%s` emited by component `%s`: %v; using current time",
+				timestampStr, componentName, err)
+		} else {
+			timestamp = timestamp2
+		}
+	}
+	delete(rawOutputs, "kind")
+	delete(rawOutputs, "timestamp")
+	backupOutputs := make([]parameters.CapturedOutput, 0, len(rawOutputs))
+	for name, value := range rawOutputs {
+		backupOutputs = append(backupOutputs, parameters.CapturedOutput{Name: name, Value: value})
+	}
+	return state.ComponentBackup{
+		Timestamp: timestamp,
+		Status:    status,
+		Kind:      kind,
+		Outputs:   backupOutputs,
+	}, err
+}
+
+// previousBackupEnv exposes component's outputs from previous backup bundle to the implementation
+// so that an incremental snapshot could be taken, ie. `snapshot.id` output becomes HUB_PREVIOUS_BACKUP_SNAPSHOT_ID.
+func previousBackupEnv(previous *state.BackupManifest, componentName string) []string {
+	backup, exist := previous.Components[componentName]
+	if !exist || backup.Status != "success" {
+		return nil
+	}
+	return backupEnv(HubEnvVarNamePreviousBackupPrefix, backup)
+}
+
+func backupEnv(prefix string, backup state.ComponentBackup) []string {
+	env := []string{
+		fmt.Sprintf("%sTIMESTAMP=%s", prefix, backup.Timestamp.Format(time.RFC3339)),
+		fmt.Sprintf("%sKIND=%s", prefix, backup.Kind),
+	}
+	for _, output := range backup.Outputs {
+		env = append(env, fmt.Sprintf("%s%s=%s", prefix,
+			backupEnvName(output.Name), strings.TrimSpace(util.MaybeJson(output.Value))))
+	}
+	return env
+}
+
+func backupEnvName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(name))
+}
+
 func warnBackupFlagsNotImplemented(request *Request) {
 	if request.Application != "" {
 		util.Warn("Application `%s` parameters won't be used - not implemented. Parameters are loaded from state.", request.Application)
@@ -269,3 +523,137 @@ func warnBackupFlagsNotImplemented(request *Request) {
 		util.Warn("Environment `%s` parameters won't be used - not implemented. Parameters are loaded from state.", request.Environment)
 	}
 }
+
+func BackupVerify(request *Request, bundleFilenames []string, requireSignature bool) {
+	bundle := state.MustParseBackupBundles(bundleFilenames)
+	if config.Verbose {
+		log.Printf("Verifying backup bundle `%s`", bundle.Source)
+	}
+
+	errs := make([]error, 0)
+	if err := state.VerifyBackupBundleChecksum(bundle); err != nil {
+		errs = append(errs, err)
+	} else if config.Verbose {
+		log.Printf("Backup bundle checksum %s verified", bundle.Checksum)
+	}
+	// signature is made over checksum and is meaningless if the content doesn't match
+	if len(errs) == 0 && (bundle.Signature != "" || requireSignature) {
+		if err := state.VerifyBackupBundleSignature(bundle); err != nil {
+			errs = append(errs, err)
+		} else if config.Verbose {
+			log.Print("Backup bundle signature verified")
+		}
+	}
+	if bundle.Status != "success" {
+		util.Warn("Backup bundle status is `%s`", bundle.Status)
+	}
+
+	if len(request.ManifestFilenames) > 0 {
+		errs = append(errs, verifyComponentsBackup(request, bundle)...)
+	}
+
+	if len(errs) > 0 {
+		util.MaybeFatalf("Backup bundle `%s` verification failed:\n\t%s", bundle.Source, util.Errors("\n\t", errs...))
+	} else {
+		log.Printf("Backup bundle `%s` verified", bundle.Source)
+	}
+}
+
+// verifyComponentsBackup invokes `backup-verify` verb on each component that implements it
+// to confirm that the snapshots referenced by the bundle still exist.
+// Component's backup outputs are passed to the implementation as HUB_BACKUP_* environment variables.
+func verifyComponentsBackup(request *Request, bundle *state.BackupManifest) []error {
+	verb := request.Verb
+
+	stackManifest, componentsManifests, _, err := manifest.ParseManifest(request.ManifestFilenames)
+	if err != nil {
+		log.Fatalf("Unable to verify backup: %v", err)
+	}
+
+	osEnv, err := initOsEnv(request.OsEnvironmentMode)
+	if err != nil {
+		log.Fatalf("Unable to parse OS environment setup: %v", err)
+	}
+
+	stackBaseDir := util.Basedir(request.ManifestFilenames)
+	componentsBaseDir := request.ComponentsBaseDir
+	if componentsBaseDir == "" {
+		componentsBaseDir = stackBaseDir
+	}
+
+	var parsedState *state.StateManifest
+	if len(request.StateFilenames) > 0 {
+		stateFiles, errs := storage.Check(request.StateFilenames, "state")
+		if len(errs) > 0 {
+			util.MaybeFatalf("Unable to check state files: %v", util.Errors2(errs...))
+		}
+		parsedState, err = state.ParseState(stateFiles)
+		if err != nil {
+			util.MaybeFatalf("Unable to load state %v: %v", request.StateFilenames, err)
+		}
+	}
+
+	componentsNames := make([]string, 0, len(bundle.Components))
+	for componentName := range bundle.Components {
+		if len(request.Components) == 0 || util.Contains(request.Components, componentName) {
+			componentsNames = append(componentsNames, componentName)
+		}
+	}
+	sort.Strings(componentsNames)
+
+	errs := make([]error, 0)
+	for _, componentName := range componentsNames {
+		backup := bundle.Components[componentName]
+		if backup.Status != "success" {
+			continue
+		}
+		component := manifest.ComponentRefByName(stackManifest.Components, componentName)
+		if component == nil {
+			util.Warn("Component `%s` from backup bundle is not found in stack manifest", componentName)
+			continue
+		}
+		componentManifest := manifest.ComponentManifestByRef(componentsManifests, component)
+		dir := manifest.ComponentSourceDirFromRef(component, stackBaseDir, componentsBaseDir)
+		if !util.Contains(componentManifest.Lifecycle.Verbs, verb) {
+			if config.Verbose {
+				log.Printf("Component `%s` does not implement `%s` verb", componentName, verb)
+			}
+			continue
+		}
+		if impl, _ := probeImplementation(dir, verb); !impl {
+			errs = append(errs, fmt.Errorf("Component `%s` declares `%s` verb but no implementation found in `%s`",
+				componentName, verb, dir))
+			continue
+		}
+
+		if config.Verbose {
+			log.Printf("%s ***%s***", verb, componentName)
+		}
+
+		stackParameters := make(parameters.LockedParameters)
+		allOutputs := make(parameters.CapturedOutputs)
+		if parsedState != nil {
+			state.MergeParsedState(parsedState,
+				componentName, component.Depends, stackManifest.Lifecycle.Order, false,
+				stackParameters, allOutputs, nil)
+		}
+		expandedComponentParameters, expandErrs := parameters.ExpandParameters(componentName, componentManifest.Meta.Kind, component.Depends,
+			stackParameters, allOutputs,
+			manifest.FlattenParameters(componentManifest.Parameters, componentManifest.Meta.Name))
+		if len(expandErrs) > 0 {
+			util.MaybeFatalf("Component `%s` parameters expansion failed:\n\t%s",
+				componentName, util.Errors("\n\t", expandErrs...))
+		}
+		componentParameters := parameters.MergeParameters(make(parameters.LockedParameters), expandedComponentParameters)
+
+		componentOsEnv := mergeOsEnviron(osEnv, backupEnv(HubEnvVarNameBackupPrefix, backup))
+		_, _, err := delegate(context.Background(), verb, component, componentManifest, componentParameters, allOutputs,
+			dir, stackBaseDir, componentOsEnv, "")
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Component `%s` backup verification failed: %v", componentName, err))
+		} else {
+			log.Printf("Component `%s` backup verified", componentName)
+		}
+	}
+	return errs
+}
@@ -1,27 +1,36 @@
 package lifecycle
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/agilestacks/hub/cmd/hub/config"
 	"github.com/agilestacks/hub/cmd/hub/manifest"
+	"github.com/agilestacks/hub/cmd/hub/metrics"
+	"github.com/agilestacks/hub/cmd/hub/notify"
 	"github.com/agilestacks/hub/cmd/hub/parameters"
+	"github.com/agilestacks/hub/cmd/hub/report"
 	"github.com/agilestacks/hub/cmd/hub/state"
 	"github.com/agilestacks/hub/cmd/hub/storage"
+	"github.com/agilestacks/hub/cmd/hub/tracing"
 	"github.com/agilestacks/hub/cmd/hub/util"
 )
 
 const (
-	HubEnvVarNameComponentName    = "HUB_COMPONENT"
-	HubEnvVarNameRandom           = "HUB_RANDOM"
-	SkaffoldKubeContextEnvVarName = "SKAFFOLD_KUBE_CONTEXT"
+	HubEnvVarNameComponentName        = "HUB_COMPONENT"
+	HubEnvVarNameRandom               = "HUB_RANDOM"
+	HubEnvVarNamePreviousBackupPrefix = "HUB_PREVIOUS_BACKUP_"
+	HubEnvVarNameBackupPrefix         = "HUB_BACKUP_"
+	SkaffoldKubeContextEnvVarName     = "SKAFFOLD_KUBE_CONTEXT"
 )
 
 func Execute(request *Request, pipe io.WriteCloser) {
@@ -33,6 +42,27 @@ func Execute(request *Request, pipe io.WriteCloser) {
 	if err != nil {
 		log.Fatalf("Unable to %s: %s", request.Verb, err)
 	}
+	request.Components = manifest.ExpandComponentsNames(stackManifest.Components, request.Components)
+	if instances := manifest.ComponentInstances(stackManifest.Components, request.OffsetComponent); len(instances) > 0 {
+		request.OffsetComponent = instances[0]
+	}
+	if instances := manifest.ComponentInstances(stackManifest.Components, request.LimitComponent); len(instances) > 0 {
+		request.LimitComponent = instances[len(instances)-1]
+	}
+
+	_, operationSpan := tracing.StartOperation(context.Background(),
+		fmt.Sprintf("%s %s", request.Verb, stackManifest.Meta.Name))
+	operationSpan.SetAttribute("hub.verb", request.Verb)
+	operationSpan.SetAttribute("hub.stack", stackManifest.Meta.Name)
+	if len(request.Components) > 0 {
+		operationSpan.SetAttribute("hub.components", request.Components)
+	}
+	if request.StackInstance != "" {
+		operationSpan.SetAttribute("hub.stackInstance", request.StackInstance)
+	}
+	metrics.StartOperation()
+	operationStart := time.Now()
+	operationTags := map[string]string{"stack": stackManifest.Meta.Name, "verb": request.Verb}
 
 	if pipe != nil {
 		metricTags := fmt.Sprintf("stack:%s", stackManifest.Meta.Name)
@@ -79,6 +109,7 @@ func Execute(request *Request, pipe io.WriteCloser) {
 	defer util.Done()
 
 	var stateManifest *state.StateManifest
+	var previousState *state.StateManifest // for --report parameters diff
 	var operationsHistory []state.LifecycleOperation
 	stateUpdater := func(interface{}) {}
 	var operationLogId string
@@ -89,7 +120,17 @@ func Execute(request *Request, pipe io.WriteCloser) {
 		}
 		storage.EnsureNoLockFiles(stateFiles)
 		parsed, err := state.ParseState(stateFiles)
-		if isUndeploy || isSomeComponents {
+		if err == nil && parsed != nil && request.Report != "" {
+			previousState = state.MaskSecrets(parsed)
+		}
+		if request.Import {
+			// import creates a fresh state or adds to existing one
+			if err == nil {
+				stateManifest = parsed
+			} else if err != os.ErrNotExist {
+				log.Fatalf("Failed to read %v state files: %v", request.StateFilenames, err)
+			}
+		} else if isUndeploy || isSomeComponents {
 			if err != nil {
 				if err != os.ErrNotExist {
 					log.Fatalf("Failed to read %v state files: %v", request.StateFilenames, err)
@@ -160,7 +201,7 @@ func Execute(request *Request, pipe io.WriteCloser) {
 	stackParameters, errs := parameters.LockParameters(
 		manifest.FlattenParameters(stackManifest.Parameters, chosenManifestFilename),
 		extraExpansionValues,
-		func(parameter manifest.Parameter) (interface{}, error) {
+		func(parameter manifest.Parameter) (interface{}, *manifest.ParameterSource, error) {
 			return AskParameter(parameter, environment,
 				request.Environment, request.StackInstance, request.Application,
 				isDeploy)
@@ -235,17 +276,64 @@ func Execute(request *Request, pipe io.WriteCloser) {
 
 	failedComponents := make([]string, 0)
 
+	notifier := notify.New(request.Verb, stackManifest.Meta.Name, request.StackInstance, operationLogId,
+		stackManifest.Notifications)
+	if notifier != nil {
+		notifier.Status = func() (string, string) {
+			if stateManifest != nil {
+				return stateManifest.Status, stateManifest.Message
+			}
+			return "", ""
+		}
+	}
+
 	// TODO handle ^C interrupt to update op log and stack status
 	// or expiry by time and set to `interrupted`
 	if stateManifest != nil {
-		stateManifest = state.UpdateOperation(stateManifest, operationLogId, request.Verb, "in-progress",
-			map[string]interface{}{"args": os.Args})
+		options := map[string]interface{}{"args": os.Args}
+		if request.Import {
+			options["import"] = true
+		}
+		if len(request.Restore) > 0 {
+			options["restore"] = request.Restore
+		}
+		stateManifest = state.UpdateOperation(stateManifest, operationLogId, request.Verb, "in-progress", options)
+		if len(request.Restore) > 0 {
+			stateManifest = state.AppendOperationLog(stateManifest, operationLogId,
+				fmt.Sprintf("Restoring %s from backup %s", strings.Join(request.Components, ", "), strings.Join(request.Restore, ", ")))
+		}
 	}
 
-	ctx := watchInterrupt()
+	componentLogs := make(map[string]string)
+	if request.Report != "" {
+		writeReportAtDone(request.Report, func() *report.Report {
+			if stateManifest == nil {
+				return nil
+			}
+			return report.FromState(request.Verb, previousState, stateManifest, operationLogId,
+				operationStart, componentLogs)
+		})
+	}
+
+	if notifier != nil {
+		started := make([]string, 0, len(order))
+		for i, componentName := range order {
+			if !skipComponent(i, componentName) {
+				started = append(started, componentName)
+			}
+		}
+		notifier.Start(started)
+	}
+
+	ctx := tracing.ContextWithSpan(watchInterrupt(), operationSpan)
+	var componentSpan *tracing.Span
+	endComponent := func() {}
 
 NEXT_COMPONENT:
 	for componentIndex, componentName := range order {
+		endComponent()
+		endComponent = func() {}
+		componentSpan = nil
 		if skipComponent(componentIndex, componentName) {
 			if config.Debug {
 				log.Printf("Skip %s", componentName)
@@ -261,6 +349,21 @@ NEXT_COMPONENT:
 		component := manifest.ComponentRefByName(components, componentName)
 		componentManifest := manifest.ComponentManifestByRef(componentsManifests, component)
 
+		var componentCtx context.Context
+		componentCtx, componentSpan = tracing.Start(ctx, componentName)
+		componentSpan.SetAttribute("hub.component", componentName)
+		componentSpan.SetAttribute("hub.verb", request.Verb)
+		componentTags := metricTags(operationTags, "component", componentName)
+		componentStart := time.Now()
+		endComponent = func() {
+			status := "success"
+			if util.Contains(failedComponents, componentTags["component"]) {
+				status = "error"
+			}
+			metrics.Timing("component.duration", time.Since(componentStart), metricTags(componentTags, "status", status))
+			componentSpan.End()
+		}
+
 		if stateManifest != nil && (componentIndex == offsetComponentIndex || len(request.Components) > 0) {
 			if len(request.Components) > 0 {
 				allOutputs = make(parameters.CapturedOutputs)
@@ -292,6 +395,17 @@ NEXT_COMPONENT:
 				stateUpdater(stateManifest)
 			}
 		}
+		componentFailed := func(msg string, final bool) {
+			if updateStateComponentFailed != nil {
+				updateStateComponentFailed(msg, final)
+			}
+			notifier.ComponentFailed(componentName, msg)
+			componentSpan.SetError(errors.New(msg))
+			metrics.Count("component.failures", 1, componentTags)
+			if final { // operation is aborted
+				metrics.Timing("component.duration", time.Since(componentStart), metricTags(componentTags, "status", "error"))
+			}
+		}
 
 		if isDeploy && len(component.Depends) > 0 {
 			failed := make([]string, 0, len(component.Depends))
@@ -304,15 +418,17 @@ NEXT_COMPONENT:
 				maybeFatalIfMandatory(&stackManifest.Lifecycle, componentName,
 					fmt.Sprintf("Component `%s` failed to %s: depends on failed optional component `%s`",
 						componentName, request.Verb, strings.Join(failed, ", ")),
-					updateStateComponentFailed)
+					componentFailed)
 				failedComponents = append(failedComponents, componentName)
 				continue NEXT_COMPONENT
 			}
 		}
 
+		_, parametersSpan := tracing.Start(componentCtx, "parameters")
 		expandedComponentParameters, expansionErrs := parameters.ExpandParameters(componentName, componentManifest.Meta.Kind, component.Depends,
 			stackParameters, allOutputs,
 			manifest.FlattenParameters(componentManifest.Parameters, componentManifest.Meta.Name))
+		parametersSpan.End()
 		expandedComponentParameters = addHubProvides(expandedComponentParameters, provides)
 		allParameters := parameters.MergeParameters(stackParametersNoLinks, expandedComponentParameters)
 		optionalParametersFalse := calculateOptionalFalseParameters(componentName, allParameters, optionalRequires)
@@ -328,7 +444,7 @@ NEXT_COMPONENT:
 			maybeFatalIfMandatory(&stackManifest.Lifecycle, componentName,
 				fmt.Sprintf("Component `%s` parameters expansion failed:\n\t%s",
 					componentName, util.Errors("\n\t", expansionErrs...)),
-				updateStateComponentFailed)
+				componentFailed)
 			failedComponents = append(failedComponents, componentName)
 			continue NEXT_COMPONENT
 		}
@@ -341,7 +457,7 @@ NEXT_COMPONENT:
 					// proceed without --force set to handle required component (depends on) being already undeployed via --component
 					util.Warn("%v", err)
 				} else {
-					maybeFatalIfMandatory(&stackManifest.Lifecycle, componentName, fmt.Sprintf("%v", err), updateStateComponentFailed)
+					maybeFatalIfMandatory(&stackManifest.Lifecycle, componentName, fmt.Sprintf("%v", err), componentFailed)
 					continue NEXT_COMPONENT
 				}
 			}
@@ -380,31 +496,54 @@ NEXT_COMPONENT:
 			util.Warn("Unable to set %s: %v", HubEnvVarNameRandom, err)
 		}
 		componentDir := manifest.ComponentSourceDirFromRef(component, stackBaseDir, componentsBaseDir)
-		stdout, stderr, err := delegate(maybeTestVerb(request.Verb, request.DryRun),
-			component, componentManifest, componentParameters,
-			componentDir, osEnv, randomStr)
+		workDir := componentDir
+		var removeWorkspace func()
+		if request.Workspace {
+			workDir, removeWorkspace, err = prepareWorkspace(componentName, componentDir, request.KeepWorkspace)
+			if err != nil {
+				maybeFatalIfMandatory(&stackManifest.Lifecycle, componentName, fmt.Sprintf("%v", err), componentFailed)
+				failedComponents = append(failedComponents, componentName)
+				continue NEXT_COMPONENT
+			}
+		}
+		var stdout, stderr []byte
+		if request.Import && request.ImportOutputs != "" {
+			stdout, err = importOutputs(request.ImportOutputs)
+		} else {
+			verb := maybeTestVerb(request.Verb, request.DryRun)
+			if request.Import {
+				verb = "import"
+			}
+			stdout, stderr, err = delegate(componentCtx, verb,
+				component, componentManifest, componentParameters, allOutputs,
+				workDir, stackBaseDir, osEnv, randomStr)
+		}
 
 		var rawOutputs parameters.RawOutputs
 		if err != nil {
 			if stateManifest != nil {
-				stateManifest = state.AppendOperationLog(stateManifest, operationLogId,
+				logAdd := redact(secretValues(componentParameters, allOutputs),
 					fmt.Sprintf("%v%s", err, formatStdoutStderr(stdout, stderr)))
+				stateManifest = state.AppendOperationLog(stateManifest, operationLogId, logAdd)
+				componentLogs[componentName] = logAdd
 			}
 			maybeFatalIfMandatory(&stackManifest.Lifecycle, componentName,
 				fmt.Sprintf("Component `%s` failed to %s: %v", componentName, request.Verb, err),
-				updateStateComponentFailed)
+				componentFailed)
 			failedComponents = append(failedComponents, componentName)
 		} else if isDeploy {
+			_, outputsSpan := tracing.Start(componentCtx, "outputs")
 			rawOutputsCaptured, componentOutputs, dynamicProvides, errs :=
-				captureOutputs(componentName, componentDir, componentManifest, componentParameters,
+				captureOutputs(componentName, workDir, componentManifest, componentParameters,
 					stdout, random)
+			outputsSpan.End()
 			rawOutputs = rawOutputsCaptured
 			if len(errs) > 0 {
 				log.Printf("Component `%s` failed to %s", componentName, request.Verb)
 				maybeFatalIfMandatory(&stackManifest.Lifecycle, componentName,
 					fmt.Sprintf("Component `%s` outputs capture failed:\n\t%s",
 						componentName, util.Errors("\n\t", errs...)),
-					updateStateComponentFailed)
+					componentFailed)
 				failedComponents = append(failedComponents, componentName)
 			}
 			if len(componentOutputs) > 0 &&
@@ -442,6 +581,9 @@ NEXT_COMPONENT:
 				stateManifest.Provides = noEnvironmentProvides(provides)
 			}
 		}
+		if removeWorkspace != nil {
+			removeWorkspace()
+		}
 
 		if ctx.Err() != nil {
 			break
@@ -456,12 +598,16 @@ NEXT_COMPONENT:
 		}
 
 		if err == nil && isDeploy {
-			err = waitForReadyConditions(ctx, componentManifest.Lifecycle.ReadyConditions, componentParameters, allOutputs, component.Depends)
+			readyStart := time.Now()
+			err = waitForReadyConditions(componentCtx, componentManifest.Lifecycle.ReadyConditions, componentParameters, allOutputs, component.Depends)
+			if len(componentManifest.Lifecycle.ReadyConditions) > 0 {
+				metrics.Timing("component.ready.wait", time.Since(readyStart), componentTags)
+			}
 			if err != nil {
 				log.Printf("Component `%s` failed to %s", componentName, request.Verb)
 				maybeFatalIfMandatory(&stackManifest.Lifecycle, componentName,
 					fmt.Sprintf("Component `%s` ready condition failed: %v", componentName, err),
-					updateStateComponentFailed)
+					componentFailed)
 				failedComponents = append(failedComponents, componentName)
 			}
 		}
@@ -481,10 +627,18 @@ NEXT_COMPONENT:
 
 		// end of component cycle
 	}
+	endComponent()
+	if ctx.Err() != nil {
+		operationSpan.SetError(ctx.Err())
+	}
 
 	stackReadyConditionFailed := false
 	if isDeploy {
+		readyStart := time.Now()
 		err := waitForReadyConditions(ctx, stackManifest.Lifecycle.ReadyConditions, stackParameters, allOutputs, nil)
+		if len(stackManifest.Lifecycle.ReadyConditions) > 0 {
+			metrics.Timing("ready.wait", time.Since(readyStart), operationTags)
+		}
 		if err != nil {
 			message := fmt.Sprintf("Stack ready condition failed: %v", err)
 			if stateManifest != nil {
@@ -514,6 +668,19 @@ NEXT_COMPONENT:
 		stackOutputs = parameters.ExpandRequestedOutputs(stackParameters, allOutputs, stackManifest.Outputs, false)
 	}
 
+	finalStatus, finalMessage := "", ""
+	if stateManifest != nil {
+		finalStatus, finalMessage = stateManifest.Status, stateManifest.Message
+	}
+	notifier.Finish(finalStatus, finalMessage, stackOutputs)
+
+	operationSpan.SetAttribute("hub.status", finalStatus)
+	if len(failedComponents) > 0 {
+		operationSpan.SetError(fmt.Errorf("Component(s) failed to %s: %s", request.Verb, strings.Join(failedComponents, ", ")))
+	}
+	operationSpan.End()
+	metrics.Timing("operation.duration", time.Since(operationStart), metricTags(operationTags, "status", finalStatus))
+
 	if config.Verbose {
 		if isDeploy {
 			provides2 := noEnvironmentProvides(provides)
@@ -530,6 +697,36 @@ NEXT_COMPONENT:
 	}
 }
 
+// writeReportAtDone writes --report when operation completes, or is aborted by a fatal error
+func writeReportAtDone(filename string, build func() *report.Report) {
+	util.AtDone(func() <-chan struct{} {
+		r := build()
+		if r == nil {
+			util.Warn("Nothing to write into `%s` report", filename)
+			return nil
+		}
+		if err := report.Write(filename, r); err != nil {
+			util.Warn("Unable to write `%s` report: %v", filename, err)
+		} else if config.Verbose {
+			log.Printf("Wrote %s report to %s", r.Verb, filename)
+		}
+		return nil
+	})
+}
+
+func metricTags(tags map[string]string, kv ...string) map[string]string {
+	merged := make(map[string]string, len(tags)+len(kv)/2)
+	for k, v := range tags {
+		merged[k] = v
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i+1] != "" {
+			merged[kv[i]] = kv[i+1]
+		}
+	}
+	return merged
+}
+
 func optionalComponent(lifecycle *manifest.Lifecycle, componentName string) bool {
 	return (len(lifecycle.Mandatory) > 0 && !util.Contains(lifecycle.Mandatory, componentName)) ||
 		util.Contains(lifecycle.Optional, componentName)
@@ -588,9 +785,19 @@ func maybeTestVerb(verb string, test bool) string {
 	return verb
 }
 
-func delegate(verb string, component *manifest.ComponentRef, componentManifest *manifest.Manifest,
-	componentParameters parameters.LockedParameters,
-	dir string, osEnv []string, random string) ([]byte, []byte, error) {
+func delegate(ctx context.Context, verb string, component *manifest.ComponentRef, componentManifest *manifest.Manifest,
+	componentParameters parameters.LockedParameters, outputs parameters.CapturedOutputs,
+	dir, stackDir string, osEnv []string, random string) (stdout []byte, stderr []byte, err error) {
+
+	return delegateWithOutput(ctx, verb, component, componentManifest, componentParameters, outputs,
+		dir, stackDir, osEnv, random, true)
+}
+
+// delegateWithOutput paginates sub-process output on terminal when requested, which must be off
+// for concurrent sub-processes
+func delegateWithOutput(ctx context.Context, verb string, component *manifest.ComponentRef, componentManifest *manifest.Manifest,
+	componentParameters parameters.LockedParameters, outputs parameters.CapturedOutputs,
+	dir, stackDir string, osEnv []string, random string, paginate bool) (stdout []byte, stderr []byte, err error) {
 
 	if config.Debug && len(componentParameters) > 0 {
 		log.Print("Component parameters:")
@@ -598,7 +805,13 @@ func delegate(verb string, component *manifest.ComponentRef, componentManifest *
 	}
 
 	componentName := manifest.ComponentQualifiedNameFromRef(component)
-	errs := processTemplates(component, &componentManifest.Templates, componentParameters, nil, dir)
+	ctx, span := tracing.Start(ctx, "delegate "+verb)
+	span.SetAttribute("hub.component", componentName)
+	defer func() { span.EndWithError(err) }()
+
+	_, templatesSpan := tracing.Start(ctx, "templates")
+	errs := processTemplates(component, &componentManifest.Templates, componentParameters, nil, outputs, dir, stackDir)
+	templatesSpan.End()
 	if len(errs) > 0 {
 		return nil, nil, fmt.Errorf("Failed to process templates:\n\t%s", util.Errors("\n\t", errs...))
 	}
@@ -615,7 +828,7 @@ func delegate(verb string, component *manifest.ComponentRef, componentManifest *
 		return nil, nil, err
 	}
 	skaffoldEnvironment := skaffoldEnv(impl, processEnv)
-	impl.Env = mergeOsEnviron(osEnv, processEnv, randomEnv(random), skaffoldEnvironment)
+	impl.Env = mergeOsEnviron(osEnv, processEnv, randomEnv(random), skaffoldEnvironment, tracing.TraceParentEnv(ctx))
 	if config.Debug && len(processEnv) > 0 {
 		log.Print("Component environment:")
 		printEnvironment(processEnv)
@@ -626,7 +839,7 @@ func delegate(verb string, component *manifest.ComponentRef, componentManifest *
 		}
 	}
 
-	stdout, stderr, err := execImplementation(impl, false, true)
+	stdout, stderr, err = execImplementation(impl, false, paginate, secretValues(componentParameters, outputs))
 	return stdout, stderr, err
 }
 
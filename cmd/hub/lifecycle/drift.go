@@ -0,0 +1,231 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/manifest"
+	"github.com/agilestacks/hub/cmd/hub/parameters"
+	"github.com/agilestacks/hub/cmd/hub/state"
+	"github.com/agilestacks/hub/cmd/hub/storage"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+var driftVerbs = []string{"check", "plan"}
+
+// CheckDrift invokes `check` (or `plan`) verb on each deployed component with the same parameters and outputs
+// deploy would use, and compares outputs reported by the implementation with outputs captured in state.
+// Only outputs that are captured from raw outputs (fromTfVar) and are emitted by the verb are compared.
+func CheckDrift(request *Request, record bool) {
+	stackManifest, componentsManifests, _, err := manifest.ParseManifest(request.ManifestFilenames)
+	if err != nil {
+		log.Fatalf("Unable to check drift: %v", err)
+	}
+	request.Components = manifest.ExpandComponentsNames(stackManifest.Components, request.Components)
+	manifest.CheckComponentsExist(stackManifest.Components, request.Components...)
+
+	osEnv, err := initOsEnv(request.OsEnvironmentMode)
+	if err != nil {
+		log.Fatalf("Unable to parse OS environment setup: %v", err)
+	}
+
+	stackBaseDir := util.Basedir(request.ManifestFilenames)
+	componentsBaseDir := request.ComponentsBaseDir
+	if componentsBaseDir == "" {
+		componentsBaseDir = stackBaseDir
+	}
+
+	stateFiles, errs := storage.Check(request.StateFilenames, "state")
+	if len(errs) > 0 {
+		log.Fatalf("Unable to check state files: %s", util.Errors2(errs...))
+	}
+	stateManifest, err := state.ParseState(stateFiles)
+	if err != nil {
+		log.Fatalf("Unable to load state %v: %v", request.StateFilenames, err)
+	}
+
+	order := make([]string, 0, len(stackManifest.Lifecycle.Order))
+	for _, componentName := range stackManifest.Lifecycle.Order {
+		if len(request.Components) == 0 || util.Contains(request.Components, componentName) {
+			order = append(order, componentName)
+		}
+	}
+
+	drifted := make([]string, 0)
+	errs = make([]error, 0)
+	checked := 0
+	for _, componentName := range order {
+		step, exist := stateManifest.Components[componentName]
+		if !exist || step.Status == "" {
+			if config.Verbose {
+				log.Printf("Component `%s` is not deployed - skipping drift check", componentName)
+			}
+			continue
+		}
+		component := manifest.ComponentRefByName(stackManifest.Components, componentName)
+		componentManifest := manifest.ComponentManifestByRef(componentsManifests, component)
+		dir := manifest.ComponentSourceDirFromRef(component, stackBaseDir, componentsBaseDir)
+
+		verb := driftVerb(request.Verb, componentManifest, dir)
+		if verb == "" {
+			if config.Verbose {
+				log.Printf("Component `%s` does not implement %s verb", componentName, strings.Join(driftVerbs, " nor "))
+			}
+			continue
+		}
+
+		if config.Verbose {
+			log.Printf("%s ***%s***", verb, componentName)
+		}
+		checked++
+
+		stackParameters := make(parameters.LockedParameters)
+		allOutputs := make(parameters.CapturedOutputs)
+		state.MergeParsedState(stateManifest,
+			componentName, component.Depends, stackManifest.Lifecycle.Order, false,
+			stackParameters, allOutputs, nil)
+		expandedComponentParameters, expandErrs := parameters.ExpandParameters(componentName, componentManifest.Meta.Kind, component.Depends,
+			stackParameters, allOutputs,
+			manifest.FlattenParameters(componentManifest.Parameters, componentManifest.Meta.Name))
+		if len(expandErrs) > 0 {
+			util.MaybeFatalf("Component `%s` parameters expansion failed:\n\t%s",
+				componentName, util.Errors("\n\t", expandErrs...))
+		}
+		componentParameters := parameters.MergeParameters(make(parameters.LockedParameters), expandedComponentParameters)
+
+		stdout, _, err := delegate(context.Background(), verb, component, componentManifest, componentParameters, allOutputs,
+			dir, stackBaseDir, osEnv, "")
+		drift := &state.DriftStatus{Timestamp: time.Now()}
+		if err != nil {
+			err = fmt.Errorf("Component `%s` %s failed: %v", componentName, verb, err)
+			errs = append(errs, err)
+			drift.Status = "error"
+			drift.Message = err.Error()
+		} else {
+			diff, err := driftOutputs(componentName, dir, componentManifest, componentParameters, stdout, step.CapturedOutputs)
+			if err != nil {
+				errs = append(errs, err)
+				drift.Status = "error"
+				drift.Message = err.Error()
+			} else if len(diff) > 0 {
+				drifted = append(drifted, componentName)
+				drift.Status = "drifted"
+				drift.Outputs = diff
+				log.Printf("Component `%s` drifted:\n\t%s", componentName, strings.Join(diff, "\n\t"))
+			} else {
+				drift.Status = "in-sync"
+				log.Printf("Component `%s` is in sync with state", componentName)
+			}
+		}
+		if record {
+			step.Drift = drift
+		}
+	}
+
+	if record && checked > 0 {
+		status := "success"
+		if len(errs) > 0 {
+			status = "error"
+		} else if len(drifted) > 0 {
+			status = "drifted"
+		}
+		u, err := uuid.NewRandom()
+		if err != nil {
+			log.Fatalf("Unable to generate operation Id random v4 UUID: %v", err)
+		}
+		stateManifest = state.UpdateOperation(stateManifest, u.String(), "check", status,
+			map[string]interface{}{"drifted": drifted})
+		err = state.WriteState(stateManifest, stateFiles)
+		if err != nil {
+			util.Warn("Unable to record drift status: %v", err)
+		}
+	}
+
+	if checked == 0 {
+		util.Warn("No components implement %s verb - nothing to check", strings.Join(driftVerbs, " or "))
+	}
+	if len(errs) > 0 {
+		util.MaybeFatalf("Drift check failed:\n\t%s", util.Errors("\n\t", errs...))
+	}
+	if len(drifted) > 0 {
+		util.MaybeFatalf("Drift detected in %s", strings.Join(drifted, ", "))
+	}
+}
+
+func driftVerb(verb string, componentManifest *manifest.Manifest, dir string) string {
+	verbs := driftVerbs
+	if verb != "" {
+		verbs = []string{verb}
+	}
+	for _, verb := range verbs {
+		if !util.Contains(componentManifest.Lifecycle.Verbs, verb) {
+			continue
+		}
+		if impl, _ := probeImplementation(dir, verb); impl {
+			return verb
+		}
+	}
+	return ""
+}
+
+// driftOutputs returns a description of each output which value reported by the check differs from the state
+func driftOutputs(componentName, dir string, componentManifest *manifest.Manifest,
+	componentParameters parameters.LockedParameters, stdout []byte,
+	stateOutputs []parameters.CapturedOutput) ([]string, error) {
+
+	rawOutputs := parseTextOutput(stdout)
+	reported := make([]manifest.Output, 0, len(componentManifest.Outputs))
+	for _, output := range componentManifest.Outputs {
+		if output.FromTfVar == "" {
+			continue
+		}
+		variable, _ := valueEncodings(output.FromTfVar)
+		if _, exist := rawOutputs[variable]; exist {
+			reported = append(reported, output)
+		}
+	}
+	if len(reported) == 0 {
+		util.Warn("Component `%s` reported no outputs to compare with state", componentName)
+		return nil, nil
+	}
+	liveOutputs, errs := expandRequestedOutputs(componentName, dir, componentParameters, reported, rawOutputs)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("Component `%s` outputs capture failed:\n\t%s", componentName, util.Errors("\n\t", errs...))
+	}
+
+	stateValues := make(map[string]parameters.CapturedOutput)
+	for _, output := range state.DecryptOutputs(stateOutputs) {
+		stateValues[output.QName()] = output
+	}
+	diff := make([]string, 0)
+	for _, live := range parameters.CapturedOutputsToList(liveOutputs) {
+		qName := live.QName()
+		liveValue := util.MaybeJson(live.Value)
+		current, exist := stateValues[qName]
+		if !exist {
+			diff = append(diff, fmt.Sprintf("%s: not in state, live `%s`",
+				live.Name, driftValue(live.Name, live.Kind, liveValue)))
+			continue
+		}
+		stateValue := util.MaybeJson(current.Value)
+		if stateValue != liveValue {
+			diff = append(diff, fmt.Sprintf("%s: state `%s`, live `%s`", live.Name,
+				driftValue(live.Name, live.Kind, stateValue), driftValue(live.Name, live.Kind, liveValue)))
+		}
+	}
+	return diff, nil
+}
+
+// secrets are always masked as drift description may be recorded in state
+func driftValue(name, kind, value string) string {
+	if state.IsSecret(name, kind) && value != "" {
+		return "(masked)"
+	}
+	return util.Trim(value)
+}
@@ -23,7 +23,9 @@ func goWait(routine func()) chan string {
 	return ch
 }
 
-func execImplementation(impl *exec.Cmd, passStdin, paginate bool) ([]byte, []byte, error) {
+// execImplementation returns sub-process stdout and stderr as is, while secrets are redacted in the output
+// displayed to the user
+func execImplementation(impl *exec.Cmd, passStdin, paginate bool, secrets []string) ([]byte, []byte, error) {
 	stderrImpl, err := impl.StderrPipe()
 	if err != nil {
 		return nil, nil, fmt.Errorf("Unable to obtain sub-process stderr pipe: %v", err)
@@ -63,6 +65,9 @@ func execImplementation(impl *exec.Cmd, passStdin, paginate bool) ([]byte, []byt
 		log.SetOutput(tail)
 	}
 
+	stdout = newRedactWriter(stdout, secrets)
+	stderr = newRedactWriter(stderr, secrets)
+
 	var stdoutBuffer bytes.Buffer
 	var stderrBuffer bytes.Buffer
 	stdoutWritter := io.MultiWriter(&stdoutBuffer, stdout)
@@ -85,6 +90,8 @@ func execImplementation(impl *exec.Cmd, passStdin, paginate bool) ([]byte, []byt
 	err = impl.Start()
 	<-stdoutComplete
 	<-stderrComplete
+	flushRedactWriter(stdout)
+	flushRedactWriter(stderr)
 
 	fmt.Print("---\n")
 	os.Stdout.Sync()
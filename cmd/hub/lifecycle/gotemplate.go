@@ -0,0 +1,209 @@
+package lifecycle
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	gotemplate "text/template"
+
+	"github.com/Masterminds/sprig"
+	"gopkg.in/yaml.v2"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/kube"
+	"github.com/agilestacks/hub/cmd/hub/parameters"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+const maxIncludeDepth = 10
+
+type goTemplateContext struct {
+	filename      string
+	componentName string
+	depends       []string
+	kv            map[string]interface{}
+	outputs       parameters.CapturedOutputs
+	// `include` and `b64file` search path: component dir, then stack dir
+	dirs  []string
+	depth int
+}
+
+func (ctx *goTemplateContext) funcs() gotemplate.FuncMap {
+	return gotemplate.FuncMap{
+		"output":     ctx.output,
+		"param":      ctx.param,
+		"secret":     ctx.secret,
+		"required":   ctx.required,
+		"include":    ctx.include,
+		"b64file":    ctx.b64file,
+		"kubeconfig": ctx.kubeconfig,
+		"toYaml":     toYaml,
+		"fromYaml":   fromYaml,
+	}
+}
+
+func (ctx *goTemplateContext) output(qName string) (interface{}, error) {
+	if output, exist := ctx.outputs[qName]; exist {
+		return output.Value, nil
+	}
+	if value, exist := ctx.kv[qName]; exist && strings.Contains(qName, ":") {
+		return value, nil
+	}
+	if !strings.Contains(qName, ":") {
+		return nil, fmt.Errorf("Template `%s` output `%s` must be qualified as `component:name`", ctx.filename, qName)
+	}
+	return nil, fmt.Errorf("Template `%s` refer to unknown output `%s`", ctx.filename, qName)
+}
+
+func (ctx *goTemplateContext) param(name string) (interface{}, error) {
+	value, exist := parameters.FindValue(name, ctx.componentName, ctx.depends, ctx.kv)
+	if !exist {
+		return nil, fmt.Errorf("Template `%s` refer to unknown parameter `%s`", ctx.filename, name)
+	}
+	return value, nil
+}
+
+func (ctx *goTemplateContext) secret(name string) (string, error) {
+	var value interface{}
+	var err error
+	if strings.Contains(name, ":") {
+		value, err = ctx.output(name)
+	} else {
+		value, err = ctx.param(name)
+	}
+	if err != nil {
+		return "", err
+	}
+	str := util.String(value)
+	if str == "" {
+		return "", fmt.Errorf("Template `%s` secret `%s` is empty", ctx.filename, name)
+	}
+	if config.Trace {
+		log.Printf("--- secret %s => %s", name, util.Trim(str))
+	}
+	return str, nil
+}
+
+func (ctx *goTemplateContext) required(name string, value interface{}) (interface{}, error) {
+	if util.Empty(value) {
+		return nil, fmt.Errorf("Template `%s` component `%s` requires `%s` to be set to a non-empty value",
+			ctx.filename, ctx.componentName, name)
+	}
+	return value, nil
+}
+
+func (ctx *goTemplateContext) find(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	for _, dir := range ctx.dirs {
+		candidate := filepath.Join(dir, path)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("Template `%s` refer to `%s` not found in %v", ctx.filename, path, ctx.dirs)
+}
+
+func (ctx *goTemplateContext) include(path string, data interface{}) (string, error) {
+	if ctx.depth >= maxIncludeDepth {
+		return "", fmt.Errorf("Template `%s` include `%s` reached depth %d - probably a loop", ctx.filename, path, ctx.depth)
+	}
+	filename, err := ctx.find(path)
+	if err != nil {
+		return "", err
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read `%s` included by `%s`: %v", filename, ctx.filename, err)
+	}
+	nested := *ctx
+	nested.filename = filename
+	nested.depth++
+	return nested.execute(string(content), data)
+}
+
+func (ctx *goTemplateContext) b64file(path string) (string, error) {
+	filename, err := ctx.find(path)
+	if err != nil {
+		return "", err
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read `%s`: %v", filename, err)
+	}
+	return base64.StdEncoding.EncodeToString(content), nil
+}
+
+func (ctx *goTemplateContext) kubeconfig(context ...string) (string, error) {
+	lookup := func(name string) (string, bool) {
+		value, exist := parameters.FindValue(name, ctx.componentName, ctx.depends, ctx.kv)
+		if !exist {
+			return "", false
+		}
+		return util.String(value), true
+	}
+	name := ""
+	if len(context) > 0 {
+		name = context[0]
+	}
+	content, err := kube.KubeconfigContent(lookup, name)
+	if err != nil {
+		return "", fmt.Errorf("Template `%s` unable to render Kubeconfig: %v", ctx.filename, err)
+	}
+	return content, nil
+}
+
+func (ctx *goTemplateContext) execute(content string, data interface{}) (string, error) {
+	tmpl, err := gotemplate.New(filepath.Base(ctx.filename)).
+		Funcs(sprig.TxtFuncMap()).Funcs(hubGoTemplateFuncMap).Funcs(ctx.funcs()).
+		Parse(content)
+	if err != nil {
+		return "", err
+	}
+	var buffer bytes.Buffer
+	err = tmpl.Execute(&buffer, data)
+	if err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+func toYaml(value interface{}) (string, error) {
+	bytes, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(bytes), "\n"), nil
+}
+
+func fromYaml(str string) (interface{}, error) {
+	var value interface{}
+	err := yaml.Unmarshal([]byte(str), &value)
+	if err != nil {
+		return nil, err
+	}
+	return stringKeys(value), nil
+}
+
+// stringKeys converts YAML map[interface{}]interface{} to map[string]interface{} for sprig and `toJson`
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[fmt.Sprintf("%v", key)] = stringKeys(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = stringKeys(val)
+		}
+	}
+	return value
+}
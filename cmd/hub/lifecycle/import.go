@@ -0,0 +1,20 @@
+package lifecycle
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+)
+
+// importOutputs reads raw outputs supplied by user in the same `name = value` format
+// the implementation prints after `Outputs:` marker; the marker is optional
+func importOutputs(filename string) ([]byte, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read outputs to import: %v", err)
+	}
+	if !bytes.HasPrefix(content, outputsMarker) && !bytes.Contains(content, append([]byte("\n"), outputsMarker...)) {
+		content = append(append([]byte{}, outputsMarker...), content...)
+	}
+	return content, nil
+}
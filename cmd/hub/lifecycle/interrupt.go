@@ -16,14 +16,16 @@ var interruptSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
 func watchInterrupt() context.Context {
 	ctx, interrupted := context.WithCancel(context.Background())
 	sigs := make(chan os.Signal, 1)
-	unwatch := make(chan struct{})
+	unwatch := make(chan struct{}, 1)
 	signal.Notify(sigs, interruptSignals...)
 	go func() {
+		defer util.RecoverAbort()
 		for {
 			select {
 			case sig := <-sigs:
 				if ctx.Err() != nil {
-					os.Exit(3)
+					signal.Reset(interruptSignals...)
+					util.Exit(3)
 				}
 				interrupted()
 				if config.Verbose {
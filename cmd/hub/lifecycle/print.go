@@ -9,6 +9,7 @@ import (
 	"github.com/agilestacks/hub/cmd/hub/config"
 	"github.com/agilestacks/hub/cmd/hub/manifest"
 	"github.com/agilestacks/hub/cmd/hub/parameters"
+	"github.com/agilestacks/hub/cmd/hub/state"
 	"github.com/agilestacks/hub/cmd/hub/util"
 )
 
@@ -141,7 +142,7 @@ func printStackOutputs(outputs []parameters.ExpandedOutput) {
 			if output.Brief != "" {
 				brief = fmt.Sprintf(" [%s]", util.HighlightColor(output.Brief))
 			}
-			value := util.String(output.Value)
+			value := util.String(state.DecryptValue(output.Value))
 			valueMasked := false
 			if !config.Trace && strings.HasPrefix(output.Kind, "secret") && len(value) > 0 {
 				value = "(masked)"
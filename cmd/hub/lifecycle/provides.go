@@ -2,7 +2,6 @@ package lifecycle
 
 import (
 	"log"
-	"os"
 	"strings"
 
 	"github.com/agilestacks/hub/cmd/hub/config"
@@ -56,7 +55,7 @@ func mergeProvides(provides map[string][]string, componentName string, component
 					log.Print("Outputs:")
 					parameters.PrintCapturedOutputs(componentOutputs)
 					if !config.Force {
-						os.Exit(1)
+						util.Exit(1)
 					}
 				}
 			}
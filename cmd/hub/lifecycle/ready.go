@@ -12,18 +12,25 @@ import (
 	"github.com/agilestacks/hub/cmd/hub/config"
 	"github.com/agilestacks/hub/cmd/hub/manifest"
 	"github.com/agilestacks/hub/cmd/hub/parameters"
+	"github.com/agilestacks/hub/cmd/hub/tracing"
 	"github.com/agilestacks/hub/cmd/hub/util"
 )
 
 func waitForReadyConditions(ctx context.Context, conditions []manifest.ReadyCondition,
 	parameters parameters.LockedParameters, outputs parameters.CapturedOutputs, componentDepends []string) error {
 
+	if len(conditions) == 0 {
+		return nil
+	}
+	_, span := tracing.Start(ctx, "ready")
 	for _, condition := range conditions {
 		err := waitForReadyCondition(ctx, condition, parameters, outputs, componentDepends)
 		if err != nil {
+			span.EndWithError(err)
 			return err
 		}
 	}
+	span.End()
 	return nil
 }
 
@@ -0,0 +1,125 @@
+package lifecycle
+
+import (
+	"encoding/base64"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/agilestacks/hub/cmd/hub/parameters"
+	"github.com/agilestacks/hub/cmd/hub/state"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+const (
+	redactedValue        = "***"
+	redactMinSecretLen   = 4 // do not redact every `a` or `yes`
+	redactSecretVariants = 5
+)
+
+// secretValues returns values of secret parameters and outputs with their base64 and URL-encoded variants,
+// longest first so that a longer secret is redacted before a shorter one that is a part of it
+func secretValues(params parameters.LockedParameters, outputs parameters.CapturedOutputs) []string {
+	secrets := make([]string, 0, (len(params)+len(outputs))*redactSecretVariants)
+	add := func(value interface{}) {
+		str := strings.TrimSpace(util.MaybeJson(value))
+		if len(str) < redactMinSecretLen {
+			return
+		}
+		secrets = append(secrets,
+			str,
+			base64.StdEncoding.EncodeToString([]byte(str)),
+			url.QueryEscape(str),
+			strings.ReplaceAll(url.QueryEscape(str), "+", "%20"),
+			url.PathEscape(str))
+	}
+	for _, p := range params {
+		if state.IsSecret(p.Name, "") {
+			add(p.Value)
+		}
+	}
+	for _, o := range outputs {
+		if state.IsSecret(o.Name, o.Kind) {
+			add(o.Value)
+		}
+	}
+	secrets = util.Uniq(secrets)
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	return secrets
+}
+
+func newSecretsReplacer(secrets []string) *strings.Replacer {
+	oldnew := make([]string, 0, len(secrets)*2)
+	for _, secret := range secrets {
+		oldnew = append(oldnew, secret, redactedValue)
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+func redact(secrets []string, text string) string {
+	if len(secrets) == 0 {
+		return text
+	}
+	return newSecretsReplacer(secrets).Replace(text)
+}
+
+// redactWriter replaces secrets in the stream; the tail of the written data that may be
+// the beginning of a secret is held until more data arrives or the writer is flushed
+type redactWriter struct {
+	out      io.Writer
+	secrets  []string
+	replacer *strings.Replacer
+	pending  string
+}
+
+func newRedactWriter(out io.Writer, secrets []string) io.Writer {
+	if len(secrets) == 0 {
+		return out
+	}
+	return &redactWriter{out: out, secrets: secrets, replacer: newSecretsReplacer(secrets)}
+}
+
+func (w *redactWriter) Write(p []byte) (int, error) {
+	data := w.pending + string(p)
+	hold := w.secretPrefixLen(data)
+	w.pending = data[len(data)-hold:]
+	_, err := io.WriteString(w.out, w.replacer.Replace(data[:len(data)-hold]))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *redactWriter) Flush() error {
+	if w.pending == "" {
+		return nil
+	}
+	_, err := io.WriteString(w.out, w.replacer.Replace(w.pending))
+	w.pending = ""
+	return err
+}
+
+// secretPrefixLen returns length of the longest suffix of data that is a proper prefix of any secret
+func (w *redactWriter) secretPrefixLen(data string) int {
+	hold := 0
+	for _, secret := range w.secrets {
+		max := len(secret) - 1
+		if max > len(data) {
+			max = len(data)
+		}
+		for l := max; l > hold; l-- {
+			if strings.HasSuffix(data, secret[:l]) {
+				hold = l
+				break
+			}
+		}
+	}
+	return hold
+}
+
+func flushRedactWriter(w io.Writer) {
+	if r, ok := w.(*redactWriter); ok {
+		r.Flush()
+	}
+}
@@ -99,11 +99,11 @@ func Render(manifestFilenames, stateFilenames []string, componentName,
 				util.Warn("Component `%s` state doesn't exist; using stack-level parameters and outputs instead", componentName)
 			}
 		}
-		params = parameters.ParametersFromList(stateParameters)
+		params = parameters.ParametersFromList(state.DecryptParameters(stateParameters))
 		if len(additionalParameters) > 0 {
 			params = parameters.MergeParameters(params, additionalParameters)
 		}
-		outputs = parameters.OutputsFromList(stateOutputs)
+		outputs = parameters.OutputsFromList(state.DecryptOutputs(stateOutputs))
 	}
 
 	if config.Debug {
@@ -124,7 +124,11 @@ func Render(manifestFilenames, stateFilenames []string, componentName,
 		componentName = "*stack*"
 	}
 	ref := &manifest.ComponentRef{Name: componentName}
-	errs = processTemplates(ref, &templateSetup, params, outputs, dir)
+	stackDir := dir
+	if len(manifestFilenames) > 0 {
+		stackDir = util.Basedir(manifestFilenames)
+	}
+	errs = processTemplates(ref, &templateSetup, params, outputs, outputs, dir, stackDir)
 	if len(errs) > 0 {
 		util.MaybeFatalf("Failed to process `%s` templates:\n\t%s",
 			componentName, util.Errors("\n\t", errs...))
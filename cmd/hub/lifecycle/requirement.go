@@ -200,7 +200,7 @@ func checkStackRequires(requires []string, optional, requiresOfOptionalComponent
 			}
 			continue
 		}
-		os.Exit(1)
+		util.Exit(1)
 	}
 	return provided
 }
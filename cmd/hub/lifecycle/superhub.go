@@ -2,16 +2,36 @@ package lifecycle
 
 import (
 	"log"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/agilestacks/hub/cmd/hub/api"
 	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/metrics"
 	"github.com/agilestacks/hub/cmd/hub/state"
 	"github.com/agilestacks/hub/cmd/hub/storage"
 	"github.com/agilestacks/hub/cmd/hub/util"
 )
 
+var (
+	hubSyncRetryInitial = 2 * time.Second
+	hubSyncRetryMax     = 60 * time.Second
+)
+
+// hubSync sends state to SuperHub; a failed sync is queued on disk and retried with backoff
+// until a newer state is synced or the run is over
+type hubSync struct {
+	selector string
+	lock     sync.Mutex
+	pending  *api.StackInstancePatch
+	dequeued bool // no entry of the instance is left in on-disk queue, including from previous runs
+	retrying bool
+	stop     chan struct{}
+}
+
 func hubSyncer(request *Request) func(*state.StateManifest) {
+	s := &hubSync{selector: request.StackInstance, stop: make(chan struct{})}
+	util.AtDone(s.done)
 	return func(stateManifest *state.StateManifest) {
 		patch := api.TransformStateToApi(stateManifest)
 		remoteStatePaths := storage.RemoteStoragePaths(request.StateFilenames)
@@ -29,10 +49,106 @@ func hubSyncer(request *Request) func(*state.StateManifest) {
 				printStackInstancePatch(patch)
 			}
 		}
-		_, err := api.PatchStackInstance(request.StackInstance, patch, true)
-		if err != nil {
-			util.Warn("Unable to sync stack instance state to SuperHub: %v\n\ttry running sync manually: hub api instance sync %s -s %s ",
-				err, request.StackInstance, strings.Join(request.StateFilenames, ","))
+		s.sync(patch)
+	}
+}
+
+func (s *hubSync) sync(patch api.StackInstancePatch) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err := api.PatchStackInstance(s.selector, patch, true)
+	if err != nil {
+		alreadyQueued := s.pending != nil
+		s.queue(patch, err)
+		if alreadyQueued {
+			if config.Debug {
+				log.Printf("Stack Instance state sync failed, queued: %v", err)
+			}
+			return
 		}
+		util.Warn("Unable to sync stack instance state to SuperHub: %v\n\tqueued for retry, replay manually with: hub api instance sync --pending %s",
+			err, s.selector)
+		if !s.retrying {
+			s.retrying = true
+			go s.retry()
+		}
+		return
+	}
+	s.synced()
+}
+
+func (s *hubSync) retry() {
+	backoff := hubSyncRetryInitial
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(backoff):
+		}
+		s.lock.Lock()
+		if s.pending == nil {
+			s.retrying = false
+			s.lock.Unlock()
+			return
+		}
+		metrics.Count("superhub.sync.retries", 1, map[string]string{"stackInstance": s.selector})
+		_, err := api.PatchStackInstance(s.selector, *s.pending, true)
+		if err == nil {
+			if config.Verbose {
+				log.Print("Queued Stack Instance state synced to SuperHub")
+			}
+			s.synced()
+			s.retrying = false
+			s.lock.Unlock()
+			return
+		}
+		if config.Debug {
+			log.Printf("Retry of Stack Instance state sync failed: %v", err)
+		}
+		s.lock.Unlock()
+		backoff *= 2
+		if backoff > hubSyncRetryMax {
+			backoff = hubSyncRetryMax
+		}
+	}
+}
+
+// queue and synced must be called with the lock held
+func (s *hubSync) queue(patch api.StackInstancePatch, syncErr error) {
+	s.pending = &patch
+	s.dequeued = false
+	err := api.EnqueueSync(s.selector, patch, syncErr)
+	if err != nil {
+		util.Warn("Unable to queue stack instance state sync: %v", err)
+	}
+}
+
+func (s *hubSync) synced() {
+	if s.pending == nil && s.dequeued {
+		return
+	}
+	s.pending = nil
+	s.dequeued = true
+	err := api.DequeueSync(s.selector)
+	if err != nil {
+		util.Warn("Unable to remove stack instance state from sync queue: %v", err)
+	}
+}
+
+// done makes the last attempt to sync queued state before exit
+func (s *hubSync) done() <-chan struct{} {
+	close(s.stop)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.pending == nil {
+		return nil
+	}
+	_, err := api.PatchStackInstance(s.selector, *s.pending, true)
+	if err != nil {
+		util.Warn("Stack instance state is not synced to SuperHub: %v\n\treplay with: hub api instance sync --pending %s",
+			err, s.selector)
+		return nil
 	}
+	s.synced()
+	return nil
 }
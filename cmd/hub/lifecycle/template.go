@@ -1,7 +1,6 @@
 package lifecycle
 
 import (
-	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -13,9 +12,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
-	gotemplate "text/template"
 
-	"github.com/Masterminds/sprig"
 	"github.com/alexkappa/mustache"
 	"golang.org/x/crypto/bcrypt"
 	"gopkg.in/yaml.v2"
@@ -49,8 +46,8 @@ type OpenErr struct {
 }
 
 func processTemplates(component *manifest.ComponentRef, templateSetup *manifest.TemplateSetup,
-	params parameters.LockedParameters, outputs parameters.CapturedOutputs,
-	dir string) []error {
+	params parameters.LockedParameters, outputs, lookupOutputs parameters.CapturedOutputs,
+	dir, stackDir string) []error {
 
 	componentName := manifest.ComponentQualifiedNameFromRef(component)
 	kv := parameters.ParametersKV(params)
@@ -139,7 +136,15 @@ func processTemplates(component *manifest.ComponentRef, templateSetup *manifest.
 		case trueMustacheKind:
 			outContent, err = processMustache(content, filename, componentName, mustacheKV)
 		case goKind:
-			outContent, err = processGo(content, filename, componentName, goKV)
+			ctx := &goTemplateContext{
+				filename:      filename,
+				componentName: componentName,
+				depends:       component.Depends,
+				kv:            kv,
+				outputs:       lookupOutputs,
+				dirs:          templateSearchPath(dir, stackDir),
+			}
+			outContent, err = processGo(content, ctx, goKV)
 		}
 		if err != nil {
 			errs = append(errs, err)
@@ -564,15 +569,14 @@ var hubGoTemplateFuncMap = map[string]interface{}{
 	"bcrypt": bcryptStr,
 }
 
-func processGo(content, filename, componentName string, kv map[string]interface{}) (string, error) {
-	tmpl, err := gotemplate.New(filepath.Base(filename)).Funcs(sprig.TxtFuncMap()).Funcs(hubGoTemplateFuncMap).Parse(content)
-	if err != nil {
-		return "", err
-	}
-	var buffer bytes.Buffer
-	err = tmpl.Execute(&buffer, kv)
-	if err != nil {
-		return "", err
+func processGo(content string, ctx *goTemplateContext, kv map[string]interface{}) (string, error) {
+	return ctx.execute(content, kv)
+}
+
+func templateSearchPath(dir, stackDir string) []string {
+	dirs := []string{dir}
+	if stackDir != "" && stackDir != dir {
+		dirs = append(dirs, stackDir)
 	}
-	return buffer.String(), nil
+	return dirs
 }
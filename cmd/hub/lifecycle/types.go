@@ -15,6 +15,8 @@ type Request struct {
 	OsEnvironmentMode          string
 	EnvironmentOverrides       string
 	ComponentsBaseDir          string
+	Workspace                  bool // render templates and run implementation in ephemeral copy of component dir
+	KeepWorkspace              bool
 	GitOutputs                 bool
 	GitOutputsStatus           bool
 	Environment                string
@@ -22,4 +24,10 @@ type Request struct {
 	Application                string
 	SyncStackInstance          bool
 	SyncSkipParametersAndOplog bool
+	Restore                    []string // backup bundle(s) deploy restores from
+	Parallel                   int      // backup
+	PreviousBackup             []string // backup: bundle(s) incremental backup is based on
+	Import                     bool     // deploy: invoke `import` verb to adopt existing resources
+	ImportOutputs              string   // import: file with raw outputs supplied by user instead of `import` verb
+	Report                     string   // deploy & undeploy, backup: write run report to .md, .html, or JUnit .xml file
 }
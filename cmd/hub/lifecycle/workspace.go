@@ -0,0 +1,109 @@
+package lifecycle
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+// prepareWorkspace copies component source dir into an ephemeral directory so that rendered
+// templates and files created by implementation do not land in the source tree.
+// This is a full copy, not a copy-on-write overlay; Git metadata is linked back to the source
+// to keep `git` commands working. The workspace root stays private (0700) as rendered templates
+// may contain secrets. The returned func removes the workspace; it is also called at util.Done()
+// in case the operation is aborted before the component finishes.
+func prepareWorkspace(componentName, dir string, keep bool) (string, func(), error) {
+	source, err := filepath.Abs(dir)
+	if err != nil {
+		return "", nil, fmt.Errorf("Unable to determine `%s` absolute path: %v", dir, err)
+	}
+	workspace, err := ioutil.TempDir("", fmt.Sprintf("hub-%s-", util.PlainName(componentName)))
+	if err != nil {
+		return "", nil, fmt.Errorf("Unable to create `%s` component workspace: %v", componentName, err)
+	}
+	err = filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(workspace, rel)
+		switch {
+		case rel == ".":
+			return nil
+		case info.Name() == ".git":
+			if err := os.Symlink(path, target); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		case info.IsDir():
+			return os.Mkdir(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		return nil
+	})
+	if err != nil {
+		os.RemoveAll(workspace)
+		return "", nil, fmt.Errorf("Unable to copy `%s` component source `%s` into workspace: %v", componentName, dir, err)
+	}
+	if config.Verbose {
+		log.Printf("Component `%s` workspace `%s`", componentName, workspace)
+	}
+	var once sync.Once
+	cleanup := func() {
+		once.Do(func() { cleanupWorkspace(componentName, workspace, keep) })
+	}
+	util.AtDone(func() <-chan struct{} {
+		cleanup()
+		return nil
+	})
+	return workspace, cleanup, nil
+}
+
+func copyFile(from, to string, mode os.FileMode) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func cleanupWorkspace(componentName, workspace string, keep bool) {
+	if keep {
+		log.Printf("Keeping component `%s` workspace `%s`", componentName, workspace)
+		return
+	}
+	if err := os.RemoveAll(workspace); err != nil {
+		util.Warn("Unable to remove component `%s` workspace `%s`: %v", componentName, workspace, err)
+	} else if config.Debug {
+		log.Printf("Removed component `%s` workspace `%s`", componentName, workspace)
+	}
+}
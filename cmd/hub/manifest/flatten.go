@@ -109,9 +109,39 @@ func ComponentQualifiedNameFromMeta(meta *Metadata) string {
 }
 
 func ComponentSourceDirNameFromRef(component *ComponentRef) string {
+	if component.Each != nil {
+		return component.Each.Of
+	}
 	return component.Name
 }
 
+// ExpandComponentsNames replaces names of `forEach` components with names of generated instances
+func ExpandComponentsNames(components []ComponentRef, names []string) []string {
+	expanded := make([]string, 0, len(names))
+	for _, name := range names {
+		instances := ComponentInstances(components, name)
+		if len(instances) > 0 {
+			expanded = append(expanded, instances...)
+		} else {
+			expanded = append(expanded, name)
+		}
+	}
+	return expanded
+}
+
+func ComponentInstances(components []ComponentRef, name string) []string {
+	instances := make([]string, 0)
+	if name == "" || ComponentRefByName(components, name) != nil {
+		return instances
+	}
+	for _, component := range components {
+		if component.Each != nil && component.Each.Of == name {
+			instances = append(instances, ComponentQualifiedNameFromRef(&component))
+		}
+	}
+	return instances
+}
+
 func ComponentSourceDirFromRef(component *ComponentRef, stackBaseDir, componentsBaseDir string) string {
 	dir := ""
 	source := component.Source
@@ -130,6 +160,8 @@ func ComponentSourceDirFromRef(component *ComponentRef, stackBaseDir, components
 			}
 		} else if source.Git.Remote != "" {
 			dir = filepath.Join(componentsBaseDir, ComponentSourceDirNameFromRef(component), source.Git.SubDir)
+		} else if source.Registry != "" {
+			dir = filepath.Join(componentsBaseDir, ComponentSourceDirNameFromRef(component))
 		}
 	}
 	if dir == "" {
@@ -22,6 +22,7 @@ func ParseManifest(manifestFilenames []string) (*Manifest, []Manifest, string, e
 	}
 
 	yamlDocuments := bytes.Split(yamlBytes, []byte("\n---\n"))
+	lineOffsets := yamlDocumentsLineOffsets(yamlDocuments)
 
 	var manifests []Manifest
 	for i, yamlDocument := range yamlDocuments {
@@ -36,6 +37,11 @@ func ParseManifest(manifestFilenames []string) (*Manifest, []Manifest, string, e
 				manifestFilename, i+1, len(yamlDocuments), err)
 		}
 		manifest.Document = string(yamlDocument)
+		mechanism := ParameterSourceStack
+		if manifest.Kind == "component" {
+			mechanism = ParameterSourceComponent
+		}
+		SetParametersSource(manifest.Parameters, mechanism, manifestFilename, manifest.Document, lineOffsets[i])
 		manifests = append(manifests, manifest)
 	}
 	if len(manifests) == 0 {
@@ -65,6 +71,8 @@ func ParseParametersManifest(manifestFilenames []string) (*ParametersManifest, s
 		if err != nil {
 			return nil, manifestFilename, fmt.Errorf("Unable to parse %s: %v", manifestFilename, err)
 		}
+		SetParametersSource(manifest.Parameters, ParameterSourceParams, manifestFilename,
+			string(yamlDocument), yamlDocumentsLineOffsets(yamlDocuments)[i])
 		if len(yamlDocuments) > i+1 {
 			util.Warn("Parameters manifest `%s` contains more than one YAML document, only first is used",
 				manifestFilename)
@@ -94,6 +102,8 @@ func GetWellKnownParametersManifest() (*WellKnownParametersManifest, error) {
 		if err != nil {
 			return nil, fmt.Errorf("Unable to parse well-known parameters: %v", err)
 		}
+		SetParametersSource(manifest.Parameters, ParameterSourceWellKnown, "meta/hub-well-known-parameters.yaml",
+			string(yamlDocument), yamlDocumentsLineOffsets(yamlDocuments)[i])
 		if len(yamlDocuments) > i+1 {
 			util.Warn("Embedded well-known parameters manifest contains more than one YAML document, only first is used")
 		}
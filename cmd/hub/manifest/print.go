@@ -15,8 +15,8 @@ func printParameters(parameters []Parameter) {
 			def = fmt.Sprintf(" [%s]", util.Wrap(util.String(p.Default)))
 		}
 		from := ""
-		if p.FromEnv != "" || p.FromFile != "" {
-			from = fmt.Sprintf(" (from:%s%s)", p.FromEnv, p.FromFile)
+		if p.FromEnv != "" || p.FromFile != "" || p.FromStackState != "" {
+			from = fmt.Sprintf(" (from:%s%s%s)", p.FromEnv, p.FromFile, p.FromStackState)
 		}
 		env := ""
 		if p.Env != "" {
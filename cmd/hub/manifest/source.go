@@ -0,0 +1,97 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	ParameterSourceComponent      = "component"
+	ParameterSourceStack          = "stack"
+	ParameterSourceParams         = "params"
+	ParameterSourceWellKnown      = "well-known"
+	ParameterSourceOverride       = "override"
+	ParameterSourceState          = "state"
+	ParameterSourceFromEnv        = "fromEnv"
+	ParameterSourceFromFile       = "fromFile"
+	ParameterSourceFromStackState = "fromStackState"
+	ParameterSourceForEach        = "forEach"
+	ParameterSourceSuperHub       = "superhub"
+	ParameterSourcePrompt         = "prompt"
+	ParameterSourceOutput         = "output"
+)
+
+type ParameterSource struct {
+	Mechanism string
+	File      string `yaml:",omitempty"`
+	Line      int    `yaml:",omitempty"`
+	Ref       string `yaml:",omitempty"` // env var name, SuperHub entity, output name
+}
+
+func (s *ParameterSource) String() string {
+	if s == nil {
+		return "(unknown)"
+	}
+	str := s.Mechanism
+	if s.File != "" {
+		if s.Line > 0 {
+			str = fmt.Sprintf("%s %s:%d", str, s.File, s.Line)
+		} else {
+			str = fmt.Sprintf("%s %s", str, s.File)
+		}
+	}
+	if s.Ref != "" {
+		str = fmt.Sprintf("%s (%s)", str, s.Ref)
+	}
+	return str
+}
+
+// SetParametersSource assigns source to parameters that has none yet.
+// Lines are located by scanning YAML document for `name:` keys in the order parameters are declared.
+func SetParametersSource(parameters []Parameter, mechanism, filename, document string, lineOffset int) {
+	lines := strings.Split(document, "\n")
+	cursor := 0
+	setParametersSource(parameters, mechanism, filename, lines, lineOffset, &cursor)
+}
+
+func setParametersSource(parameters []Parameter, mechanism, filename string, lines []string, lineOffset int, cursor *int) {
+	for i := range parameters {
+		parameter := &parameters[i]
+		line := findNameLine(lines, parameter.Name, cursor)
+		if parameter.Source == nil {
+			source := &ParameterSource{Mechanism: mechanism, File: filename}
+			if line > 0 {
+				source.Line = lineOffset + line
+			}
+			parameter.Source = source
+		}
+		if len(parameter.Parameters) > 0 {
+			setParametersSource(parameter.Parameters, mechanism, filename, lines, lineOffset, cursor)
+		}
+	}
+}
+
+func findNameLine(lines []string, name string, cursor *int) int {
+	re, err := regexp.Compile(`^\s*(-\s+)?name:\s*["']?` + regexp.QuoteMeta(name) + `["']?\s*(#.*)?$`)
+	if err != nil {
+		return 0
+	}
+	for i := *cursor; i < len(lines); i++ {
+		if re.MatchString(lines[i]) {
+			*cursor = i + 1
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func yamlDocumentsLineOffsets(documents [][]byte) []int {
+	offsets := make([]int, 0, len(documents))
+	offset := 0
+	for _, document := range documents {
+		offsets = append(offsets, offset)
+		offset += strings.Count(string(document), "\n") + 2
+	}
+	return offsets
+}
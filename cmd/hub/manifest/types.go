@@ -10,9 +10,10 @@ type Git struct {
 }
 
 type SourceLocation struct {
-	Dir string `yaml:",omitempty"`
-	S3  string `yaml:",omitempty"`
-	Git Git    `yaml:",omitempty"`
+	Dir      string `yaml:",omitempty"`
+	S3       string `yaml:",omitempty"`
+	Git      Git    `yaml:",omitempty"`
+	Registry string `yaml:",omitempty"`
 }
 
 type Metadata struct {
@@ -39,9 +40,16 @@ type ComponentRef struct {
 	Name        string
 	Source      SourceLocation    `yaml:",omitempty"`
 	Depends     []string          `yaml:",omitempty"`
+	ForEach     interface{}       `yaml:"forEach,omitempty"` // parameter name, list, or map
+	Each        *ComponentEach    `yaml:",omitempty"`        // set by elaborate on generated instances
 	Annotations map[string]string `yaml:",omitempty"`
 }
 
+type ComponentEach struct {
+	Of  string
+	Key string
+}
+
 type RequiresTuning struct {
 	Optional []string `yaml:",omitempty"`
 }
@@ -94,8 +102,12 @@ type Parameter struct {
 	FromEnv  string `yaml:"fromEnv,omitempty"`
 	FromFile string `yaml:"fromFile,omitempty"`
 
+	FromStackState string `yaml:"fromStackState,omitempty"` // s3://bucket/net.state#component:vpc.id
+
 	Env string `yaml:",omitempty"`
 
+	Source *ParameterSource `yaml:",omitempty"`
+
 	Parameters []Parameter `yaml:",omitempty"`
 }
 
@@ -112,6 +124,15 @@ type TemplateSetup struct {
 	Extra       []TemplateTarget `yaml:",omitempty"`
 }
 
+// Notification is an outbound webhook fired on lifecycle events; empty Events and Verbs match all
+type Notification struct {
+	Url      string
+	Events   []string          `yaml:",omitempty"`
+	Verbs    []string          `yaml:",omitempty"`
+	Template string            `yaml:",omitempty"`
+	Headers  map[string]string `yaml:",omitempty"`
+}
+
 type Manifest struct {
 	Version int
 	Kind    string
@@ -128,6 +149,8 @@ type Manifest struct {
 	Parameters []Parameter   `yaml:",omitempty"`
 	Templates  TemplateSetup `yaml:",omitempty"`
 
+	Notifications []Notification `yaml:",omitempty"`
+
 	Document string `yaml:",omitempty"`
 }
 
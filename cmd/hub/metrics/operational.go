@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+// Operational metrics - durations, failures, retries - are collected during lifecycle operation
+// and pushed to sinks configured in .hub-config.yaml `metrics-sinks:` at the end of operation.
+// Unlike usage metering, nothing is sent unless a sink is configured.
+
+const (
+	SinkPushgateway = "pushgateway"
+	SinkStatsd      = "statsd"
+	SinkOtlp        = "otlp"
+
+	kindCount  = "count"
+	kindTiming = "timing"
+)
+
+type Sink struct {
+	Kind    string
+	Url     string            // pushgateway, otlp
+	Address string            // statsd host:port
+	Prefix  string            // statsd metric name prefix, default hub.
+	Job     string            // pushgateway job, default hub
+	Tags    map[string]string // added to every metric
+	Headers map[string]string // otlp
+}
+
+// Sinks are set from `metrics-sinks:` in .hub-config.yaml
+var Sinks []Sink
+
+type point struct {
+	name      string
+	kind      string
+	value     float64
+	tags      map[string]string
+	timestamp time.Time
+}
+
+var (
+	pointsLock sync.Mutex
+	collecting bool
+	points     []point
+)
+
+// StartOperation starts collection of operational metrics to be flushed at util.Done()
+func StartOperation() {
+	if len(Sinks) == 0 {
+		return
+	}
+	pointsLock.Lock()
+	collecting = true
+	pointsLock.Unlock()
+	util.AtDone(flushSinks)
+}
+
+func Count(name string, value int64, tags map[string]string) {
+	record(point{name: name, kind: kindCount, value: float64(value), tags: tags})
+}
+
+func Timing(name string, duration time.Duration, tags map[string]string) {
+	record(point{name: name, kind: kindTiming, value: duration.Seconds(), tags: tags})
+}
+
+func record(p point) {
+	pointsLock.Lock()
+	defer pointsLock.Unlock()
+	if !collecting {
+		return
+	}
+	p.timestamp = time.Now()
+	points = append(points, p)
+}
+
+func flushSinks() <-chan struct{} {
+	pointsLock.Lock()
+	batch := points
+	points = nil
+	collecting = false
+	pointsLock.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+	for _, sink := range Sinks {
+		var err error
+		sinkPoints := withTags(batch, sink.Tags)
+		switch sink.Kind {
+		case SinkPushgateway:
+			err = pushToPushgateway(&sink, sinkPoints)
+		case SinkStatsd:
+			err = sendToStatsd(&sink, sinkPoints)
+		case SinkOtlp:
+			err = exportToOtlp(&sink, sinkPoints)
+		default:
+			err = fmt.Errorf("unknown kind `%s`; supported: %s, %s, %s", sink.Kind, SinkPushgateway, SinkStatsd, SinkOtlp)
+		}
+		if err != nil {
+			util.Warn("Unable to send metrics to %s sink: %v", sink.Kind, err)
+		} else if config.Debug {
+			log.Printf("Sent %d metrics to %s sink", len(sinkPoints), sink.Kind)
+		}
+	}
+	return nil
+}
+
+func withTags(batch []point, tags map[string]string) []point {
+	if len(tags) == 0 {
+		return batch
+	}
+	tagged := make([]point, 0, len(batch))
+	for _, p := range batch {
+		merged := make(map[string]string, len(tags)+len(p.tags))
+		for k, v := range tags {
+			merged[k] = v
+		}
+		for k, v := range p.tags {
+			merged[k] = v
+		}
+		p.tags = merged
+		tagged = append(tagged, p)
+	}
+	return tagged
+}
+
+func sortedTagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+)
+
+const (
+	otlpMetricsPath = "/v1/metrics"
+	// AGGREGATION_TEMPORALITY_DELTA
+	otlpDelta = 1
+)
+
+type otlpKeyValue struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+type otlpDataPoint struct {
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	TimeUnixNano string         `json:"timeUnixNano"`
+	AsDouble     *float64       `json:"asDouble,omitempty"`
+	AsInt        string         `json:"asInt,omitempty"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+	AggregationTemporality int             `json:"aggregationTemporality"`
+	IsMonotonic            bool            `json:"isMonotonic"`
+}
+
+type otlpMetric struct {
+	Name  string     `json:"name"`
+	Unit  string     `json:"unit,omitempty"`
+	Gauge *otlpGauge `json:"gauge,omitempty"`
+	Sum   *otlpSum   `json:"sum,omitempty"`
+}
+
+// OTLP/HTTP JSON encoding; timings are gauges in seconds, counts are delta sums
+func exportToOtlp(sink *Sink, points []point) error {
+	if sink.Url == "" {
+		return fmt.Errorf("no `url` set")
+	}
+	names := make([]string, 0)
+	metrics := make(map[string]*otlpMetric)
+	for _, p := range points {
+		name := "hub." + p.name
+		metric, exist := metrics[name]
+		if !exist {
+			metric = &otlpMetric{Name: name}
+			if p.kind == kindTiming {
+				metric.Unit = "s"
+				metric.Gauge = &otlpGauge{}
+			} else {
+				metric.Unit = "1"
+				metric.Sum = &otlpSum{AggregationTemporality: otlpDelta, IsMonotonic: true}
+			}
+			metrics[name] = metric
+			names = append(names, name)
+		}
+		dataPoint := otlpDataPoint{TimeUnixNano: strconv.FormatInt(p.timestamp.UnixNano(), 10)}
+		for _, k := range sortedTagKeys(p.tags) {
+			dataPoint.Attributes = append(dataPoint.Attributes,
+				otlpKeyValue{Key: k, Value: map[string]string{"stringValue": p.tags[k]}})
+		}
+		if metric.Gauge != nil {
+			value := p.value
+			dataPoint.AsDouble = &value
+			metric.Gauge.DataPoints = append(metric.Gauge.DataPoints, dataPoint)
+		} else {
+			dataPoint.AsInt = strconv.FormatInt(int64(p.value), 10)
+			metric.Sum.DataPoints = append(metric.Sum.DataPoints, dataPoint)
+		}
+	}
+	ordered := make([]*otlpMetric, 0, len(names))
+	for _, name := range names {
+		ordered = append(ordered, metrics[name])
+	}
+	body := map[string]interface{}{
+		"resourceMetrics": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []otlpKeyValue{{Key: "service.name", Value: map[string]string{"stringValue": "hub-cli"}}},
+				},
+				"scopeMetrics": []interface{}{
+					map[string]interface{}{
+						"scope":   map[string]string{"name": "github.com/agilestacks/hub"},
+						"metrics": ordered,
+					},
+				},
+			},
+		},
+	}
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	addr := strings.TrimSuffix(sink.Url, "/")
+	if !strings.HasSuffix(addr, otlpMetricsPath) {
+		addr += otlpMetricsPath
+	}
+	req, err := http.NewRequest("POST", addr, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-type", "application/json")
+	for k, v := range sink.Headers {
+		req.Header.Set(k, v)
+	}
+	if config.Trace {
+		log.Printf(">>> %s %s", req.Method, req.URL.String())
+		log.Printf("%s", string(reqBody))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error during HTTP request: %v", err)
+	}
+	resp.Body.Close()
+	if config.Trace {
+		log.Printf("<<< %s %s: %s", req.Method, req.URL.String(), resp.Status)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("OTLP endpoint returned HTTP status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+)
+
+// Prometheus text exposition format; points with same name and labels are summed up
+func pushToPushgateway(sink *Sink, points []point) error {
+	if sink.Url == "" {
+		return fmt.Errorf("no `url` set")
+	}
+	job := sink.Job
+	if job == "" {
+		job = "hub"
+	}
+
+	type series struct {
+		labels string
+		value  float64
+	}
+	names := make([]string, 0)
+	byName := make(map[string][]*series)
+	for _, p := range points {
+		name := "hub_" + sanitizeName(p.name)
+		if p.kind == kindTiming {
+			name += "_seconds"
+		} else {
+			name += "_total"
+		}
+		labels := make([]string, 0, len(p.tags))
+		for _, k := range sortedTagKeys(p.tags) {
+			labels = append(labels, fmt.Sprintf("%s=%s", sanitizeName(k), strconv.Quote(p.tags[k])))
+		}
+		labelsStr := strings.Join(labels, ",")
+		all, exist := byName[name]
+		if !exist {
+			names = append(names, name)
+		}
+		found := false
+		for _, s := range all {
+			if s.labels == labelsStr {
+				s.value += p.value
+				found = true
+				break
+			}
+		}
+		if !found {
+			byName[name] = append(all, &series{labels: labelsStr, value: p.value})
+		}
+	}
+
+	var body bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&body, "# TYPE %s gauge\n", name)
+		for _, s := range byName[name] {
+			fmt.Fprintf(&body, "%s{%s} %s\n", name, s.labels, strconv.FormatFloat(s.value, 'g', -1, 64))
+		}
+	}
+
+	addr := fmt.Sprintf("%s/metrics/job/%s", strings.TrimSuffix(sink.Url, "/"), url.PathEscape(job))
+	req, err := http.NewRequest("POST", addr, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-type", "text/plain; version=0.0.4")
+	if config.Trace {
+		log.Printf(">>> %s %s", req.Method, req.URL.String())
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error during HTTP request: %v", err)
+	}
+	resp.Body.Close()
+	if config.Trace {
+		log.Printf("<<< %s %s: %s", req.Method, req.URL.String(), resp.Status)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Pushgateway returned HTTP status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+)
+
+const statsdMaxDatagram = 1400
+
+// StatsD line protocol with DogStatsD tags extension: hub.component.duration:1234|ms|#stack:s,component:c
+func sendToStatsd(sink *Sink, points []point) error {
+	if sink.Address == "" {
+		return fmt.Errorf("no `address` set")
+	}
+	prefix := sink.Prefix
+	if prefix == "" {
+		prefix = "hub."
+	}
+	conn, err := net.Dial("udp", sink.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var datagram bytes.Buffer
+	send := func() error {
+		if datagram.Len() == 0 {
+			return nil
+		}
+		if config.Trace {
+			log.Printf(">>> StatsD %s\n%s", sink.Address, datagram.String())
+		}
+		_, err := conn.Write(datagram.Bytes())
+		datagram.Reset()
+		return err
+	}
+	for _, p := range points {
+		var line string
+		if p.kind == kindTiming {
+			line = fmt.Sprintf("%s%s:%d|ms", prefix, p.name, int64(p.value*1000))
+		} else {
+			line = fmt.Sprintf("%s%s:%d|c", prefix, p.name, int64(p.value))
+		}
+		if len(p.tags) > 0 {
+			tags := make([]string, 0, len(p.tags))
+			for _, k := range sortedTagKeys(p.tags) {
+				tags = append(tags, fmt.Sprintf("%s:%s", k, p.tags[k]))
+			}
+			line += "|#" + strings.Join(tags, ",")
+		}
+		if datagram.Len() > 0 && datagram.Len()+1+len(line) > statsdMaxDatagram {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+		if datagram.Len() > 0 {
+			datagram.WriteByte('\n')
+		}
+		datagram.WriteString(line)
+	}
+	return send()
+}
@@ -0,0 +1,464 @@
+package mockapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	gosocketio "github.com/arkadijs/golang-socketio"
+	gosocketiotransport "github.com/arkadijs/golang-socketio/transport"
+	"github.com/google/uuid"
+
+	"github.com/agilestacks/hub/cmd/hub/api"
+	"github.com/agilestacks/hub/cmd/hub/config"
+)
+
+const (
+	authPrefix = "/auth/api/v1/"
+	hubPrefix  = "/hub/api/v1/"
+
+	accessTokenLifetime = time.Hour
+)
+
+var wsEntities = map[string]string{
+	"environments": "environment",
+	"instances":    "stackInstance",
+	"templates":    "stackTemplate",
+}
+
+var instanceVerbs = map[string]string{
+	"deploy":   "deployed",
+	"undeploy": "undeployed",
+	"backup":   "deployed",
+}
+
+type server struct {
+	store *store
+	ws    *gosocketio.Server
+
+	tokensLock sync.Mutex
+	tokens     map[string]time.Time // access token -> expiry
+}
+
+// Serve runs in-memory SuperHub API with the subset of endpoints used by Hub CLI: login, environments,
+// stack instances, templates, their secrets and parameters, and socket.io stream of changes.
+// Data is loaded from YAML file, and saved back on changes if persist is set.
+func Serve(listen, filename string, persist bool) {
+	st, err := loadStore(filename, persist)
+	if err != nil {
+		log.Fatalf("Unable to load mock API data: %v", err)
+	}
+	s := &server{
+		store:  st,
+		ws:     gosocketio.NewServer(gosocketiotransport.GetDefaultWebsocketTransport()),
+		tokens: make(map[string]time.Time),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(authPrefix, s.handleAuth)
+	mux.HandleFunc(hubPrefix, s.authorized(s.handleHub))
+	mux.Handle("/hub/socket.io/", s.authorized(s.ws.ServeHTTP))
+
+	baseUrl := listen
+	if strings.HasPrefix(baseUrl, ":") {
+		baseUrl = "localhost" + baseUrl
+	}
+	fmt.Printf(`# eval this in your shell
+export HUB_API=http://%s
+export HUB_TOKEN=mock
+`, baseUrl)
+	log.Fatal(http.ListenAndServe(listen, mux))
+}
+
+func (s *server) handleAuth(w http.ResponseWriter, r *http.Request) {
+	if config.Debug {
+		log.Printf("%s %s", r.Method, r.URL.Path)
+	}
+	switch strings.TrimPrefix(r.URL.Path, authPrefix) {
+	case "users/credentials/login-token":
+		var req api.AuthUserPass
+		if !decode(w, r, &req) {
+			return
+		}
+		if len(s.store.data.Users) > 0 {
+			found := false
+			for _, user := range s.store.data.Users {
+				if user.Username == req.Username {
+					found = true
+					if user.Password != req.Password {
+						writeError(w, http.StatusUnauthorized, "Bad password")
+						return
+					}
+				}
+			}
+			if !found {
+				writeError(w, http.StatusNotFound, "No user found")
+				return
+			}
+		}
+		writeJson(w, http.StatusOK, api.LoginTokenResponse{LoginToken: uuid.New().String()})
+
+	case "signin":
+		var req api.AuthLoginToken
+		if !decode(w, r, &req) {
+			return
+		}
+		if req.LoginToken == "" {
+			writeError(w, http.StatusUnauthorized, "No login token")
+			return
+		}
+		writeJson(w, http.StatusOK, s.issueTokens())
+
+	case "refresh":
+		var req api.SigninResponse
+		if !decode(w, r, &req) {
+			return
+		}
+		if req.RefreshToken == "" {
+			writeError(w, http.StatusUnauthorized, "No refresh token")
+			return
+		}
+		writeJson(w, http.StatusOK, s.issueTokens())
+
+	case "authenticated-ping":
+		exp, valid := s.validToken(r)
+		if !valid {
+			writeError(w, http.StatusUnauthorized, "Bad access token")
+			return
+		}
+		writeJson(w, http.StatusOK, api.AuthPingResponse{Exp: exp.Unix()})
+
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (s *server) issueTokens() api.SigninResponse {
+	exp := time.Now().Add(accessTokenLifetime)
+	token := uuid.New().String()
+	s.tokensLock.Lock()
+	s.tokens[token] = exp
+	s.tokensLock.Unlock()
+	return api.SigninResponse{AccessToken: token, RefreshToken: uuid.New().String(), Exp: exp.Unix()}
+}
+
+func (s *server) validToken(r *http.Request) (time.Time, bool) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("accessToken")
+	}
+	s.tokensLock.Lock()
+	defer s.tokensLock.Unlock()
+	exp, exist := s.tokens[token]
+	return exp, exist && exp.After(time.Now())
+}
+
+func (s *server) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, valid := s.validToken(r); !valid {
+			writeError(w, http.StatusUnauthorized, "Bad access token")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// handleHub serves <kind>, <kind>/<id>, <kind>/<id>/secrets[/<secretId>], and instances/<id>/<verb>
+func (s *server) handleHub(w http.ResponseWriter, r *http.Request) {
+	if config.Debug {
+		log.Printf("%s %s", r.Method, r.URL.String())
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, hubPrefix), "/"), "/")
+	kind := parts[0]
+
+	st := s.store
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	if st.collection(kind) == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Mock API does not implement `%s`", kind))
+		return
+	}
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			writeJson(w, http.StatusOK, s.list(kind, r))
+		case http.MethodPost:
+			var entity Entity
+			if !decode(w, r, &entity) {
+				return
+			}
+			s.storeSecretParameters(entity)
+			s.create(kind, entity)
+			s.changed(w, kind, entity, "create", http.StatusCreated)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+		return
+	}
+
+	entity, index := st.find(kind, parts[1])
+	if entity == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No %s `%s`", wsEntities[kind], parts[1]))
+		return
+	}
+
+	if len(parts) == 2 {
+		switch r.Method {
+		case http.MethodGet:
+			writeJson(w, http.StatusOK, entity)
+		case http.MethodPatch, http.MethodPut:
+			var change Entity
+			if !decode(w, r, &change) {
+				return
+			}
+			s.storeSecretParameters(change)
+			patchEntity(entity, change, r.URL.Query().Get("replace") != "" || r.Method == http.MethodPut)
+			s.changed(w, kind, entity, "update", http.StatusOK)
+		case http.MethodDelete:
+			entities := st.collection(kind)
+			*entities = append((*entities)[:index], (*entities)[index+1:]...)
+			s.changed(w, kind, entity, "delete", http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+		return
+	}
+
+	switch {
+	case parts[2] == "secrets" && len(parts) == 3 && r.Method == http.MethodPost:
+		var values map[string]string
+		if !decode(w, r, &values) {
+			return
+		}
+		id := uuid.New().String()
+		st.data.Secrets[id] = values
+		setParameter(entity, Entity{
+			"name":      values["name"],
+			"component": values["component"],
+			"kind":      "secret",
+			"value":     Entity{"secret": id, "kind": values["kind"]},
+		})
+		err := st.save()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJson(w, http.StatusCreated, api.CreateSecretResponse{Id: id})
+
+	case parts[2] == "secrets" && len(parts) == 4 && r.Method == http.MethodGet:
+		secret, exist := st.data.Secrets[parts[3]]
+		if !exist {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("No secret `%s`", parts[3]))
+			return
+		}
+		writeJson(w, http.StatusOK, secret)
+
+	case kind == "instances" && len(parts) == 3 && r.Method == http.MethodPost && instanceVerbs[parts[2]] != "":
+		jobId := uuid.New().String()
+		if r.URL.Query().Get("dryRun") == "" {
+			go s.runVerb(parts[1], parts[2])
+		}
+		writeJson(w, http.StatusAccepted, api.StackInstanceLifecycleResponse{Id: parts[1], JobId: jobId})
+
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (s *server) list(kind string, r *http.Request) []Entity {
+	query := r.URL.Query()
+	list := make([]Entity, 0)
+	for _, entity := range *s.store.collection(kind) {
+		if name := query.Get("name"); name != "" && str(entity["name"]) != name {
+			continue
+		}
+		if domain := query.Get("domain"); domain != "" && str(entity["domain"]) != domain {
+			continue
+		}
+		if q := query.Get("query"); q != "" &&
+			!strings.Contains(str(entity["name"]), q) && !strings.Contains(str(entity["domain"]), q) {
+			continue
+		}
+		if env := query.Get("environment"); env != "" {
+			ref, _ := entity["environment"].(Entity)
+			if ref == nil || str(ref["id"]) != env {
+				continue
+			}
+		}
+		list = append(list, entity)
+	}
+	return list
+}
+
+// create assigns an Id and expands references to environment and template
+func (s *server) create(kind string, entity Entity) {
+	entity["id"] = s.store.nextId(kind)
+	if kind == "instances" {
+		for field, refKind := range map[string]string{"environment": "environments", "template": "templates"} {
+			if id, ok := entity[field].(string); ok {
+				ref := Entity{"id": id}
+				if target, _ := s.store.find(refKind, id); target != nil {
+					ref["name"] = target["name"]
+				}
+				entity[field] = ref
+			}
+		}
+		if str(entity["domain"]) == "" {
+			entity["domain"] = entity["name"]
+		}
+		if entity["status"] == nil {
+			entity["status"] = Entity{"status": "configured"}
+		}
+	}
+	entities := s.store.collection(kind)
+	*entities = append(*entities, entity)
+}
+
+// changed must be called with the lock held
+func (s *server) changed(w http.ResponseWriter, kind string, entity Entity, action string, status int) {
+	err := s.store.save()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.broadcast(api.WsMessage{
+		Id:      str(entity["id"]),
+		Entity:  wsEntities[kind],
+		Name:    str(entity["name"]),
+		Action:  action,
+		Success: true,
+	})
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	writeJson(w, status, entity)
+}
+
+// runVerb simulates automation task by streaming a log line and updating instance status
+func (s *server) runVerb(id, verb string) {
+	st := s.store
+	st.lock.Lock()
+	entity, _ := st.find("instances", id)
+	if entity == nil {
+		st.lock.Unlock()
+		return
+	}
+	name := str(entity["name"])
+	st.lock.Unlock()
+
+	msg := api.WsMessage{Id: id, Entity: "stackInstance", Name: name, Action: verb + "-update", Success: true,
+		Logs: fmt.Sprintf("Mock %s of %s\n", verb, name)}
+	time.Sleep(500 * time.Millisecond) // let the client connect to the stream
+	s.broadcast(msg)
+
+	st.lock.Lock()
+	if entity, _ := st.find("instances", id); entity != nil {
+		status, _ := entity["status"].(Entity)
+		if status == nil {
+			status = make(Entity)
+		}
+		status["status"] = instanceVerbs[verb]
+		entity["status"] = status
+		err := st.save()
+		if err != nil {
+			log.Printf("Unable to save mock API data: %v", err)
+		}
+	}
+	st.lock.Unlock()
+
+	msg.Action = verb
+	msg.Logs = ""
+	s.broadcast(msg)
+}
+
+func (s *server) broadcast(msg api.WsMessage) {
+	s.ws.BroadcastToAll("change", msg)
+}
+
+// storeSecretParameters replaces inline secret parameter values, as sent by sync, with references to secrets
+func (s *server) storeSecretParameters(entity Entity) {
+	params, _ := entity["parameters"].([]interface{})
+	for _, param := range params {
+		p, ok := param.(Entity)
+		if !ok || str(p["kind"]) != "secret" {
+			continue
+		}
+		value, ok := p["value"].(Entity)
+		if !ok || value["secret"] != nil {
+			continue
+		}
+		secret := make(map[string]string, len(value)+2)
+		for key, v := range value {
+			secret[key] = str(v)
+		}
+		secret["name"] = str(p["name"])
+		if component := str(p["component"]); component != "" {
+			secret["component"] = component
+		}
+		id := uuid.New().String()
+		s.store.data.Secrets[id] = secret
+		p["value"] = Entity{"secret": id, "kind": secret["kind"]}
+	}
+}
+
+// patchEntity replaces top-level fields; parameters are merged by name and component unless replace is set
+func patchEntity(entity, change Entity, replace bool) {
+	for key, value := range change {
+		if key == "id" {
+			continue
+		}
+		if key == "parameters" && !replace {
+			if params, ok := value.([]interface{}); ok {
+				for _, param := range params {
+					if p, ok := param.(Entity); ok {
+						setParameter(entity, p)
+					}
+				}
+				continue
+			}
+		}
+		entity[key] = value
+	}
+}
+
+func setParameter(entity, param Entity) {
+	params, _ := entity["parameters"].([]interface{})
+	for i, existing := range params {
+		if p, ok := existing.(Entity); ok &&
+			str(p["name"]) == str(param["name"]) && str(p["component"]) == str(param["component"]) {
+			params[i] = param
+			return
+		}
+	}
+	entity["parameters"] = append(params, param)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, value interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(value)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Unable to decode request: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, status int, detail string) {
+	writeJson(w, status, api.ApiErrors{Errors: []api.ApiError{{Type: "mock", Detail: detail}}})
+}
+
+func writeJson(w http.ResponseWriter, status int, value interface{}) {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		status = http.StatusInternalServerError
+		bytes = []byte(fmt.Sprintf(`{"errors":[{"type":"mock","detail":%q}]}`, err.Error()))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(bytes)
+}
@@ -0,0 +1,137 @@
+package mockapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Entity is a JSON object as served by the API; only a few fields are interpreted by the mock
+type Entity = map[string]interface{}
+
+type User struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type Data struct {
+	Users        []User                       `yaml:"users,omitempty"`
+	Environments []Entity                     `yaml:"environments,omitempty"`
+	Instances    []Entity                     `yaml:"instances,omitempty"`
+	Templates    []Entity                     `yaml:"templates,omitempty"`
+	Secrets      map[string]map[string]string `yaml:"secrets,omitempty"`
+}
+
+type store struct {
+	lock     sync.Mutex
+	data     Data
+	filename string
+	persist  bool
+}
+
+func loadStore(filename string, persist bool) (*store, error) {
+	s := &store{filename: filename, persist: persist}
+	if filename != "" {
+		bytes, err := ioutil.ReadFile(filename)
+		if err != nil && !(os.IsNotExist(err) && persist) {
+			return nil, err
+		}
+		if err == nil {
+			err = yaml.Unmarshal(bytes, &s.data)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to parse `%s`: %v", filename, err)
+			}
+		}
+	}
+	for _, entities := range []*[]Entity{&s.data.Environments, &s.data.Instances, &s.data.Templates} {
+		for i, entity := range *entities {
+			(*entities)[i] = jsonCompatible(entity).(Entity)
+		}
+	}
+	if s.data.Secrets == nil {
+		s.data.Secrets = make(map[string]map[string]string)
+	}
+	return s, nil
+}
+
+// save must be called with the lock held
+func (s *store) save() error {
+	if !s.persist || s.filename == "" {
+		return nil
+	}
+	bytes, err := yaml.Marshal(&s.data)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.filename, bytes, 0644)
+}
+
+func (s *store) collection(kind string) *[]Entity {
+	switch kind {
+	case "environments":
+		return &s.data.Environments
+	case "instances":
+		return &s.data.Instances
+	case "templates":
+		return &s.data.Templates
+	}
+	return nil
+}
+
+func (s *store) nextId(kind string) string {
+	max := 0
+	for _, entity := range *s.collection(kind) {
+		if id, err := strconv.Atoi(str(entity["id"])); err == nil && id > max {
+			max = id
+		}
+	}
+	return strconv.Itoa(max + 1)
+}
+
+func (s *store) find(kind, id string) (Entity, int) {
+	for i, entity := range *s.collection(kind) {
+		if str(entity["id"]) == id {
+			return entity, i
+		}
+	}
+	return nil, -1
+}
+
+// jsonCompatible converts YAML maps with interface{} keys so that value could be marshalled to JSON
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[fmt.Sprintf("%v", key)] = jsonCompatible(val)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[key] = jsonCompatible(val)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, val := range v {
+			list = append(list, jsonCompatible(val))
+		}
+		return list
+	}
+	return value
+}
+
+func str(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
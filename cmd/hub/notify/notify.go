@@ -0,0 +1,244 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/manifest"
+	"github.com/agilestacks/hub/cmd/hub/parameters"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+const (
+	EventStart            = "start"
+	EventFinish           = "finish"
+	EventComponentFailure = "component-failure"
+
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// ConfigNotifications are set from `notifications:` in .hub-config.yaml and fire for every stack
+var ConfigNotifications []manifest.Notification
+
+var httpClient = util.RobustHttpClient(0, false)
+
+type Output struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+	Brief string      `json:"brief,omitempty"`
+}
+
+// Event is sent as JSON payload, or is the data of the notification template
+type Event struct {
+	Event            string    `json:"event"`
+	Verb             string    `json:"verb"`
+	Stack            string    `json:"stack"`
+	StackInstance    string    `json:"stackInstance,omitempty"`
+	OperationId      string    `json:"operationId,omitempty"`
+	Timestamp        time.Time `json:"timestamp"`
+	Result           string    `json:"result,omitempty"`
+	Status           string    `json:"status,omitempty"`
+	Message          string    `json:"message,omitempty"`
+	Components       []string  `json:"components,omitempty"`
+	Component        string    `json:"component,omitempty"`
+	FailedComponents []string  `json:"failedComponents,omitempty"`
+	Outputs          []Output  `json:"outputs,omitempty"`
+}
+
+type Notifier struct {
+	notifications []manifest.Notification
+	base          Event
+	failed        []string
+	finished      bool
+	// Status returns current stack status and message to report if the operation is aborted
+	Status func() (string, string)
+}
+
+// New returns nil if there are no notifications configured; all methods are nil-safe
+func New(verb, stack, stackInstance, operationId string, notifications []manifest.Notification) *Notifier {
+	all := make([]manifest.Notification, 0, len(ConfigNotifications)+len(notifications))
+	all = append(all, ConfigNotifications...)
+	all = append(all, notifications...)
+	if len(all) == 0 {
+		return nil
+	}
+	n := &Notifier{
+		notifications: all,
+		base: Event{
+			Verb:          verb,
+			Stack:         stack,
+			StackInstance: stackInstance,
+			OperationId:   operationId,
+		},
+	}
+	util.AtDone(n.done)
+	return n
+}
+
+func (n *Notifier) Start(components []string) {
+	if n == nil {
+		return
+	}
+	event := n.event(EventStart)
+	event.Components = components
+	n.send(event)
+}
+
+func (n *Notifier) ComponentFailed(component, message string) {
+	if n == nil {
+		return
+	}
+	if !util.Contains(n.failed, component) {
+		n.failed = append(n.failed, component)
+	}
+	event := n.event(EventComponentFailure)
+	event.Component = component
+	event.Result = ResultFailure
+	event.Message = message
+	event.FailedComponents = n.failed
+	n.send(event)
+}
+
+func (n *Notifier) Finish(status, message string, outputs []parameters.ExpandedOutput) {
+	if n == nil || n.finished {
+		return
+	}
+	n.finished = true
+	event := n.event(EventFinish)
+	event.Result = ResultSuccess
+	if len(n.failed) > 0 {
+		event.Result = ResultFailure
+	}
+	event.Status = status
+	event.Message = message
+	event.FailedComponents = n.failed
+	event.Outputs = filterOutSecretOutputs(outputs)
+	n.send(event)
+}
+
+// done reports operation aborted by a fatal error or interrupt
+func (n *Notifier) done() <-chan struct{} {
+	if n.finished {
+		return nil
+	}
+	n.finished = true
+	event := n.event(EventFinish)
+	event.Result = ResultFailure
+	if n.Status != nil {
+		event.Status, event.Message = n.Status()
+	}
+	event.FailedComponents = n.failed
+	n.send(event)
+	return nil
+}
+
+func (n *Notifier) event(kind string) Event {
+	event := n.base
+	event.Event = kind
+	event.Timestamp = time.Now()
+	return event
+}
+
+func (n *Notifier) send(event Event) {
+	for _, notification := range n.notifications {
+		if !matches(&notification, &event) {
+			continue
+		}
+		err := post(&notification, &event)
+		if err != nil {
+			util.Warn("Unable to send `%s` notification to %s: %v", event.Event, host(notification.Url), err)
+		} else if config.Debug {
+			log.Printf("Sent `%s` notification to %s", event.Event, host(notification.Url))
+		}
+	}
+}
+
+func matches(notification *manifest.Notification, event *Event) bool {
+	if len(notification.Verbs) > 0 && !util.Contains(notification.Verbs, event.Verb) {
+		return false
+	}
+	if len(notification.Events) == 0 {
+		return true
+	}
+	for _, kind := range notification.Events {
+		if kind == event.Event ||
+			(event.Event == EventFinish && kind == event.Result) {
+			return true
+		}
+	}
+	return false
+}
+
+func post(notification *manifest.Notification, event *Event) error {
+	payload, err := render(notification, event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", os.ExpandEnv(notification.Url), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range notification.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%d HTTP", resp.StatusCode)
+	}
+	return nil
+}
+
+func render(notification *manifest.Notification, event *Event) ([]byte, error) {
+	if notification.Template == "" {
+		return json.Marshal(event)
+	}
+	tmpl, err := template.New("notification").Funcs(sprig.TxtFuncMap()).Parse(notification.Template)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse template: %v", err)
+	}
+	var payload bytes.Buffer
+	err = tmpl.Execute(&payload, event)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to render template: %v", err)
+	}
+	return payload.Bytes(), nil
+}
+
+func filterOutSecretOutputs(outputs []parameters.ExpandedOutput) []Output {
+	filtered := make([]Output, 0, len(outputs))
+	for _, o := range outputs {
+		if !strings.HasPrefix(o.Kind, "secret") {
+			filtered = append(filtered, Output{Name: o.Name, Value: o.Value, Brief: o.Brief})
+		}
+	}
+	return filtered
+}
+
+// webhook URLs often carry a token, thus only host is logged
+func host(rawUrl string) string {
+	u, err := url.Parse(os.ExpandEnv(rawUrl))
+	if err != nil || u.Host == "" {
+		return "(invalid URL)"
+	}
+	return u.Host
+}
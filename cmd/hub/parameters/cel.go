@@ -16,7 +16,7 @@ var (
 )
 
 func init() {
-	env, err := cel.NewEnv()
+	env, err := NewCelEnv()
 	if err != nil {
 		log.Fatalf("Unable to init CEL runtime: %v", err)
 	}
@@ -24,20 +24,24 @@ func init() {
 }
 
 func CelEval(expr string, component string, depends []string, kv map[string]interface{}) (string, error) {
-	ast, issues := CEL.Parse(expr)
-	if issues != nil && issues.Err() != nil {
-		return "(parse error)", fmt.Errorf("CEL parse error: %v", issues.Err())
+	ast, err := CelParse(CEL, expr)
+	if err != nil {
+		return "(parse error)", fmt.Errorf("CEL parse error: %v", err)
 	}
-	program, err := CEL.Program(ast)
+	activation := newCelActivation(component, depends, kv)
+	program, err := CEL.Program(ast, CelProgramOptions(activation))
 	if err != nil {
 		return "(program error)", fmt.Errorf("CEL program construction error `%s`: %v", expr, err)
 	}
-	activation := newCelActivation(component, depends, kv)
 	out, _, err := program.Eval(activation)
 	if err != nil {
 		return "(eval error)", fmt.Errorf("CEL evaluation error `%s`: %v", expr, err)
 	}
-	return fmt.Sprintf("%+v", out), nil
+	str, err := CelString(out)
+	if err != nil {
+		return "(eval error)", fmt.Errorf("CEL evaluation error `%s`: %v", expr, err)
+	}
+	return str, nil
 }
 
 type celActivation struct {
@@ -0,0 +1,590 @@
+package parameters
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/ext"
+	"github.com/google/cel-go/interpreter"
+	"github.com/google/cel-go/interpreter/functions"
+	"github.com/google/cel-go/parser"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"gopkg.in/yaml.v2"
+)
+
+// Hub CEL library:
+//   strings      ext.Strings() + s.lower(), s.upper(), list.join(sep)
+//   encoding     base64.encode(s), base64.decode(s), json.encode(v), json.decode(s), yaml.decode(s)
+//   net          net.cidrSubnet(cidr, newbits, num), net.cidrHost(cidr, num)
+//   semver       semver.compare(a, b), semver.matches(version, constraint)
+//   parameters   default(name, fallback), has(name), lookup(output, component)
+//
+// Parameter names are qualified, ie. `dns.domain`, thus default() and has() are macros
+// that pass the name to be resolved instead of failing evaluation on unknown variable.
+// has() on comprehension variables is a regular CEL presence test.
+
+const (
+	celHas     = "hub.has"
+	celDefault = "hub.default"
+	celLookup  = "lookup"
+)
+
+func NewCelEnv() (*cel.Env, error) {
+	return cel.NewEnv(ext.Strings(), cel.Lib(hubCelLib{}))
+}
+
+// CelProgramOptions binds default(), has(), and lookup() to the activation
+// the expression is evaluated with.
+func CelProgramOptions(activation interpreter.Activation) cel.ProgramOption {
+	return cel.Functions(
+		&functions.Overload{
+			Operator: celHas,
+			Unary: func(name ref.Val) ref.Val {
+				str, ok := name.(types.String)
+				if !ok {
+					return types.MaybeNoSuchOverloadErr(name)
+				}
+				_, exist := resolveQualifiedName(activation, string(str))
+				return types.Bool(exist)
+			},
+		},
+		&functions.Overload{
+			Operator: celDefault,
+			Binary: func(name, fallback ref.Val) ref.Val {
+				str, ok := name.(types.String)
+				if !ok {
+					return types.MaybeNoSuchOverloadErr(name)
+				}
+				value, exist := resolveQualifiedName(activation, string(str))
+				if !exist || value == nil || value == "" {
+					return fallback
+				}
+				return types.DefaultTypeAdapter.NativeToValue(value)
+			},
+		},
+		&functions.Overload{
+			Operator: celLookup,
+			Binary: func(output, component ref.Val) ref.Val {
+				name, ok1 := output.(types.String)
+				comp, ok2 := component.(types.String)
+				if !ok1 || !ok2 {
+					return types.NoSuchOverloadErr()
+				}
+				qName := OutputQualifiedName(string(name), string(comp))
+				value, exist := activation.ResolveName(qName)
+				if !exist {
+					return types.NewErr("no such output `%s`", qName)
+				}
+				return types.DefaultTypeAdapter.NativeToValue(value)
+			},
+		})
+}
+
+// resolveQualifiedName looks for `a.b.c` as is, then descends into `a.b` map value, etc.
+func resolveQualifiedName(activation interpreter.Activation, name string) (interface{}, bool) {
+	if value, exist := activation.ResolveName(name); exist {
+		return value, true
+	}
+	parts := strings.Split(name, ".")
+	for i := len(parts) - 1; i > 0; i-- {
+		value, exist := activation.ResolveName(strings.Join(parts[:i], "."))
+		if !exist {
+			continue
+		}
+		for _, key := range parts[i:] {
+			m := reflect.ValueOf(value)
+			if m.Kind() != reflect.Map || m.Type().Key().Kind() != reflect.String && m.Type().Key().Kind() != reflect.Interface {
+				return nil, false
+			}
+			v := m.MapIndex(reflect.ValueOf(key))
+			if !v.IsValid() {
+				return nil, false
+			}
+			value = v.Interface()
+		}
+		return value, true
+	}
+	return nil, false
+}
+
+// CelParse parses expression with Hub macros. Presence test `has(a.b)` on a parameter name
+// is rewritten to resolve the qualified name, while presence tests on comprehension
+// variables keep standard CEL semantics.
+func CelParse(env *cel.Env, expr string) (*cel.Ast, error) {
+	ast, issues := env.Parse(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	scopeHas(ast.Expr(), nil)
+	return ast, nil
+}
+
+func scopeHas(expr *exprpb.Expr, bound map[string]struct{}) {
+	if expr == nil {
+		return
+	}
+	switch e := expr.ExprKind.(type) {
+	case *exprpb.Expr_SelectExpr:
+		if e.SelectExpr.TestOnly {
+			if name, ok := qualifiedName(e.SelectExpr.Operand); ok {
+				if _, isBound := bound[rootName(name)]; !isBound {
+					// operand subtree is dropped, reuse its id for the literal
+					literal := &exprpb.Expr{Id: e.SelectExpr.Operand.Id,
+						ExprKind: &exprpb.Expr_ConstExpr{ConstExpr: &exprpb.Constant{
+							ConstantKind: &exprpb.Constant_StringValue{StringValue: name + "." + e.SelectExpr.Field}}}}
+					expr.ExprKind = &exprpb.Expr_CallExpr{CallExpr: &exprpb.Expr_Call{
+						Function: celHas, Args: []*exprpb.Expr{literal}}}
+					return
+				}
+			}
+		}
+		scopeHas(e.SelectExpr.Operand, bound)
+	case *exprpb.Expr_CallExpr:
+		scopeHas(e.CallExpr.Target, bound)
+		for _, arg := range e.CallExpr.Args {
+			scopeHas(arg, bound)
+		}
+	case *exprpb.Expr_ListExpr:
+		for _, elem := range e.ListExpr.Elements {
+			scopeHas(elem, bound)
+		}
+	case *exprpb.Expr_StructExpr:
+		for _, entry := range e.StructExpr.Entries {
+			scopeHas(entry.GetMapKey(), bound)
+			scopeHas(entry.Value, bound)
+		}
+	case *exprpb.Expr_ComprehensionExpr:
+		c := e.ComprehensionExpr
+		scopeHas(c.IterRange, bound)
+		scopeHas(c.AccuInit, bound)
+		nested := map[string]struct{}{c.IterVar: {}, c.AccuVar: {}}
+		for name := range bound {
+			nested[name] = struct{}{}
+		}
+		for _, sub := range []*exprpb.Expr{c.LoopCondition, c.LoopStep, c.Result} {
+			scopeHas(sub, nested)
+		}
+	}
+}
+
+// CelCheck parses and type-checks expression so that unknown functions and wrong arguments
+// are reported early. Free variables are declared `dyn` as parameters are resolved at runtime;
+// their (qualified) names are returned for the caller to validate.
+func CelCheck(expr string) ([]string, error) {
+	ast, err := CelParse(CEL, expr)
+	if err != nil {
+		return nil, fmt.Errorf("CEL parse error `%s`: %v", expr, err)
+	}
+	idents := make(map[string]struct{})
+	collectIdents(ast.Expr(), idents)
+	names := make([]string, 0, len(idents))
+	vars := make([]*exprpb.Decl, 0, len(idents))
+	for ident := range idents {
+		names = append(names, ident)
+		vars = append(vars, decls.NewVar(ident, decls.Dyn))
+	}
+	sort.Strings(names)
+	env, err := CEL.Extend(cel.Declarations(vars...))
+	if err != nil {
+		return nil, fmt.Errorf("CEL environment error: %v", err)
+	}
+	_, issues := env.Check(ast)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("CEL check error `%s`: %v", expr, issues.Err())
+	}
+	return names, nil
+}
+
+var celNamespacedFunctions = map[string]struct{}{
+	"base64.encode": {}, "base64.decode": {}, "json.encode": {}, "json.decode": {}, "yaml.decode": {},
+	"net.cidrSubnet": {}, "net.cidrHost": {}, "semver.compare": {}, "semver.matches": {},
+}
+
+func collectIdents(expr *exprpb.Expr, idents map[string]struct{}) {
+	if expr == nil {
+		return
+	}
+	switch e := expr.ExprKind.(type) {
+	case *exprpb.Expr_IdentExpr:
+		idents[e.IdentExpr.Name] = struct{}{}
+	case *exprpb.Expr_SelectExpr:
+		if name, ok := qualifiedName(expr); ok {
+			idents[name] = struct{}{}
+			return
+		}
+		collectIdents(e.SelectExpr.Operand, idents)
+	case *exprpb.Expr_CallExpr:
+		target := e.CallExpr.Target
+		if ident, ok := target.GetExprKind().(*exprpb.Expr_IdentExpr); ok {
+			if _, fn := celNamespacedFunctions[ident.IdentExpr.Name+"."+e.CallExpr.Function]; fn {
+				target = nil
+			}
+		}
+		collectIdents(target, idents)
+		for _, arg := range e.CallExpr.Args {
+			collectIdents(arg, idents)
+		}
+	case *exprpb.Expr_ListExpr:
+		for _, elem := range e.ListExpr.Elements {
+			collectIdents(elem, idents)
+		}
+	case *exprpb.Expr_StructExpr:
+		for _, entry := range e.StructExpr.Entries {
+			collectIdents(entry.GetMapKey(), idents)
+			collectIdents(entry.Value, idents)
+		}
+	case *exprpb.Expr_ComprehensionExpr:
+		c := e.ComprehensionExpr
+		collectIdents(c.IterRange, idents)
+		nested := make(map[string]struct{})
+		for _, sub := range []*exprpb.Expr{c.AccuInit, c.LoopCondition, c.LoopStep, c.Result} {
+			collectIdents(sub, nested)
+		}
+		for ident := range nested {
+			if root := rootName(ident); root != c.IterVar && root != c.AccuVar {
+				idents[ident] = struct{}{}
+			}
+		}
+	}
+}
+
+func rootName(name string) string {
+	if i := strings.Index(name, "."); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+type hubCelLib struct{}
+
+func (hubCelLib) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Macros(
+			parser.NewGlobalMacro("has", 1, expandHas),
+			parser.NewGlobalMacro("default", 2, expandDefault),
+		),
+		cel.Declarations(
+			decls.NewFunction(celHas,
+				decls.NewOverload("hub_has_string", []*exprpb.Type{decls.String}, decls.Bool)),
+			decls.NewFunction(celDefault,
+				decls.NewOverload("hub_default_string_dyn", []*exprpb.Type{decls.String, decls.Dyn}, decls.Dyn)),
+			decls.NewFunction(celLookup,
+				decls.NewOverload("lookup_string_string", []*exprpb.Type{decls.String, decls.String}, decls.Dyn)),
+			decls.NewFunction("lower",
+				decls.NewInstanceOverload("string_lower", []*exprpb.Type{decls.String}, decls.String)),
+			decls.NewFunction("upper",
+				decls.NewInstanceOverload("string_upper", []*exprpb.Type{decls.String}, decls.String)),
+			decls.NewFunction("join",
+				decls.NewInstanceOverload("list_join_string",
+					[]*exprpb.Type{decls.NewListType(decls.String), decls.String}, decls.String)),
+			decls.NewFunction("base64.encode",
+				decls.NewOverload("base64_encode_string", []*exprpb.Type{decls.String}, decls.String)),
+			decls.NewFunction("base64.decode",
+				decls.NewOverload("base64_decode_string", []*exprpb.Type{decls.String}, decls.String)),
+			decls.NewFunction("json.encode",
+				decls.NewOverload("json_encode_dyn", []*exprpb.Type{decls.Dyn}, decls.String)),
+			decls.NewFunction("json.decode",
+				decls.NewOverload("json_decode_string", []*exprpb.Type{decls.String}, decls.Dyn)),
+			decls.NewFunction("yaml.decode",
+				decls.NewOverload("yaml_decode_string", []*exprpb.Type{decls.String}, decls.Dyn)),
+			decls.NewFunction("net.cidrSubnet",
+				decls.NewOverload("net_cidr_subnet_string_int_int",
+					[]*exprpb.Type{decls.String, decls.Int, decls.Int}, decls.String)),
+			decls.NewFunction("net.cidrHost",
+				decls.NewOverload("net_cidr_host_string_int", []*exprpb.Type{decls.String, decls.Int}, decls.String)),
+			decls.NewFunction("semver.compare",
+				decls.NewOverload("semver_compare_string_string", []*exprpb.Type{decls.String, decls.String}, decls.Int)),
+			decls.NewFunction("semver.matches",
+				decls.NewOverload("semver_matches_string_string", []*exprpb.Type{decls.String, decls.String}, decls.Bool)),
+		),
+	}
+}
+
+func (hubCelLib) ProgramOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{
+		cel.Functions(
+			&functions.Overload{Operator: "lower", Unary: celStringToString(func(s string) (string, error) {
+				return strings.ToLower(s), nil
+			})},
+			&functions.Overload{Operator: "string_lower", Unary: celStringToString(func(s string) (string, error) {
+				return strings.ToLower(s), nil
+			})},
+			&functions.Overload{Operator: "upper", Unary: celStringToString(func(s string) (string, error) {
+				return strings.ToUpper(s), nil
+			})},
+			&functions.Overload{Operator: "string_upper", Unary: celStringToString(func(s string) (string, error) {
+				return strings.ToUpper(s), nil
+			})},
+			&functions.Overload{Operator: "join", Binary: celJoin},
+			&functions.Overload{Operator: "list_join_string", Binary: celJoin},
+			&functions.Overload{Operator: "base64.encode", Unary: celStringToString(func(s string) (string, error) {
+				return base64.StdEncoding.EncodeToString([]byte(s)), nil
+			})},
+			&functions.Overload{Operator: "base64.decode", Unary: celStringToString(func(s string) (string, error) {
+				bytes, err := base64.StdEncoding.DecodeString(s)
+				return string(bytes), err
+			})},
+			&functions.Overload{Operator: "json.encode", Unary: celJsonEncode},
+			&functions.Overload{Operator: "json.decode", Unary: celDecode(json.Unmarshal)},
+			&functions.Overload{Operator: "yaml.decode", Unary: celDecode(yaml.Unmarshal)},
+			&functions.Overload{Operator: "net.cidrSubnet", Function: celCidrSubnet},
+			&functions.Overload{Operator: "net.cidrHost", Binary: celCidrHost},
+			&functions.Overload{Operator: "semver.compare", Binary: celSemverCompare},
+			&functions.Overload{Operator: "semver.matches", Binary: celSemverMatches},
+		),
+	}
+}
+
+func expandHas(eh parser.ExprHelper, target *exprpb.Expr, args []*exprpb.Expr) (*exprpb.Expr, *common.Error) {
+	switch arg := args[0].ExprKind.(type) {
+	case *exprpb.Expr_IdentExpr:
+		return eh.GlobalCall(celHas, eh.LiteralString(arg.IdentExpr.Name)), nil
+	case *exprpb.Expr_SelectExpr:
+		if !arg.SelectExpr.TestOnly {
+			// qualified parameter names are resolved by CelParse, see scopeHas
+			return eh.PresenceTest(arg.SelectExpr.Operand, arg.SelectExpr.Field), nil
+		}
+	}
+	return nil, &common.Error{Message: "invalid argument to has() macro"}
+}
+
+func expandDefault(eh parser.ExprHelper, target *exprpb.Expr, args []*exprpb.Expr) (*exprpb.Expr, *common.Error) {
+	name, ok := qualifiedName(args[0])
+	if !ok {
+		return nil, &common.Error{Message: "default() first argument must be a parameter name"}
+	}
+	return eh.GlobalCall(celDefault, eh.LiteralString(name), args[1]), nil
+}
+
+func qualifiedName(expr *exprpb.Expr) (string, bool) {
+	switch e := expr.ExprKind.(type) {
+	case *exprpb.Expr_IdentExpr:
+		return e.IdentExpr.Name, true
+	case *exprpb.Expr_SelectExpr:
+		if e.SelectExpr.TestOnly {
+			return "", false
+		}
+		if operand, ok := qualifiedName(e.SelectExpr.Operand); ok {
+			return operand + "." + e.SelectExpr.Field, true
+		}
+	}
+	return "", false
+}
+
+func celStringToString(f func(string) (string, error)) functions.UnaryOp {
+	return func(value ref.Val) ref.Val {
+		str, ok := value.(types.String)
+		if !ok {
+			return types.MaybeNoSuchOverloadErr(value)
+		}
+		out, err := f(string(str))
+		if err != nil {
+			return types.NewErr("%v", err)
+		}
+		return types.String(out)
+	}
+}
+
+func celJoin(list, sep ref.Val) ref.Val {
+	lister, ok1 := list.(traits.Lister)
+	str, ok2 := sep.(types.String)
+	if !ok1 || !ok2 {
+		return types.NoSuchOverloadErr()
+	}
+	parts := make([]string, 0)
+	for it := lister.Iterator(); it.HasNext() == types.True; {
+		parts = append(parts, fmt.Sprintf("%v", it.Next().Value()))
+	}
+	return types.String(strings.Join(parts, string(str)))
+}
+
+// CelString formats CEL evaluation result for substitution: lists and maps, such as produced by
+// split(), json.decode(), yaml.decode(), are formatted as JSON, scalars as is
+func CelString(value ref.Val) (string, error) {
+	switch value.(type) {
+	case traits.Lister, traits.Mapper:
+		str, ok := celJsonEncode(value).(types.String)
+		if !ok {
+			return "", fmt.Errorf("Unable to format `%+v` as JSON", value.Value())
+		}
+		return string(str), nil
+	}
+	return fmt.Sprintf("%+v", value), nil
+}
+
+func celJsonEncode(value ref.Val) ref.Val {
+	native, err := value.ConvertToNative(reflect.TypeOf((*interface{})(nil)).Elem())
+	if err != nil {
+		native = value.Value()
+	}
+	bytes, err := json.Marshal(jsonCompatible(native))
+	if err != nil {
+		return types.NewErr("json.encode: %v", err)
+	}
+	return types.String(bytes)
+}
+
+func celDecode(unmarshal func([]byte, interface{}) error) functions.UnaryOp {
+	return func(value ref.Val) ref.Val {
+		str, ok := value.(types.String)
+		if !ok {
+			return types.MaybeNoSuchOverloadErr(value)
+		}
+		var out interface{}
+		if err := unmarshal([]byte(str), &out); err != nil {
+			return types.NewErr("decode error: %v", err)
+		}
+		return types.DefaultTypeAdapter.NativeToValue(jsonCompatible(out))
+	}
+}
+
+// jsonCompatible converts YAML map[interface{}]interface{} and CEL values into JSON-friendly form.
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case ref.Val:
+		return jsonCompatible(v.Value())
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[fmt.Sprintf("%v", jsonCompatible(key))] = jsonCompatible(val)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[key] = jsonCompatible(val)
+		}
+		return m
+	case map[ref.Val]ref.Val:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[fmt.Sprintf("%v", key.Value())] = jsonCompatible(val)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, 0, len(v))
+		for _, val := range v {
+			l = append(l, jsonCompatible(val))
+		}
+		return l
+	case []ref.Val:
+		l := make([]interface{}, 0, len(v))
+		for _, val := range v {
+			l = append(l, jsonCompatible(val))
+		}
+		return l
+	}
+	return value
+}
+
+func celCidrSubnet(args ...ref.Val) ref.Val {
+	if len(args) != 3 {
+		return types.NoSuchOverloadErr()
+	}
+	cidr, ok1 := args[0].(types.String)
+	newbits, ok2 := args[1].(types.Int)
+	num, ok3 := args[2].(types.Int)
+	if !ok1 || !ok2 || !ok3 {
+		return types.NoSuchOverloadErr()
+	}
+	_, network, err := net.ParseCIDR(string(cidr))
+	if err != nil {
+		return types.NewErr("net.cidrSubnet: %v", err)
+	}
+	ones, bits := network.Mask.Size()
+	prefix := ones + int(newbits)
+	if newbits < 0 || prefix > bits {
+		return types.NewErr("net.cidrSubnet: cannot extend /%d prefix by %d bits", ones, newbits)
+	}
+	if num < 0 || big.NewInt(int64(num)).BitLen() > int(newbits) {
+		return types.NewErr("net.cidrSubnet: subnet %d does not fit into %d bits", num, newbits)
+	}
+	ip := addToIP(network.IP, new(big.Int).Lsh(big.NewInt(int64(num)), uint(bits-prefix)))
+	subnet := net.IPNet{IP: ip, Mask: net.CIDRMask(prefix, bits)}
+	return types.String(subnet.String())
+}
+
+func celCidrHost(cidrVal, numVal ref.Val) ref.Val {
+	cidr, ok1 := cidrVal.(types.String)
+	num, ok2 := numVal.(types.Int)
+	if !ok1 || !ok2 {
+		return types.NoSuchOverloadErr()
+	}
+	_, network, err := net.ParseCIDR(string(cidr))
+	if err != nil {
+		return types.NewErr("net.cidrHost: %v", err)
+	}
+	ones, bits := network.Mask.Size()
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	host := big.NewInt(int64(num))
+	if num < 0 {
+		host.Add(host, size)
+	}
+	if host.Sign() < 0 || host.Cmp(size) >= 0 {
+		return types.NewErr("net.cidrHost: host %d does not fit into %s", num, network.String())
+	}
+	return types.String(addToIP(network.IP, host).String())
+}
+
+func addToIP(ip net.IP, offset *big.Int) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		sum := uint32(binary.BigEndian.Uint32(v4)) + uint32(offset.Uint64())
+		out := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(out, sum)
+		return out
+	}
+	sum := new(big.Int).Add(new(big.Int).SetBytes(ip.To16()), offset).Bytes()
+	out := make(net.IP, net.IPv6len)
+	copy(out[net.IPv6len-len(sum):], sum)
+	return out
+}
+
+func celSemverCompare(a, b ref.Val) ref.Val {
+	va, err := celSemver(a)
+	if err != nil {
+		return types.NewErr("semver.compare: %v", err)
+	}
+	vb, err := celSemver(b)
+	if err != nil {
+		return types.NewErr("semver.compare: %v", err)
+	}
+	return types.Int(va.Compare(vb))
+}
+
+func celSemverMatches(version, constraint ref.Val) ref.Val {
+	v, err := celSemver(version)
+	if err != nil {
+		return types.NewErr("semver.matches: %v", err)
+	}
+	str, ok := constraint.(types.String)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(constraint)
+	}
+	c, err := semver.NewConstraint(string(str))
+	if err != nil {
+		return types.NewErr("semver.matches: %v", err)
+	}
+	return types.Bool(c.Check(v))
+}
+
+func celSemver(value ref.Val) (*semver.Version, error) {
+	str, ok := value.(types.String)
+	if !ok {
+		return nil, fmt.Errorf("expected string, got %v", value.Type())
+	}
+	return semver.NewVersion(string(str))
+}
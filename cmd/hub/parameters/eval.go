@@ -28,7 +28,7 @@ func RequireExpansion(value interface{}) bool {
 
 func LockParameters(parameters []manifest.Parameter,
 	extraValues []manifest.Parameter,
-	ask func(manifest.Parameter) (interface{}, error)) (LockedParameters, []error) {
+	ask func(manifest.Parameter) (interface{}, *manifest.ParameterSource, error)) (LockedParameters, []error) {
 
 	for _, parameter := range parameters {
 		if !util.Empty(parameter.Default) && parameter.Kind != "user" {
@@ -41,11 +41,29 @@ func LockParameters(parameters []manifest.Parameter,
 		}
 	}
 	errs := make([]error, 0)
+	// populate empty parameters from other stacks state
+	states := make(map[string]*stackState)
+	for i, parameter := range parameters {
+		if util.Empty(parameter.Value) && parameter.FromStackState != "" {
+			value, source, err := resolveFromStackState(parameter, states)
+			if err != nil {
+				errs = append(errs, err)
+				value = "(error)"
+			}
+			parameters[i].Value = value
+			if source != nil {
+				parameters[i].Source = source
+			}
+		}
+	}
 	// populate empty user-level parameters from environment or user input
 	for i, parameter := range parameters {
 		if util.Empty(parameter.Value) && parameter.Kind == "user" && len(parameter.Parameters) == 0 {
-			value, err := ask(parameter)
+			value, source, err := ask(parameter)
 			parameters[i].Value = value
+			if source != nil {
+				parameters[i].Source = source
+			}
 			if err != nil {
 				errs = append(errs, err)
 			}
@@ -68,7 +86,7 @@ func LockParameters(parameters []manifest.Parameter,
 			kv[fqName] = parameter.Value
 		}
 		locked[fqName] = LockedParameter{Name: parameter.Name, Component: parameter.Component,
-			Value: parameter.Value, Env: parameter.Env}
+			Value: parameter.Value, Env: parameter.Env, Source: parameter.Source}
 	}
 	if config.Debug && len(locked) > 0 {
 		log.Print("Parameters locked:")
@@ -246,6 +264,25 @@ func FindValue(parameterName string, componentName string, componentDepends []st
 	return v, exist
 }
 
+// findSource returns stack parameter source the component parameter value was derived from,
+// otherwise the value must come from an output
+func findSource(name, componentName string, parameters LockedParameters,
+	componentSource *manifest.ParameterSource) *manifest.ParameterSource {
+
+	for _, qName := range []string{parameterQualifiedName(name, componentName), name} {
+		if parameter, exist := parameters[qName]; exist && parameter.Source != nil {
+			return parameter.Source
+		}
+	}
+	if _, exist := parameters[parameterQualifiedName(name, componentName)]; exist {
+		return componentSource
+	}
+	if _, exist := parameters[name]; exist {
+		return componentSource
+	}
+	return &manifest.ParameterSource{Mechanism: manifest.ParameterSourceOutput, Ref: name}
+}
+
 func ExpandParameters(componentName, componentKind string, componentDepends []string,
 	parameters LockedParameters, outputs CapturedOutputs,
 	componentParameters []manifest.Parameter) ([]LockedParameter, []error) {
@@ -265,6 +302,7 @@ func ExpandParameters(componentName, componentKind string, componentDepends []st
 		v, exist := FindValue(parameter.Name, componentName, componentDepends, kv)
 		if exist {
 			parameter.Value = v
+			parameter.Source = findSource(parameter.Name, componentName, parameters, parameter.Source)
 			if RequireExpansion(parameter.Value) {
 				errs = append(errs, ExpandParameter(&parameter, componentDepends, kv)...)
 			}
@@ -297,7 +335,8 @@ func ExpandParameters(componentName, componentKind string, componentDepends []st
 			log.Printf("--- %s | %s => %v", parameter.Name, componentName, parameter.Value)
 		}
 
-		expanded = append(expanded, LockedParameter{Name: parameter.Name, Value: parameter.Value, Env: parameter.Env})
+		expanded = append(expanded, LockedParameter{Name: parameter.Name, Value: parameter.Value, Env: parameter.Env,
+			Source: parameter.Source})
 		kv[parameter.Name] = parameter.Value
 	}
 	if config.Trace && len(expanded) > 1 {
@@ -0,0 +1,129 @@
+package parameters
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/crypto"
+	"github.com/agilestacks/hub/cmd/hub/manifest"
+	"github.com/agilestacks/hub/cmd/hub/storage"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+// subset of state.StateManifest - the state package depends on parameters
+type stackState struct {
+	Kind         string
+	Meta         struct{ Name string }
+	StackOutputs []ExpandedOutput `yaml:"stackOutputs"`
+	Components   map[string]struct {
+		CapturedOutputs []CapturedOutput `yaml:"capturedOutputs"`
+	}
+}
+
+// ParseStackStateRef splits `fromStackState: s3://bucket/net.state#component:vpc.id` into state location(s)
+// and output reference
+func ParseStackStateRef(ref string) (string, string, error) {
+	i := strings.LastIndex(ref, "#")
+	if i <= 0 || i == len(ref)-1 {
+		return "", "", fmt.Errorf("`fromStackState: %s` must be in form <state location>#[component:]output", ref)
+	}
+	return ref[:i], ref[i+1:], nil
+}
+
+func resolveFromStackState(parameter manifest.Parameter, states map[string]*stackState) (interface{}, *manifest.ParameterSource, error) {
+	location, output, err := ParseStackStateRef(parameter.FromStackState)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Parameter `%s` %v", parameter.QName(), err)
+	}
+	location = os.ExpandEnv(location)
+	st, exist := states[location]
+	if !exist {
+		st, err = readStackState(location)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Parameter `%s` `fromStackState: %s`: %v",
+				parameter.QName(), parameter.FromStackState, err)
+		}
+		states[location] = st
+	}
+	value, found := st.output(output)
+	if !found {
+		return nil, nil, fmt.Errorf("Parameter `%s` `fromStackState: %s`: no output `%s` in stack `%s` state; available outputs: %s",
+			parameter.QName(), parameter.FromStackState, output, st.Meta.Name, strings.Join(st.outputNames(), ", "))
+	}
+	if str, ok := value.(string); ok && crypto.IsEncryptedValue(str) {
+		value, err = crypto.DecryptAnyValue(str)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Parameter `%s` `fromStackState: %s`: unable to decrypt value: %v",
+				parameter.QName(), parameter.FromStackState, err)
+		}
+	}
+	if config.Debug {
+		log.Printf("Parameter `%s` <= `%s` from `%s` state", parameter.QName(), output, location)
+	}
+	return value, &manifest.ParameterSource{Mechanism: manifest.ParameterSourceFromStackState, File: location, Ref: output}, nil
+}
+
+func readStackState(location string) (*stackState, error) {
+	yamlBytes, filename, err := storage.CheckAndRead(util.SplitPaths(location), "state")
+	if err != nil {
+		return nil, err
+	}
+	var st stackState
+	err = yaml.Unmarshal(yamlBytes, &st)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse `%s`: %v", filename, err)
+	}
+	if st.Kind != "state" {
+		return nil, fmt.Errorf("`%s` kind = `%s` but it must be `state`", filename, st.Kind)
+	}
+	return &st, nil
+}
+
+// output finds stack output by exact name, or by name without `component:` prefix,
+// or captured output of component if reference is `component:output`
+func (st *stackState) output(ref string) (interface{}, bool) {
+	for _, o := range st.StackOutputs {
+		if o.Name == ref {
+			return o.Value, true
+		}
+	}
+	if !strings.Contains(ref, ":") {
+		for _, o := range st.StackOutputs {
+			if i := strings.Index(o.Name, ":"); i > 0 && o.Name[i+1:] == ref {
+				return o.Value, true
+			}
+		}
+		return nil, false
+	}
+	parts := strings.SplitN(ref, ":", 2)
+	if step, exist := st.Components[parts[0]]; exist {
+		for _, o := range step.CapturedOutputs {
+			if o.Name == parts[1] {
+				return o.Value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (st *stackState) outputNames() []string {
+	names := make([]string, 0, len(st.StackOutputs))
+	for _, o := range st.StackOutputs {
+		names = append(names, o.Name)
+	}
+	for component, step := range st.Components {
+		for _, o := range step.CapturedOutputs {
+			names = append(names, fmt.Sprintf("%s:%s", component, o.Name))
+		}
+	}
+	names = util.Uniq(names)
+	if len(names) == 0 {
+		return []string{"(none)"}
+	}
+	return names
+}
@@ -2,13 +2,16 @@ package parameters
 
 import (
 	"fmt"
+
+	"github.com/agilestacks/hub/cmd/hub/manifest"
 )
 
 type LockedParameter struct {
 	Component string `yaml:",omitempty"`
 	Name      string
 	Value     interface{}
-	Env       string `yaml:",omitempty"`
+	Env       string                    `yaml:",omitempty"`
+	Source    *manifest.ParameterSource `yaml:",omitempty"`
 }
 
 type RawOutput struct {
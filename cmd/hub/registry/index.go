@@ -0,0 +1,276 @@
+package registry
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"gopkg.in/yaml.v2"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+const (
+	indexFilename = "index.yaml"
+	indexKind     = "registry"
+)
+
+var httpClient = util.RobustHttpClient(0, false)
+
+func isHttp(registry string) bool {
+	return strings.HasPrefix(registry, "https://") || strings.HasPrefix(registry, "http://")
+}
+
+func location(registry, name string) string {
+	if isHttp(registry) {
+		return strings.TrimSuffix(registry, "/") + "/" + name
+	}
+	return filepath.Join(registry, name)
+}
+
+func get(registry, name string) ([]byte, bool, error) {
+	data, _, exist, err := getWithETag(registry, name)
+	return data, exist, err
+}
+
+func getWithETag(registry, name string) ([]byte, string, bool, error) {
+	loc := location(registry, name)
+	if !isHttp(registry) {
+		data, err := ioutil.ReadFile(loc)
+		if err != nil && os.IsNotExist(err) {
+			return nil, "", false, nil
+		}
+		return data, "", err == nil, err
+	}
+	if config.Trace {
+		log.Printf(">>> GET %s", loc)
+	}
+	resp, err := httpClient.Get(loc)
+	if err != nil {
+		return nil, "", false, err
+	}
+	defer resp.Body.Close()
+	if config.Trace {
+		log.Printf("<<< GET %s: %s", loc, resp.Status)
+	}
+	if resp.StatusCode == 404 {
+		return nil, "", false, nil
+	}
+	if resp.StatusCode != 200 {
+		return nil, "", false, fmt.Errorf("GET %s returned HTTP status %d; expected 200", loc, resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	return data, resp.Header.Get("ETag"), err == nil, err
+}
+
+func put(registry, name string, data []byte) error {
+	return putConditional(registry, name, data, nil)
+}
+
+// putConditional sends If-Match / If-None-Match headers to HTTP registry
+func putConditional(registry, name string, data []byte, headers map[string]string) error {
+	loc := location(registry, name)
+	if !isHttp(registry) {
+		err := os.MkdirAll(registry, 0755)
+		if err != nil {
+			return err
+		}
+		// write to temporary file first so that readers never see partial content
+		temp := fmt.Sprintf("%s.%d.tmp", loc, os.Getpid())
+		err = ioutil.WriteFile(temp, data, 0644)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(temp, loc)
+		if err != nil {
+			os.Remove(temp)
+		}
+		return err
+	}
+	req, err := http.NewRequest("PUT", loc, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for header, value := range headers {
+		req.Header.Set(header, value)
+	}
+	if config.Trace {
+		log.Printf(">>> PUT %s %v", loc, headers)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if config.Trace {
+		log.Printf("<<< PUT %s: %s", loc, resp.Status)
+	}
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return errConcurrentUpdate
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("PUT %s returned HTTP status %d", loc, resp.StatusCode)
+	}
+	return nil
+}
+
+func readIndex(registry string) (*Index, error) {
+	index, _, _, err := readIndexWithETag(registry)
+	return index, err
+}
+
+func readIndexWithETag(registry string) (*Index, string, bool, error) {
+	data, etag, exist, err := getWithETag(registry, indexFilename)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("Unable to read `%s` registry index: %v", registry, err)
+	}
+	index, err := parseIndex(registry, data, exist)
+	return index, etag, exist, err
+}
+
+func parseIndex(registry string, data []byte, exist bool) (*Index, error) {
+	index := &Index{Version: 1, Kind: indexKind, Components: make(map[string][]IndexEntry)}
+	if !exist {
+		return index, nil
+	}
+	err := yaml.Unmarshal(data, index)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse `%s` registry index: %v", registry, err)
+	}
+	if index.Kind != indexKind {
+		return nil, fmt.Errorf("`%s` registry index kind is `%s`; expected `%s`", registry, index.Kind, indexKind)
+	}
+	if index.Components == nil {
+		index.Components = make(map[string][]IndexEntry)
+	}
+	return index, nil
+}
+
+func marshalIndex(index *Index) ([]byte, error) {
+	for name, entries := range index.Components {
+		sort.Slice(entries, func(i, j int) bool {
+			return compareVersions(entries[i].Version, entries[j].Version) < 0
+		})
+		index.Components[name] = entries
+	}
+	return yaml.Marshal(index)
+}
+
+var errConcurrentUpdate = errors.New("registry index was updated concurrently")
+
+const (
+	indexUpdateAttempts = 10
+	indexLockTimeout    = 60 * time.Second
+)
+
+// updateIndex performs read-modify-write of the index: local registry index is protected by lock file,
+// HTTP registry index is written with conditional PUT and update is retried on conflict
+func updateIndex(registry string, update func(*Index) error) error {
+	if !isHttp(registry) {
+		unlock, err := lockIndex(registry)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		index, err := readIndex(registry)
+		if err != nil {
+			return err
+		}
+		err = update(index)
+		if err != nil {
+			return err
+		}
+		data, err := marshalIndex(index)
+		if err != nil {
+			return err
+		}
+		err = put(registry, indexFilename, data)
+		if err != nil {
+			return fmt.Errorf("Unable to write `%s` registry index: %v", registry, err)
+		}
+		return nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		index, etag, exist, err := readIndexWithETag(registry)
+		if err != nil {
+			return err
+		}
+		// index is loaded into fresh struct on every attempt
+		err = update(index)
+		if err != nil {
+			return err
+		}
+		data, err := marshalIndex(index)
+		if err != nil {
+			return err
+		}
+		var headers map[string]string
+		if !exist {
+			headers = map[string]string{"If-None-Match": "*"}
+		} else if etag != "" {
+			headers = map[string]string{"If-Match": etag}
+		} else if attempt == 1 {
+			util.Warn("`%s` registry did not return ETag for index; concurrent publish may lose entries", registry)
+		}
+		err = putConditional(registry, indexFilename, data, headers)
+		if err == nil {
+			return nil
+		}
+		if err != errConcurrentUpdate || attempt >= indexUpdateAttempts {
+			return fmt.Errorf("Unable to write `%s` registry index: %v", registry, err)
+		}
+		if config.Debug {
+			log.Printf("Registry `%s` index changed concurrently, retrying update (attempt %d)", registry, attempt+1)
+		}
+		time.Sleep(time.Duration(attempt*200) * time.Millisecond)
+	}
+}
+
+func lockIndex(registry string) (func(), error) {
+	err := os.MkdirAll(registry, 0755)
+	if err != nil {
+		return nil, err
+	}
+	lock := filepath.Join(registry, indexFilename+".lock")
+	deadline := time.Now().Add(indexLockTimeout)
+	for {
+		file, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("Unable to create `%s`: %v", lock, err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Unable to lock `%s` registry index: `%s` exists for more than %v; remove it if no publish is in progress",
+				registry, lock, indexLockTimeout)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func parseVersion(version string) (*semver.Version, error) {
+	return semver.NewVersion(version)
+}
+
+func compareVersions(a, b string) int {
+	va, errA := parseVersion(a)
+	vb, errB := parseVersion(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return va.Compare(vb)
+}
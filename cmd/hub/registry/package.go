@@ -0,0 +1,252 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/manifest"
+)
+
+const (
+	componentManifestFilename = "hub-component.yaml"
+	checksumSuffix            = ".sha256"
+	checksumPrefix            = "sha256:"
+)
+
+var excludedFromArchive = []string{".git", registryMarkerFilename}
+
+func Package(dir, outDir string) (string, string, error) {
+	componentManifest, _, _, err := manifest.ParseManifest([]string{filepath.Join(dir, componentManifestFilename)})
+	if err != nil {
+		return "", "", err
+	}
+	name := componentManifest.Meta.Name
+	version := componentManifest.Meta.Version
+	if name == "" {
+		return "", "", fmt.Errorf("No `meta.name` set in `%s` component manifest", dir)
+	}
+	if version == "" {
+		return "", "", fmt.Errorf("No `meta.version` set in `%s` component manifest", name)
+	}
+	if _, err := parseVersion(version); err != nil {
+		return "", "", fmt.Errorf("Component `%s` version `%s` is not semver: %v", name, version, err)
+	}
+
+	archive, err := tarGz(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("Unable to archive `%s`: %v", dir, err)
+	}
+	if outDir == "" {
+		outDir = "."
+	}
+	filename := filepath.Join(outDir, archiveName(name, version))
+	err = ioutil.WriteFile(filename, archive, 0644)
+	if err != nil {
+		return "", "", err
+	}
+	checksum := checksum(archive)
+	err = ioutil.WriteFile(filename+checksumSuffix,
+		[]byte(fmt.Sprintf("%s  %s\n", strings.TrimPrefix(checksum, checksumPrefix), filepath.Base(filename))), 0644)
+	if err != nil {
+		return "", "", err
+	}
+	if config.Verbose {
+		log.Printf("Component `%s` version %s packaged into %s (%s)", name, version, filename, checksum)
+	}
+	return filename, checksum, nil
+}
+
+func archiveName(name, version string) string {
+	return fmt.Sprintf("%s-%s.tar.gz", name, version)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return checksumPrefix + hex.EncodeToString(sum[:])
+}
+
+func tarGz(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		for _, excluded := range excludedFromArchive {
+			if info.Name() == excluded {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		err = tw.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = tw.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = gz.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func untarGz(archive []byte, dir string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	root := filepath.Clean(dir)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(root, filepath.FromSlash(header.Name))
+		if !insideDir(root, path) {
+			return fmt.Errorf("Archive entry `%s` points outside of `%s`", header.Name, dir)
+		}
+		// do not write through symlinks created by previous entries
+		err = checkNoSymlinks(root, path)
+		if err != nil {
+			return fmt.Errorf("Archive entry `%s`: %v", header.Name, err)
+		}
+		mode := os.FileMode(header.Mode).Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, mode|0700)
+		case tar.TypeSymlink:
+			link := filepath.FromSlash(header.Linkname)
+			if filepath.IsAbs(link) || !insideDir(root, filepath.Join(filepath.Dir(path), link)) {
+				return fmt.Errorf("Archive entry `%s` is a symlink to `%s` outside of `%s`", header.Name, header.Linkname, dir)
+			}
+			err = os.MkdirAll(filepath.Dir(path), 0755)
+			if err == nil {
+				err = os.Symlink(header.Linkname, path)
+			}
+		case tar.TypeReg:
+			err = os.MkdirAll(filepath.Dir(path), 0755)
+			if err == nil {
+				var file *os.File
+				file, err = os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+				if err == nil {
+					_, err = io.Copy(file, tr)
+					file.Close()
+				}
+			}
+		default:
+			if config.Debug {
+				log.Printf("Skipping archive entry `%s` of type %d", header.Name, header.Typeflag)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func insideDir(root, path string) bool {
+	return strings.HasPrefix(filepath.Clean(path), root+string(os.PathSeparator))
+}
+
+// checkNoSymlinks returns error if path or any of its parents below root is a symlink
+func checkNoSymlinks(root, path string) error {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+	current := root
+	for _, part := range strings.Split(rel, string(os.PathSeparator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("`%s` is a symlink", current)
+		}
+	}
+	return nil
+}
+
+func readFromTarGz(archive []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.New("not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimPrefix(header.Name, "./") == name {
+			return ioutil.ReadAll(tr)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/manifest"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+func Publish(archiveFilename, registry string) error {
+	if registry == "" {
+		return errors.New("No registry set, use --registry or HUB_REGISTRY")
+	}
+	archive, err := ioutil.ReadFile(archiveFilename)
+	if err != nil {
+		return err
+	}
+	sum := checksum(archive)
+	if expected, err := ioutil.ReadFile(archiveFilename + checksumSuffix); err == nil {
+		fields := strings.Fields(string(expected))
+		if len(fields) > 0 && checksumPrefix+fields[0] != sum {
+			return fmt.Errorf("`%s` checksum %s does not match %s%s", archiveFilename, sum, archiveFilename, checksumSuffix)
+		}
+	}
+
+	manifestBytes, err := readFromTarGz(archive, componentManifestFilename)
+	if err != nil {
+		return fmt.Errorf("Unable to read `%s` from `%s`: %v", componentManifestFilename, archiveFilename, err)
+	}
+	var componentManifest manifest.Manifest
+	err = yaml.Unmarshal(manifestBytes, &componentManifest)
+	if err != nil {
+		return fmt.Errorf("Unable to parse `%s` from `%s`: %v", componentManifestFilename, archiveFilename, err)
+	}
+	name := componentManifest.Meta.Name
+	version := componentManifest.Meta.Version
+	if name == "" || version == "" {
+		return fmt.Errorf("Component manifest in `%s` must have `meta.name` and `meta.version` set", archiveFilename)
+	}
+
+	entry := IndexEntry{
+		Version:   version,
+		Archive:   archiveName(name, version),
+		Checksum:  sum,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	uploaded := false
+	err = updateIndex(registry, func(index *Index) error {
+		entries := index.Components[name]
+		replaced := false
+		for i, exist := range entries {
+			if exist.Version == version {
+				if exist.Checksum != sum {
+					msg := fmt.Sprintf("Component `%s` version %s is already published to `%s` with different checksum %s",
+						name, version, registry, exist.Checksum)
+					if !config.Force {
+						return errors.New(msg)
+					}
+					util.Warn("%s", msg)
+				}
+				entries[i] = entry
+				replaced = true
+			}
+		}
+		if !replaced {
+			entries = append(entries, entry)
+		}
+		index.Components[name] = entries
+
+		if !uploaded {
+			err := put(registry, entry.Archive, archive)
+			if err != nil {
+				return fmt.Errorf("Unable to upload `%s`: %v", filepath.Base(archiveFilename), err)
+			}
+			uploaded = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if config.Verbose {
+		log.Printf("Component `%s` version %s published to %s", name, version, registry)
+	}
+	return nil
+}
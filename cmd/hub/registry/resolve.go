@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+)
+
+const registryMarkerFilename = ".hub-registry"
+
+func ParseSpec(spec string) (string, string, error) {
+	parts := strings.SplitN(spec, "@", 2)
+	name := strings.TrimSpace(parts[0])
+	if name == "" {
+		return "", "", fmt.Errorf("No component name in registry source `%s`", spec)
+	}
+	constraint := "*"
+	if len(parts) > 1 && strings.TrimSpace(parts[1]) != "" {
+		constraint = strings.TrimSpace(parts[1])
+	}
+	return name, constraint, nil
+}
+
+// Resolve finds the highest published version matching the spec `name@constraint`,
+// downloads the archive, verifies the checksum, and unpacks it into dir.
+// An already unpacked matching version is reused.
+func Resolve(spec, registry, dir string) (string, error) {
+	if registry == "" {
+		return "", errors.New("No registry set, use --registry or HUB_REGISTRY")
+	}
+	name, constraintStr, err := ParseSpec(spec)
+	if err != nil {
+		return "", err
+	}
+	constraint, err := semver.NewConstraint(constraintStr)
+	if err != nil {
+		return "", fmt.Errorf("Unable to parse `%s` version constraint `%s`: %v", name, constraintStr, err)
+	}
+	index, err := readIndex(registry)
+	if err != nil {
+		return "", err
+	}
+	entries, exist := index.Components[name]
+	if !exist || len(entries) == 0 {
+		return "", fmt.Errorf("Component `%s` not found in `%s` registry", name, registry)
+	}
+
+	var found *IndexEntry
+	var foundVersion *semver.Version
+	for i, entry := range entries {
+		version, err := parseVersion(entry.Version)
+		if err != nil {
+			if config.Debug {
+				log.Printf("Skipping `%s` version `%s`: %v", name, entry.Version, err)
+			}
+			continue
+		}
+		if constraint.Check(version) && (foundVersion == nil || version.GreaterThan(foundVersion)) {
+			found = &entries[i]
+			foundVersion = version
+		}
+	}
+	if found == nil {
+		versions := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			versions = append(versions, entry.Version)
+		}
+		return "", fmt.Errorf("No `%s` version in `%s` registry satisfies `%s`; published: %s",
+			name, registry, constraintStr, strings.Join(versions, ", "))
+	}
+
+	marker := fmt.Sprintf("%s@%s %s\n", name, found.Version, found.Checksum)
+	markerFilename := filepath.Join(dir, registryMarkerFilename)
+	if exist, err := ioutil.ReadFile(markerFilename); err == nil {
+		if string(exist) == marker {
+			if config.Debug {
+				log.Printf("Component `%s` version %s is already unpacked into `%s`", name, found.Version, dir)
+			}
+			return found.Version, nil
+		}
+		err = os.RemoveAll(dir)
+		if err != nil {
+			return "", fmt.Errorf("Unable to remove `%s`: %v", dir, err)
+		}
+	} else if _, err := os.Stat(dir); err == nil {
+		return "", fmt.Errorf("Directory `%s` exists but is not managed by registry, remove it first", dir)
+	}
+
+	archive, exist, err := get(registry, found.Archive)
+	if err != nil {
+		return "", fmt.Errorf("Unable to download `%s`: %v", found.Archive, err)
+	}
+	if !exist {
+		return "", fmt.Errorf("Archive `%s` not found in `%s` registry", found.Archive, registry)
+	}
+	if sum := checksum(archive); sum != found.Checksum {
+		return "", fmt.Errorf("`%s` checksum %s does not match registry index %s", found.Archive, sum, found.Checksum)
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+	err = untarGz(archive, dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("Unable to unpack `%s` into `%s`: %v", found.Archive, dir, err)
+	}
+	err = ioutil.WriteFile(markerFilename, []byte(marker), 0644)
+	if err != nil {
+		return "", err
+	}
+	if config.Verbose {
+		log.Printf("Component `%s` version %s from %s unpacked into `%s`", name, found.Version, registry, dir)
+	}
+	return found.Version, nil
+}
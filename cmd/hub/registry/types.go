@@ -0,0 +1,14 @@
+package registry
+
+type IndexEntry struct {
+	Version   string
+	Archive   string
+	Checksum  string
+	Timestamp string `yaml:",omitempty"`
+}
+
+type Index struct {
+	Version    int
+	Kind       string
+	Components map[string][]IndexEntry
+}
@@ -0,0 +1,276 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var funcs = map[string]interface{}{
+	"duration": func(d time.Duration) string {
+		if d == 0 {
+			return ""
+		}
+		if d < time.Second {
+			return d.Round(time.Millisecond).String()
+		}
+		return d.Round(100 * time.Millisecond).String()
+	},
+	"timestamp": func(t time.Time) string {
+		return t.UTC().Format(time.RFC3339)
+	},
+	"join": strings.Join,
+	"cell": func(s string) string {
+		return strings.NewReplacer("|", "\\|", "\r", "", "\n", "<br>").Replace(s)
+	},
+}
+
+const markdownTemplate = `# {{.Verb}} {{.Stack}}: {{if .Status}}{{.Status}}{{else}}unknown{{end}}
+
+Operation {{if .OperationId}}` + "`{{.OperationId}}` " + `{{end}}started {{timestamp .Timestamp}}, took {{duration .Duration}}
+{{- if .Message}}
+
+> {{cell .Message}}
+{{- end}}
+
+## Components
+{{if .Components}}
+| Component | Result | Status | Duration |
+|-----------|--------|--------|----------|
+{{- range .Components}}
+| {{.Name}} | {{.Result}} | {{.Status}} | {{duration .Duration}} |
+{{- end}}
+{{else}}
+No components.
+{{end}}
+{{- if .Parameters}}
+## Changed parameters
+
+| Parameter | Component | Previous | Current |
+|-----------|-----------|----------|---------|
+{{- range .Parameters}}
+| {{.Name}} | {{.Component}} | {{cell .Previous}} | {{cell .Current}} |
+{{- end}}
+{{end}}
+{{- if .Outputs}}
+## Outputs
+
+| Output | Value | Description |
+|--------|-------|-------------|
+{{- range .Outputs}}
+| {{.Name}} | {{cell .Value}} | {{cell .Brief}} |
+{{- end}}
+{{end}}
+{{- if .Provides}}
+## Provides
+{{range .Provides}}
+- {{.Name}}: {{join .Components ", "}}
+{{- end}}
+{{end}}
+{{- if .Failures}}
+## Errors
+{{range .Components}}{{if ne .Result "success"}}
+### {{.Name}}
+{{if .Message}}
+{{.Message}}
+{{end}}{{if .Log}}
+` + "```" + `
+{{.Log}}
+` + "```" + `
+{{end}}{{end}}{{end}}{{end}}`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Verb}} {{.Stack}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+pre { background: #f8f8f8; border: 1px solid #ddd; padding: 0.8em; overflow-x: auto; }
+.success { color: #1a7f37; }
+.failure { color: #cf222e; }
+.incomplete { color: #9a6700; }
+</style>
+</head>
+<body>
+<h1>{{.Verb}} {{.Stack}}: {{if .Status}}{{.Status}}{{else}}unknown{{end}}</h1>
+<p>Operation {{if .OperationId}}<code>{{.OperationId}}</code> {{end}}started {{timestamp .Timestamp}}, took {{duration .Duration}}</p>
+{{- if .Message}}
+<blockquote>{{.Message}}</blockquote>
+{{- end}}
+<h2>Components</h2>
+{{- if .Components}}
+<table>
+<tr><th>Component</th><th>Result</th><th>Status</th><th>Duration</th></tr>
+{{- range .Components}}
+<tr><td>{{.Name}}</td><td class="{{.Result}}">{{.Result}}</td><td>{{.Status}}</td><td>{{duration .Duration}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No components.</p>
+{{- end}}
+{{- if .Parameters}}
+<h2>Changed parameters</h2>
+<table>
+<tr><th>Parameter</th><th>Component</th><th>Previous</th><th>Current</th></tr>
+{{- range .Parameters}}
+<tr><td>{{.Name}}</td><td>{{.Component}}</td><td>{{.Previous}}</td><td>{{.Current}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Outputs}}
+<h2>Outputs</h2>
+<table>
+<tr><th>Output</th><th>Value</th><th>Description</th></tr>
+{{- range .Outputs}}
+<tr><td>{{.Name}}</td><td>{{.Value}}</td><td>{{.Brief}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Provides}}
+<h2>Provides</h2>
+<ul>
+{{- range .Provides}}
+<li>{{.Name}}: {{join .Components ", "}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Failures}}
+<h2>Errors</h2>
+{{- range .Components}}{{if ne .Result "success"}}
+<h3 class="{{.Result}}">{{.Name}}</h3>
+{{- if .Message}}
+<p>{{.Message}}</p>
+{{- end}}
+{{- if .Log}}
+<pre>{{.Log}}</pre>
+{{- end}}
+{{- end}}{{end}}
+{{- end}}
+</body>
+</html>
+`
+
+func renderMarkdown(report *Report) ([]byte, error) {
+	tmpl, err := template.New("markdown").Funcs(funcs).Parse(markdownTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	err = tmpl.Execute(&out, report)
+	return out.Bytes(), err
+}
+
+func renderHtml(report *Report) ([]byte, error) {
+	tmpl, err := htmltemplate.New("html").Funcs(funcs).Parse(htmlTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	err = tmpl.Execute(&out, report)
+	return out.Bytes(), err
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",cdata"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+}
+
+type junitTestSuite struct {
+	XMLName    xml.Name        `xml:"testsuite"`
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Time       string           `xml:"time,attr"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+// each component is a test case; a component that failed is a failure, a component
+// that did not complete because the operation was aborted is an error
+func renderJUnit(report *Report) ([]byte, error) {
+	seconds := func(d time.Duration) string {
+		return fmt.Sprintf("%.3f", d.Seconds())
+	}
+	suite := junitTestSuite{
+		Name:      fmt.Sprintf("%s %s", report.Verb, report.Stack),
+		Tests:     len(report.Components),
+		Time:      seconds(report.Duration),
+		Timestamp: report.Timestamp.UTC().Format("2006-01-02T15:04:05"),
+	}
+	for _, p := range []junitProperty{
+		{Name: "operationId", Value: report.OperationId},
+		{Name: "status", Value: report.Status},
+		{Name: "message", Value: report.Message},
+	} {
+		if p.Value != "" {
+			suite.Properties = append(suite.Properties, p)
+		}
+	}
+	for _, component := range report.Components {
+		testCase := junitTestCase{
+			Name:      component.Name,
+			ClassName: report.Stack,
+			Time:      seconds(component.Duration),
+		}
+		message := component.Message
+		if message == "" {
+			message = component.Status
+		}
+		switch component.Result {
+		case ResultFailure:
+			suite.Failures++
+			testCase.Failure = &junitFailure{Message: message, Type: component.Status, Text: component.Log}
+		case ResultIncomplete:
+			suite.Errors++
+			testCase.Error = &junitFailure{Message: message, Type: component.Result, Text: component.Log}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	suites := junitTestSuites{
+		Name:       suite.Name,
+		Tests:      suite.Tests,
+		Failures:   suite.Failures,
+		Errors:     suite.Errors,
+		Time:       suite.Time,
+		TestSuites: []junitTestSuite{suite},
+	}
+	out, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
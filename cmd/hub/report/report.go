@@ -0,0 +1,233 @@
+package report
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/agilestacks/hub/cmd/hub/parameters"
+	"github.com/agilestacks/hub/cmd/hub/state"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+// Run report is written by `--report` at the end of deploy, undeploy, and backup
+// for CI to keep as an artifact; format is chosen by file extension.
+
+const (
+	FormatMarkdown = "markdown"
+	FormatHtml     = "html"
+	FormatJUnit    = "junit"
+
+	ResultSuccess    = "success"
+	ResultFailure    = "failure"
+	ResultIncomplete = "incomplete"
+
+	logExcerptLines = 30
+)
+
+type Component struct {
+	Name     string
+	Status   string
+	Result   string
+	Duration time.Duration
+	Message  string
+	Log      string
+}
+
+type ParameterChange struct {
+	Name      string
+	Component string
+	Previous  string
+	Current   string
+}
+
+type Output struct {
+	Name  string
+	Value string
+	Brief string
+}
+
+type Provide struct {
+	Name       string
+	Components []string
+}
+
+type Report struct {
+	Verb        string
+	Stack       string
+	OperationId string
+	Status      string
+	Message     string
+	Timestamp   time.Time
+	Duration    time.Duration
+	Components  []Component
+	Parameters  []ParameterChange
+	Outputs     []Output
+	Provides    []Provide
+}
+
+func (r *Report) Failures() int {
+	failures := 0
+	for _, c := range r.Components {
+		if c.Result != ResultSuccess {
+			failures++
+		}
+	}
+	return failures
+}
+
+func Format(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".md", ".markdown":
+		return FormatMarkdown, nil
+	case ".html", ".htm":
+		return FormatHtml, nil
+	case ".xml":
+		return FormatJUnit, nil
+	}
+	return "", fmt.Errorf("Unsupported report format `%s`; use .md, .html, or .xml for JUnit", filename)
+}
+
+// FromState builds report of the operation from the final state and the operation's phases;
+// previous state, if any, is used to find changed parameters, componentLogs are failed components
+// output captured during the operation
+func FromState(verb string, previous, final *state.StateManifest, operationId string,
+	started time.Time, componentLogs map[string]string) *Report {
+
+	final = state.MaskSecrets(final)
+	report := &Report{
+		Verb:        verb,
+		Stack:       final.Meta.Name,
+		OperationId: operationId,
+		Status:      final.Status,
+		Message:     final.Message,
+		Timestamp:   started,
+		Duration:    time.Since(started),
+	}
+
+	var phases []state.LifecyclePhase
+	for _, op := range final.Operations {
+		if op.Id == operationId {
+			phases = op.Phases
+			break
+		}
+	}
+	for _, phase := range phases {
+		component := Component{Name: phase.Phase, Log: logExcerpt(componentLogs[phase.Phase])}
+		switch phase.Status {
+		case "success":
+			component.Result = ResultSuccess
+		case "error":
+			component.Result = ResultFailure
+		default:
+			component.Result = ResultIncomplete
+		}
+		if step, exist := final.Components[phase.Phase]; exist && step != nil {
+			component.Status = step.Status
+			component.Message = step.Message
+			if t := step.Timestamps; !t.Start.IsZero() && t.End.After(t.Start) {
+				component.Duration = t.End.Sub(t.Start)
+			}
+		}
+		report.Components = append(report.Components, component)
+	}
+
+	if previous != nil {
+		previous = state.MaskSecrets(previous)
+	}
+	report.Parameters = changedParameters(previous, final)
+
+	for _, o := range final.StackOutputs {
+		report.Outputs = append(report.Outputs, Output{Name: o.Name, Value: util.String(o.Value), Brief: o.Brief})
+	}
+
+	names := make([]string, 0, len(final.Provides))
+	for name := range final.Provides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		report.Provides = append(report.Provides, Provide{Name: name, Components: final.Provides[name]})
+	}
+
+	return report
+}
+
+func changedParameters(previous, final *state.StateManifest) []ParameterChange {
+	values := func(manifest *state.StateManifest) (map[string]parameters.LockedParameter, []string) {
+		all := make(map[string]parameters.LockedParameter)
+		keys := make([]string, 0)
+		if manifest == nil {
+			return all, keys
+		}
+		add := func(component string, list []parameters.LockedParameter) {
+			for _, p := range list {
+				if p.Component == "" {
+					p.Component = component
+				}
+				key := p.Component + "|" + p.Name
+				if _, exist := all[key]; !exist {
+					keys = append(keys, key)
+				}
+				all[key] = p
+			}
+		}
+		add("", manifest.StackParameters)
+		for _, name := range manifest.Lifecycle.Order {
+			if step, exist := manifest.Components[name]; exist && step != nil {
+				add(name, step.Parameters)
+			}
+		}
+		return all, keys
+	}
+	previousValues, _ := values(previous)
+	currentValues, keys := values(final)
+
+	var changes []ParameterChange
+	for _, key := range keys {
+		current := currentValues[key]
+		change := ParameterChange{Name: current.Name, Component: current.Component, Current: util.String(current.Value)}
+		if p, exist := previousValues[key]; exist {
+			change.Previous = util.String(p.Value)
+			if change.Previous == change.Current {
+				continue
+			}
+		} else if change.Current == "" {
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func logExcerpt(log string) string {
+	lines := strings.Split(strings.TrimRight(log, "\n"), "\n")
+	if len(lines) > logExcerptLines {
+		lines = append([]string{fmt.Sprintf("... %d lines skipped", len(lines)-logExcerptLines)},
+			lines[len(lines)-logExcerptLines:]...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func Write(filename string, report *Report) error {
+	format, err := Format(filename)
+	if err != nil {
+		return err
+	}
+	var bytes []byte
+	switch format {
+	case FormatMarkdown:
+		bytes, err = renderMarkdown(report)
+	case FormatHtml:
+		bytes, err = renderHtml(report)
+	case FormatJUnit:
+		bytes, err = renderJUnit(report)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to render %s report: %v", format, err)
+	}
+	return ioutil.WriteFile(filename, bytes, 0644)
+}
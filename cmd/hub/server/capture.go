@@ -0,0 +1,119 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+// Hub CLI code terminates the process on error via log.Fatal*, util.MaybeFatalf, and util.Exit.
+// In server mode these are turned into panics that abort the current job only.
+
+type jobFatal string
+
+type jobExit int
+
+var fatalFuncs = []string{
+	"log.Fatal", "log.Fatalf", "log.Fatalln",
+	"log.(*Logger).Fatal", "log.(*Logger).Fatalf", "log.(*Logger).Fatalln",
+}
+
+// fatalWriter is the log output that panics when the message is written by log.Fatal*
+type fatalWriter struct {
+	out io.Writer
+}
+
+func (w fatalWriter) Write(p []byte) (int, error) {
+	n, err := w.out.Write(p)
+	if calledByLogFatal() {
+		panic(jobFatal(strings.TrimSpace(string(p))))
+	}
+	return n, err
+}
+
+func calledByLogFatal() bool {
+	pc := make([]uintptr, 32)
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	for {
+		frame, more := frames.Next()
+		if util.Contains(fatalFuncs, frame.Function) {
+			return true
+		}
+		if !more {
+			return false
+		}
+	}
+}
+
+func exitPanic(code int) {
+	panic(jobExit(code))
+}
+
+// recovered converts job abort panic into an error
+func recovered(r interface{}) error {
+	switch v := r.(type) {
+	case jobFatal:
+		return errors.New(string(v))
+	case jobExit:
+		return fmt.Errorf("Exit code %d", int(v))
+	default:
+		serverLog.Printf("Panic: %v\n%s", r, debug.Stack())
+		return fmt.Errorf("Panic: %v", r)
+	}
+}
+
+// capture runs the job with stdout, stderr, and the logger redirected to out;
+// child processes inherit redirected stdout and stderr
+func capture(out io.Writer, run func()) (err error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("Unable to create pipe: %v", err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	log.SetOutput(fatalWriter{w})
+
+	copied := make(chan struct{})
+	go func() {
+		io.Copy(out, r)
+		close(copied)
+	}()
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = recovered(p)
+		}
+		if doneErr := done(); err == nil {
+			err = doneErr
+		}
+		os.Stdout, os.Stderr = stdout, stderr
+		log.SetOutput(fatalWriter{stderr})
+		w.Close()
+		<-copied
+		r.Close()
+	}()
+
+	run()
+	util.CheckAbort()
+	return nil
+}
+
+// done runs job cleanup that may write state, and reports abort of the job goroutines
+func done() (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = recovered(p)
+		}
+	}()
+	util.Done()
+	util.CheckAbort()
+	return nil
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/agilestacks/hub/cmd/hub/api"
+)
+
+const (
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobSuccess = "success"
+	jobFailed  = "failed"
+
+	jobQueueSize      = 100
+	subscriberBacklog = 256
+)
+
+type Job struct {
+	Id       string     `json:"id"`
+	Verb     string     `json:"verb"`
+	Stack    []string   `json:"stack"`
+	Status   string     `json:"status"`
+	Message  string     `json:"message,omitempty"`
+	Created  time.Time  `json:"created"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Logs     string     `json:"logs,omitempty"`
+
+	run         func()
+	logs        bytes.Buffer
+	subscribers map[chan api.WsMessage]struct{}
+}
+
+// jobLogs is a writer that collects job output and sends it to the log stream subscribers
+type jobLogs struct {
+	server *Server
+	job    *Job
+}
+
+func (w jobLogs) Write(p []byte) (int, error) {
+	s := w.server
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	w.job.logs.Write(p)
+	s.broadcast(w.job, w.job.message(w.job.Verb+"-update", true, string(p)))
+	return len(p), nil
+}
+
+func (job *Job) message(action string, success bool, logs string) api.WsMessage {
+	return api.WsMessage{
+		Id:      job.Id,
+		Entity:  "job",
+		Name:    job.Verb,
+		Action:  action,
+		Success: success,
+		Logs:    logs,
+	}
+}
+
+func (job *Job) completed() bool {
+	return job.Status == jobSuccess || job.Status == jobFailed
+}
+
+// snapshot returns a copy of the job safe to serialize outside of the lock
+func (job *Job) snapshot(withLogs bool) Job {
+	copy := Job{
+		Id:       job.Id,
+		Verb:     job.Verb,
+		Stack:    job.Stack,
+		Status:   job.Status,
+		Message:  job.Message,
+		Created:  job.Created,
+		Started:  job.Started,
+		Finished: job.Finished,
+	}
+	if withLogs {
+		copy.Logs = job.logs.String()
+	}
+	return copy
+}
+
+// submit registers the job and puts it into the queue unless another job holds the stack
+func (s *Server) submit(verb string, stack []string, run func()) (*Job, error) {
+	u, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to generate job Id random v4 UUID: %v", err)
+	}
+	job := &Job{
+		Id:          u.String(),
+		Verb:        verb,
+		Stack:       stack,
+		Status:      jobQueued,
+		Created:     time.Now(),
+		run:         run,
+		subscribers: make(map[chan api.WsMessage]struct{}),
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, key := range stack {
+		if id, locked := s.stacks[key]; locked {
+			return nil, &conflictError{fmt.Sprintf("`%s` is locked by job %s", key, id)}
+		}
+	}
+	select {
+	case s.queue <- job:
+	default:
+		return nil, fmt.Errorf("Job queue is full (%d jobs)", jobQueueSize)
+	}
+	for _, key := range stack {
+		s.stacks[key] = job.Id
+	}
+	s.jobs[job.Id] = job
+	s.order = append(s.order, job.Id)
+	return job, nil
+}
+
+// worker executes jobs one by one as Hub CLI output, logger, and configuration are process-wide
+func (s *Server) worker() {
+	for job := range s.queue {
+		s.lock.Lock()
+		started := time.Now()
+		job.Status = jobRunning
+		job.Started = &started
+		s.lock.Unlock()
+
+		err := capture(jobLogs{s, job}, job.run)
+
+		s.lock.Lock()
+		finished := time.Now()
+		job.Finished = &finished
+		job.Status = jobSuccess
+		if err != nil {
+			job.Status = jobFailed
+			job.Message = err.Error()
+		}
+		for _, key := range job.Stack {
+			delete(s.stacks, key)
+		}
+		s.broadcast(job, job.message(job.Verb, err == nil, job.Message))
+		for ch := range job.subscribers {
+			close(ch)
+		}
+		job.subscribers = nil
+		s.lock.Unlock()
+
+		serverLog.Printf("Job %s %s %s", job.Id, job.Verb, job.Status)
+	}
+}
+
+// subscribe returns a channel that receives job logs collected so far and then updates until the job is completed
+func (s *Server) subscribe(job *Job) chan api.WsMessage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ch := make(chan api.WsMessage, subscriberBacklog)
+	if job.logs.Len() > 0 {
+		ch <- job.message(job.Verb+"-update", true, job.logs.String())
+	}
+	if job.completed() {
+		ch <- job.message(job.Verb, job.Status == jobSuccess, job.Message)
+		close(ch)
+	} else {
+		job.subscribers[ch] = struct{}{}
+	}
+	return ch
+}
+
+func (s *Server) unsubscribe(job *Job, ch chan api.WsMessage) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, exist := job.subscribers[ch]; exist {
+		delete(job.subscribers, ch)
+		close(ch)
+	}
+}
+
+// broadcast must be called with the lock held; a subscriber that cannot keep up is disconnected
+func (s *Server) broadcast(job *Job, msg api.WsMessage) {
+	for ch := range job.subscribers {
+		select {
+		case ch <- msg:
+		default:
+			delete(job.subscribers, ch)
+			close(ch)
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/agilestacks/hub/cmd/hub/compose"
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/lifecycle"
+	"github.com/agilestacks/hub/cmd/hub/manifest"
+	"github.com/agilestacks/hub/cmd/hub/state"
+	"github.com/agilestacks/hub/cmd/hub/storage"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+// Prepare sets up environment for nested `hub` invocations by component implementations
+type Prepare func(manifests, stateManifests []string, componentsBaseDir string)
+
+type Server struct {
+	prepare Prepare
+
+	lock   sync.Mutex
+	jobs   map[string]*Job
+	order  []string
+	stacks map[string]string // state or elaborate file -> job Id
+	queue  chan *Job
+}
+
+// JobRequest fields mirror command-line arguments; paths are relative to the working directory,
+// multiple paths are separated by comma
+type JobRequest struct {
+	Manifest      string `json:"manifest"`   // elaborate: hub.yaml
+	Parameters    string `json:"parameters"` // elaborate: parameters manifests
+	Elaborate     string `json:"elaborate"`
+	State         string `json:"state"`
+	Components    string `json:"components"`
+	Offset        string `json:"offset"`
+	Limit         string `json:"limit"`
+	BaseDir       string `json:"baseDir"`
+	Env           string `json:"env"`    // elaborate: environment overlay
+	Bundle        string `json:"bundle"` // backup: bundle files
+	OsEnvironment string `json:"osEnvironment"`
+	DryRun        bool   `json:"dryRun"`
+}
+
+type conflictError struct {
+	msg string
+}
+
+func (e *conflictError) Error() string {
+	return e.msg
+}
+
+var serverLog = log.New(os.Stderr, "", log.LstdFlags)
+
+// Serve listens on listen address; every request must carry `Authorization: Bearer <token>`
+// if token is set, which is mandatory unless the address is loopback
+func Serve(listen, workdir, token string, prepare Prepare) {
+	if token == "" && !isLoopback(listen) {
+		log.Fatalf("Refusing to serve on non-loopback address `%s` without a token, set --token or HUB_SERVE_TOKEN", listen)
+	}
+	if workdir != "" {
+		err := os.Chdir(workdir)
+		if err != nil {
+			log.Fatalf("Unable to change working directory: %v", err)
+		}
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		log.Fatalf("Unable to determine working directory: %v", err)
+	}
+
+	config.Tty = false
+	log.SetOutput(fatalWriter{os.Stderr})
+	util.AbortJobs(exitPanic)
+
+	s := &Server{
+		prepare: prepare,
+		jobs:    make(map[string]*Job),
+		stacks:  make(map[string]string),
+		queue:   make(chan *Job, jobQueueSize),
+	}
+	go s.worker()
+
+	mux := http.NewServeMux()
+	for _, verb := range []string{"elaborate", "deploy", "undeploy", "backup"} {
+		mux.HandleFunc("/"+verb, s.handleJob(verb))
+	}
+	mux.HandleFunc("/jobs", s.handleJobs)
+	mux.HandleFunc("/jobs/", s.handleJobs)
+	mux.HandleFunc("/explain", s.handleExplain)
+	mux.HandleFunc("/state", s.handleState)
+
+	var handler http.Handler = mux
+	if token != "" {
+		handler = requireToken(token, mux)
+	} else {
+		util.Warn("Serving without a token, any local user or process can run jobs")
+	}
+
+	serverLog.Printf("Serving %s on %s", dir, listen)
+	serverLog.Fatal(http.ListenAndServe(listen, handler))
+}
+
+func isLoopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			httpError(w, http.StatusUnauthorized, errors.New("Valid bearer token required"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleJob(verb string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			httpError(w, http.StatusMethodNotAllowed, fmt.Errorf("Use POST to start %s job", verb))
+			return
+		}
+		var req JobRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, fmt.Errorf("Unable to decode request: %v", err))
+			return
+		}
+		stack, run, err := s.jobRun(verb, &req)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, err)
+			return
+		}
+		err = checkLockFiles(stack)
+		if err != nil {
+			httpError(w, http.StatusConflict, err)
+			return
+		}
+		job, err := s.submit(verb, stack, run)
+		if err != nil {
+			status := http.StatusServiceUnavailable
+			var conflict *conflictError
+			if errors.As(err, &conflict) {
+				status = http.StatusConflict
+			}
+			httpError(w, status, err)
+			return
+		}
+		serverLog.Printf("Job %s %s %v queued", job.Id, verb, stack)
+		s.lock.Lock()
+		snapshot := job.snapshot(false)
+		s.lock.Unlock()
+		writeJson(w, http.StatusAccepted, snapshot)
+	}
+}
+
+// jobRun validates the request and returns files locked by the job, and the job function
+func (s *Server) jobRun(verb string, req *JobRequest) ([]string, func(), error) {
+	elaborate, err := workdirPaths(req.Elaborate, "hub.yaml.elaborate")
+	if err != nil {
+		return nil, nil, err
+	}
+	stateManifests, err := workdirPaths(req.State, "hub.yaml.state")
+	if err != nil {
+		return nil, nil, err
+	}
+	baseDir := ""
+	if req.BaseDir != "" {
+		dirs, err := workdirPaths(req.BaseDir, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		baseDir = dirs[0]
+	}
+	osEnvironment := req.OsEnvironment
+	if osEnvironment == "" {
+		osEnvironment = "no-tfvars"
+	}
+
+	switch verb {
+	case "elaborate":
+		manifests, err := workdirPaths(req.Manifest, "hub.yaml")
+		if err != nil {
+			return nil, nil, err
+		}
+		params, err := workdirPaths(req.Parameters, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		if req.State == "" {
+			stateManifests = nil
+		}
+		return stackKeys(elaborate), func() {
+			compose.Elaborate(manifests[0], params, "", "",
+				stateManifests, false, elaborate, baseDir, req.Env, "", nil)
+		}, nil
+
+	case "deploy", "undeploy":
+		request := &lifecycle.Request{
+			Verb:              verb,
+			DryRun:            req.DryRun,
+			ManifestFilenames: elaborate,
+			StateFilenames:    stateManifests,
+			Components:        util.SplitPaths(req.Components),
+			OffsetComponent:   req.Offset,
+			LimitComponent:    req.Limit,
+			OsEnvironmentMode: osEnvironment,
+			ComponentsBaseDir: baseDir,
+		}
+		return stackKeys(stateManifests), func() {
+			s.prepare(elaborate, stateManifests, baseDir)
+			lifecycle.Execute(request, nil)
+		}, nil
+
+	case "backup":
+		bundles, err := workdirPaths(req.Bundle, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		request := &lifecycle.Request{
+			Verb:              verb,
+			DryRun:            req.DryRun,
+			ManifestFilenames: elaborate,
+			StateFilenames:    stateManifests,
+			Components:        util.SplitPaths(req.Components),
+			OsEnvironmentMode: osEnvironment,
+			ComponentsBaseDir: baseDir,
+		}
+		return stackKeys(stateManifests), func() {
+			s.prepare(elaborate, stateManifests, baseDir)
+			lifecycle.BackupCreate(request, bundles, false, false, false, nil)
+		}, nil
+	}
+	return nil, nil, fmt.Errorf("Unknown verb `%s`", verb)
+}
+
+// handleJobs serves /jobs, /jobs/{id}, and /jobs/{id}/logs - the latter is a stream of api.WsMessage
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpError(w, http.StatusMethodNotAllowed, errors.New("Use GET to query jobs"))
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/jobs"), "/"), "/")
+	if parts[0] == "" {
+		s.lock.Lock()
+		jobs := make([]Job, 0, len(s.order))
+		for _, id := range s.order {
+			jobs = append(jobs, s.jobs[id].snapshot(false))
+		}
+		s.lock.Unlock()
+		writeJson(w, http.StatusOK, jobs)
+		return
+	}
+	s.lock.Lock()
+	job, exist := s.jobs[parts[0]]
+	s.lock.Unlock()
+	if !exist || len(parts) > 2 || (len(parts) == 2 && parts[1] != "logs") {
+		httpError(w, http.StatusNotFound, fmt.Errorf("No job `%s`", r.URL.Path))
+		return
+	}
+	if len(parts) == 1 {
+		s.lock.Lock()
+		snapshot := job.snapshot(true)
+		s.lock.Unlock()
+		writeJson(w, http.StatusOK, snapshot)
+		return
+	}
+	s.streamLogs(w, r, job)
+}
+
+// streamLogs sends job logs as Server-Sent Events with api.WsMessage payload
+func (s *Server) streamLogs(w http.ResponseWriter, r *http.Request, job *Job) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpError(w, http.StatusInternalServerError, errors.New("Streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	updates := s.subscribe(job)
+	defer s.unsubscribe(job, updates)
+	for {
+		select {
+		case msg, ok := <-updates:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(msg)
+			if err != nil {
+				return
+			}
+			_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleExplain returns state explained in JSON with secrets masked
+func (s *Server) handleExplain(w http.ResponseWriter, r *http.Request) {
+	defer recoverFatal(w)
+	query := r.URL.Query()
+	st, status, err := readState(query.Get("state"))
+	if err != nil {
+		httpError(w, status, err)
+		return
+	}
+	order := st.Lifecycle.Order
+	componentName := query.Get("component")
+	scope := []string{componentName}
+	if elaborate := query.Get("elaborate"); elaborate != "" {
+		elaborateManifests, err := workdirPaths(elaborate, "")
+		if err != nil {
+			httpError(w, http.StatusBadRequest, err)
+			return
+		}
+		stackManifest, _, _, err := manifest.ParseManifest(elaborateManifests)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, err)
+			return
+		}
+		order = stackManifest.Lifecycle.Order
+		scope = manifest.ExpandComponentsNames(stackManifest.Components, scope)
+	}
+	global := query.Get("global") == "true"
+	components, prevOutputs := state.ExplainScope(st, order, scope...)
+	explained, err := state.ExplainState(st, components, prevOutputs, global, componentName, query.Get("raw") == "true")
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJson(w, http.StatusOK, explained)
+}
+
+// handleState returns state in YAML with secrets masked
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	defer recoverFatal(w)
+	st, status, err := readState(r.URL.Query().Get("state"))
+	if err != nil {
+		httpError(w, status, err)
+		return
+	}
+	bytes, err := yaml.Marshal(state.MaskSecrets(st))
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(bytes)
+}
+
+func readState(paths string) (*state.StateManifest, int, error) {
+	stateManifests, err := workdirPaths(paths, "hub.yaml.state")
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	stateFiles, errs := storage.Check(stateManifests, "state")
+	if len(errs) > 0 {
+		return nil, http.StatusInternalServerError, fmt.Errorf("Unable to check state files: %s", util.Errors2(errs...))
+	}
+	st, err := state.ParseState(stateFiles)
+	if err != nil {
+		return nil, http.StatusNotFound, fmt.Errorf("Unable to load state: %v", err)
+	}
+	return st, 0, nil
+}
+
+// workdirPaths splits comma-separated paths and rejects local paths outside of the working directory
+func workdirPaths(list, def string) ([]string, error) {
+	if list == "" {
+		list = def
+	}
+	paths := util.SplitPaths(list)
+	for _, path := range paths {
+		if strings.Contains(path, "://") {
+			continue
+		}
+		clean := filepath.Clean(path)
+		if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("Path `%s` is outside of working directory", path)
+		}
+	}
+	return paths, nil
+}
+
+func stackKeys(paths []string) []string {
+	keys := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if !strings.Contains(path, "://") {
+			path = util.MustAbs(path)
+		}
+		keys = append(keys, path)
+	}
+	return keys
+}
+
+// checkLockFiles rejects the job if a local file is locked by `<path>.lock`, same as Hub CLI does
+func checkLockFiles(paths []string) error {
+	for _, path := range paths {
+		if strings.Contains(path, "://") {
+			continue
+		}
+		lockPath := path + ".lock"
+		if _, err := os.Stat(lockPath); err == nil {
+			return &conflictError{fmt.Sprintf("Lock file %s present", lockPath)}
+		}
+	}
+	return nil
+}
+
+// recoverFatal responds with an error when request handler hits log.Fatal* or util.Exit
+func recoverFatal(w http.ResponseWriter) {
+	if p := recover(); p != nil {
+		httpError(w, http.StatusInternalServerError, recovered(p))
+	}
+}
+
+func httpError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJson(w http.ResponseWriter, status int, value interface{}) {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		status = http.StatusInternalServerError
+		bytes = []byte(fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(bytes)
+}
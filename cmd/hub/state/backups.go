@@ -0,0 +1,195 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/crypto"
+	"github.com/agilestacks/hub/cmd/hub/storage"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+type backupFile struct {
+	File      storage.File
+	Bundle    *BackupManifest
+	Timestamp time.Time
+}
+
+// scanBackups returns backup bundles found under prefix, newest first.
+// Files that are not backup bundles are skipped.
+func scanBackups(prefix string) []backupFile {
+	files, err := storage.List(prefix, "backup bundle")
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	backups := make([]backupFile, 0, len(files))
+	for _, file := range files {
+		bundle, err := ParseBackupBundle(&storage.Files{Kind: "backup bundle", Files: []storage.File{file}})
+		if err != nil {
+			if config.Debug {
+				log.Printf("Skipping `%s`: %v", file.Path, err)
+			}
+			continue
+		}
+		timestamp := bundle.Timestamp
+		if timestamp.IsZero() {
+			timestamp = file.ModTime
+		}
+		backups = append(backups, backupFile{File: file, Bundle: bundle, Timestamp: timestamp})
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].Timestamp.After(backups[j].Timestamp)
+	})
+	return backups
+}
+
+func ListBackups(prefix string) {
+	backups := scanBackups(prefix)
+	if len(backups) == 0 {
+		log.Printf("No backup bundles found under `%s`", prefix)
+		return
+	}
+	for _, backup := range backups {
+		components := make([]string, 0, len(backup.Bundle.Components))
+		for name := range backup.Bundle.Components {
+			components = append(components, name)
+		}
+		sort.Strings(components)
+		fmt.Printf("%s\t%s\t%s\t%d\t%s\n", backup.Timestamp.Format(time.RFC3339), backup.Bundle.Status,
+			backup.File.Path, backup.File.Size, strings.Join(components, ","))
+	}
+}
+
+// selectRetained marks backups to keep: the most recent keepLast successful backups, plus the most recent
+// successful backup of each of the keepDaily most recent days that have one. Unsuccessful backups do not
+// count toward retention; they are kept only if newer than the most recent successful backup.
+func selectRetained(backups []backupFile, keepLast, keepDaily int) []bool {
+	keep := make([]bool, len(backups))
+	days := make(map[string]struct{})
+	successful := 0
+	for i, backup := range backups {
+		if backup.Bundle.Status != "success" {
+			keep[i] = successful == 0
+			continue
+		}
+		if successful < keepLast {
+			keep[i] = true
+		}
+		successful++
+		day := backup.Timestamp.Local().Format("2006-01-02")
+		if _, seen := days[day]; !seen && len(days) < keepDaily {
+			days[day] = struct{}{}
+			keep[i] = true
+		}
+	}
+	return keep
+}
+
+func PruneBackups(prefix string, keepLast, keepDaily int, dryRun bool) {
+	if keepLast <= 0 && keepDaily <= 0 && !config.Force {
+		log.Fatal("Refusing to prune all backups: set --keep-last and/or --keep-daily, or use --force")
+	}
+	backups := scanBackups(prefix)
+	keep := selectRetained(backups, keepLast, keepDaily)
+	removed := 0
+	var errs []error
+	for i, backup := range backups {
+		if keep[i] {
+			if config.Debug {
+				log.Printf("Keeping `%s` (%s)", backup.File.Path, backup.Timestamp.Format(time.RFC3339))
+			}
+			continue
+		}
+		if dryRun {
+			log.Printf("Would remove `%s` (%s)", backup.File.Path, backup.Timestamp.Format(time.RFC3339))
+			continue
+		}
+		if err := storage.Remove(backup.File); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+		if config.Verbose {
+			log.Printf("Removed `%s` (%s)", backup.File.Path, backup.Timestamp.Format(time.RFC3339))
+		}
+	}
+	if config.Verbose && !dryRun {
+		log.Printf("Pruned %d of %d backup bundle(s) under `%s`", removed, len(backups), prefix)
+	}
+	if len(errs) > 0 {
+		util.MaybeFatalf("Unable to prune backups:\n\t%s", util.Errors("\n\t", errs...))
+	}
+}
+
+const backupChecksumPrefix = "sha256:"
+
+// BackupBundleChecksum returns SHA-256 of bundle content in canonical YAML form,
+// which is the same for YAML and JSON bundles. Checksum, signature, and diagnostic fields are excluded.
+func BackupBundleChecksum(bundle *BackupManifest) (string, error) {
+	content := *bundle
+	content.Checksum = ""
+	content.Signature = ""
+	content.Source = ""
+	content.Components = make(map[string]ComponentBackup, len(bundle.Components))
+	for name, component := range bundle.Components {
+		component.Source = ""
+		component.FileIndex = 0
+		content.Components[name] = component
+	}
+	bytes, err := yaml.Marshal(&content)
+	if err != nil {
+		return "", fmt.Errorf("Unable to marshal backup bundle: %v", err)
+	}
+	sum := sha256.Sum256(bytes)
+	return backupChecksumPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+func SignBackupBundle(bundle *BackupManifest, sign bool) error {
+	checksum, err := BackupBundleChecksum(bundle)
+	if err != nil {
+		return err
+	}
+	bundle.Checksum = checksum
+	bundle.Signature = ""
+	if sign {
+		signature, err := crypto.Sign([]byte(checksum))
+		if err != nil {
+			return fmt.Errorf("Unable to sign backup bundle: %v", err)
+		}
+		bundle.Signature = signature
+	}
+	return nil
+}
+
+func VerifyBackupBundleChecksum(bundle *BackupManifest) error {
+	if bundle.Checksum == "" {
+		return errors.New("Backup bundle has no checksum")
+	}
+	checksum, err := BackupBundleChecksum(bundle)
+	if err != nil {
+		return err
+	}
+	if checksum != bundle.Checksum {
+		return fmt.Errorf("Backup bundle checksum mismatch: bundle has `%s`, calculated `%s`", bundle.Checksum, checksum)
+	}
+	return nil
+}
+
+func VerifyBackupBundleSignature(bundle *BackupManifest) error {
+	if bundle.Signature == "" {
+		return errors.New("Backup bundle is not signed")
+	}
+	if err := crypto.Verify([]byte(bundle.Checksum), bundle.Signature); err != nil {
+		return fmt.Errorf("Backup bundle signature verification failed: %v", err)
+	}
+	return nil
+}
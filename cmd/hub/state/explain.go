@@ -24,6 +24,7 @@ type ExplainedComponent struct {
 	Status     string            `yaml:",omitempty" json:"status,omitempty"`
 	Message    string            `yaml:",omitempty" json:"message,omitempty"`
 	Parameters map[string]string `yaml:",omitempty" json:"parameters,omitempty"`
+	Sources    map[string]string `yaml:",omitempty" json:"sources,omitempty"`
 	Outputs    map[string]string `yaml:",omitempty" json:"outputs,omitempty"`
 	RawOutputs map[string]string `yaml:"rawOutputs,omitempty" json:"rawOutputs,omitempty"`
 }
@@ -34,13 +35,16 @@ type ExplainedState struct {
 	Status          string                        `yaml:",omitempty" json:"status,omitempty"`
 	Message         string                        `yaml:",omitempty" json:"message,omitempty"`
 	StackParameters map[string]string             `yaml:"stackParameters,omitempty" json:"stackParameters,omitempty"`
+	StackSources    map[string]string             `yaml:"stackSources,omitempty" json:"stackSources,omitempty"`
 	StackOutputs    map[string]string             `yaml:"stackOutputs,omitempty" json:"stackOutputs,omitempty"`
 	Provides        map[string][]string           `yaml:",omitempty" json:"provides,omitempty"`
 	Components      map[string]ExplainedComponent `yaml:",omitempty" json:"components,omitempty"`
 }
 
 func Explain(elaborateManifests, stateFilenames []string, opLog, global bool, componentName string, rawOutputs bool,
-	format string /*text, kv, sh, json, yaml*/, color bool) {
+	why string, format string /*text, kv, sh, json, yaml*/, color, showSecrets bool) {
+
+	explainSecrets = showSecrets
 
 	if (color || config.Tty) && format == "text" {
 		headColor = func(str string) string {
@@ -75,27 +79,23 @@ func Explain(elaborateManifests, stateFilenames []string, opLog, global bool, co
 		}
 	}
 
-	var prevOutputs []parameters.CapturedOutput
-
+	scope := []string{}
 	if componentName != "" {
+		scope = append(scope, componentName)
 		if stackManifest != nil {
-			manifest.CheckComponentsExist(stackManifest.Components, componentName)
-		}
-
-		for i, c := range components {
-			if c == componentName {
-				if i > 0 {
-					prevComponentState, exist := state.Components[components[i-1]]
-					if exist {
-						prevOutputs = prevComponentState.CapturedOutputs
-					}
-				}
-			}
+			// `forEach` component name stands for all its instances
+			scope = manifest.ExpandComponentsNames(stackManifest.Components, scope)
+			manifest.CheckComponentsExist(stackManifest.Components, scope...)
 		}
+	}
 
-		components = []string{componentName}
+	if why != "" {
+		explainParameter(state, why, scope)
+		return
 	}
 
+	components, prevOutputs := ExplainScope(state, components, scope...)
+
 	if format == "text" {
 		if global || componentName == "" {
 			fmt.Printf("Kind: %s\n", state.Meta.Kind)
@@ -121,63 +121,18 @@ func Explain(elaborateManifests, stateFilenames []string, opLog, global bool, co
 			}
 		}
 	} else {
-		explained := ExplainedState{
-			Meta:            state.Meta,
-			Timestamp:       state.Timestamp,
-			Status:          state.Status,
-			Message:         state.Message,
-			StackParameters: make(map[string]string),
-			StackOutputs:    make(map[string]string),
-			Components:      make(map[string]ExplainedComponent),
-		}
-
-		if global || componentName == "" {
-			for _, parameter := range state.StackParameters {
-				explained.StackParameters[parameter.QName()] = util.String(parameter.Value)
-			}
-			for _, output := range state.StackOutputs {
-				explained.StackOutputs[output.Name] = util.String(output.Value)
-			}
-			explained.Provides = state.Provides
-		}
-
-		if !global || componentName != "" {
-			for _, component := range components {
-				if step, exist := state.Components[component]; exist {
-					comp := ExplainedComponent{
-						Timestamp:  step.Timestamp,
-						Timestamps: step.Timestamps,
-						Status:     step.Status,
-						Message:    step.Message,
-						Parameters: make(map[string]string),
-						Outputs:    make(map[string]string),
-						RawOutputs: make(map[string]string),
-					}
-					for _, parameter := range step.Parameters {
-						comp.Parameters[parameter.Name] = util.String(parameter.Value)
-					}
-					for _, output := range DiffOutputs(step.CapturedOutputs, prevOutputs) {
-						comp.Outputs[output.Name] = util.String(output.Value)
-					}
-					prevOutputs = step.CapturedOutputs
-					if rawOutputs {
-						for _, output := range step.RawOutputs {
-							comp.RawOutputs[output.Name] = output.Value
-						}
-					}
-					explained.Components[component] = comp
-				}
-			}
+		explained, err := ExplainState(state, components, prevOutputs, global, componentName, rawOutputs)
+		if err != nil {
+			util.MaybeFatalf("%v", err)
 		}
 
 		var bytes []byte
-		var err error
 
 		switch format {
 		case "json":
-			bytes, err = json.MarshalIndent(&explained, "", "  ")
+			bytes, err = json.MarshalIndent(explained, "", "  ")
 		case "yaml":
-			bytes, err = yaml.Marshal(&explained)
+			bytes, err = yaml.Marshal(explained)
 		// case "sh":
 		default:
 			log.Fatalf("`%s` output format is not implemented", format)
@@ -194,16 +149,146 @@ func Explain(elaborateManifests, stateFilenames []string, opLog, global bool, co
 	}
 }
 
+// ExplainScope narrows components to the ones requested and returns outputs of the component preceding them
+func ExplainScope(st *StateManifest, order []string, componentNames ...string) ([]string, []parameters.CapturedOutput) {
+	names := make([]string, 0, len(componentNames))
+	for _, name := range componentNames {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return order, nil
+	}
+	scope := make([]string, 0, len(names))
+	var prevOutputs []parameters.CapturedOutput
+	for i, c := range order {
+		if util.Contains(names, c) {
+			if len(scope) == 0 && i > 0 {
+				if prevComponentState, exist := st.Components[order[i-1]]; exist {
+					prevOutputs = prevComponentState.CapturedOutputs
+				}
+			}
+			scope = append(scope, c)
+		}
+	}
+	if len(scope) == 0 {
+		return names, nil
+	}
+	return scope, prevOutputs
+}
+
+// ExplainState returns stack and components parameters and outputs with secrets masked;
+// the error is the first value that cannot be decrypted
+func ExplainState(st *StateManifest, components []string, prevOutputs []parameters.CapturedOutput,
+	global bool, componentName string, rawOutputs bool) (*ExplainedState, error) {
+
+	var err error
+	explainValue := func(name, kind string, value interface{}) string {
+		str, valueErr := tryExplainValue(name, kind, value)
+		if valueErr != nil && err == nil {
+			err = valueErr
+		}
+		return str
+	}
+
+	explained := ExplainedState{
+		Meta:            st.Meta,
+		Timestamp:       st.Timestamp,
+		Status:          st.Status,
+		Message:         st.Message,
+		StackParameters: make(map[string]string),
+		StackSources:    make(map[string]string),
+		StackOutputs:    make(map[string]string),
+		Components:      make(map[string]ExplainedComponent),
+	}
+
+	if global || componentName == "" {
+		for _, parameter := range st.StackParameters {
+			explained.StackParameters[parameter.QName()] = explainValue(parameter.Name, "", parameter.Value)
+			if parameter.Source != nil {
+				explained.StackSources[parameter.QName()] = parameter.Source.String()
+			}
+		}
+		for _, output := range st.StackOutputs {
+			explained.StackOutputs[output.Name] = explainValue(output.Name, output.Kind, output.Value)
+		}
+		explained.Provides = st.Provides
+	}
+
+	if !global || componentName != "" {
+		for _, component := range components {
+			if step, exist := st.Components[component]; exist {
+				comp := ExplainedComponent{
+					Timestamp:  step.Timestamp,
+					Timestamps: step.Timestamps,
+					Status:     step.Status,
+					Message:    step.Message,
+					Parameters: make(map[string]string),
+					Sources:    make(map[string]string),
+					Outputs:    make(map[string]string),
+					RawOutputs: make(map[string]string),
+				}
+				for _, parameter := range step.Parameters {
+					comp.Parameters[parameter.Name] = explainValue(parameter.Name, "", parameter.Value)
+					if parameter.Source != nil {
+						comp.Sources[parameter.Name] = parameter.Source.String()
+					}
+				}
+				for _, output := range DiffOutputs(step.CapturedOutputs, prevOutputs) {
+					comp.Outputs[output.Name] = explainValue(output.Name, output.Kind, output.Value)
+				}
+				prevOutputs = step.CapturedOutputs
+				if rawOutputs {
+					for _, output := range step.RawOutputs {
+						comp.RawOutputs[output.Name] = explainValue(output.Name, "", output.Value)
+					}
+				}
+				explained.Components[component] = comp
+			}
+		}
+	}
+	return &explained, err
+}
+
 var headColor = func(str string) string {
 	return str
 }
 
+var explainSecrets = false
+
+// explainValue masks secret parameters and outputs unless --show-secrets is given
+func explainValue(name, kind string, value interface{}) string {
+	str, err := tryExplainValue(name, kind, value)
+	if err != nil {
+		util.MaybeFatalf("%v", err)
+	}
+	return str
+}
+
+func tryExplainValue(name, kind string, value interface{}) (string, error) {
+	if !explainSecrets && IsSecret(name, kind) && !util.Empty(value) {
+		return "(masked)", nil
+	}
+	decrypted, err := decryptValue(value)
+	if err != nil {
+		return "", err
+	}
+	return util.String(decrypted), nil
+}
+
 func printComponenentState(componentName string, step *StateStep, prevOutputs []parameters.CapturedOutput, rawOutputs bool) {
 	fmt.Printf("-- Timestamp: %v\n", step.Timestamp.Truncate(time.Second))
 	if t := step.Timestamps; !t.End.IsZero() && !t.Start.IsZero() {
 		fmt.Printf("-- Duration: %v\n", t.End.Sub(t.Start).Round(time.Second).String())
 	}
 	fmt.Printf("-- Status: %s\n", step.Status)
+	if step.Drift != nil {
+		fmt.Printf("-- Drift: %s (checked %v)\n", step.Drift.Status, step.Drift.Timestamp.Truncate(time.Second))
+		for _, output := range step.Drift.Outputs {
+			fmt.Printf("\t%s\n", output)
+		}
+	}
 	if step.Meta.Origin != "" && step.Meta.Origin != componentName {
 		fmt.Printf("-- Origin: %s\n", step.Meta.Origin)
 	}
@@ -240,14 +325,67 @@ func printLockedParameters(parameters []parameters.LockedParameter) {
 		if parameter.Env != "" {
 			env = fmt.Sprintf(" (env:%s)", parameter.Env)
 		}
-		fmt.Printf("\t%s => `%s`%s\n", qName, util.Wrap(util.String(parameter.Value)), env)
+		source := ""
+		if parameter.Source != nil {
+			source = fmt.Sprintf(" [%s]", parameter.Source.String())
+		}
+		fmt.Printf("\t%s => `%s`%s%s\n", qName, util.Wrap(explainValue(parameter.Name, "", parameter.Value)), env, source)
+	}
+}
+
+func explainParameter(state *StateManifest, name string, componentNames []string) {
+	matches := func(parameter parameters.LockedParameter) bool {
+		return parameter.Name == name || parameter.QName() == name
+	}
+	found := false
+	printSource := func(where string, parameter parameters.LockedParameter) {
+		found = true
+		fmt.Printf("%s %s => `%s`\n", headColor(where), parameter.QName(), util.Wrap(explainValue(parameter.Name, "", parameter.Value)))
+		if parameter.Source == nil {
+			fmt.Print("\tsource is not recorded\n")
+			return
+		}
+		source := parameter.Source
+		fmt.Printf("\tmechanism: %s\n", source.Mechanism)
+		if source.File != "" {
+			if source.Line > 0 {
+				fmt.Printf("\tfile: %s:%d\n", source.File, source.Line)
+			} else {
+				fmt.Printf("\tfile: %s\n", source.File)
+			}
+		}
+		if source.Ref != "" {
+			fmt.Printf("\tref: %s\n", source.Ref)
+		}
+	}
+	if len(componentNames) == 0 {
+		for _, parameter := range state.StackParameters {
+			if matches(parameter) {
+				printSource("Stack parameter:", parameter)
+			}
+		}
+	}
+	for _, component := range state.Lifecycle.Order {
+		if len(componentNames) > 0 && !util.Contains(componentNames, component) {
+			continue
+		}
+		if step, exist := state.Components[component]; exist {
+			for _, parameter := range step.Parameters {
+				if matches(parameter) {
+					printSource(fmt.Sprintf("Component %s parameter:", component), parameter)
+				}
+			}
+		}
+	}
+	if !found {
+		fmt.Printf("Parameter `%s` not found in state\n", name)
 	}
 }
 
 func printDiffOutputs(curr, prev []parameters.CapturedOutput) {
 	keys := make(map[string]string)
 	for _, p := range prev {
-		str := util.String(p.Value)
+		str := explainValue(p.Name, p.Kind, p.Value)
 		keys[p.QName()] = str
 		keys[p.Name] = str
 	}
@@ -267,10 +405,11 @@ func printDiffOutputs(curr, prev []parameters.CapturedOutput) {
 			if c.Brief != "" {
 				brief = fmt.Sprintf(" [%s]", c.Brief)
 			}
-			value := util.Wrap(util.String(c.Value))
+			str := explainValue(c.Name, c.Kind, c.Value)
+			value := util.Wrap(str)
 			if !overExist {
 				fmt.Printf("\t%s%s%s => `%s`\n", kind, c.Name, brief, value)
-			} else if util.String(c.Value) != over {
+			} else if str != over {
 				fmt.Printf("\t%s%s%s => `%s` (was: `%s`)\n", kind, c.Name, brief, value, util.Wrap(over))
 			} else {
 				fmt.Printf("\t%s%s%s => `%s`\n", kind, qName, brief, value)
@@ -302,7 +441,7 @@ func DiffOutputs(curr, prev []parameters.CapturedOutput) []parameters.CapturedOu
 
 func printRawOutputs(rawOutputs []parameters.RawOutput) {
 	for _, o := range rawOutputs {
-		fmt.Printf("\t%s = %s\n", o.Name, o.Value)
+		fmt.Printf("\t%s = %s\n", o.Name, explainValue(o.Name, "", o.Value))
 	}
 }
 
@@ -318,7 +457,8 @@ func printStackOutputs(expanded []parameters.ExpandedOutput) {
 			if expandedOutput.Kind != "" {
 				kind = fmt.Sprintf("[%s] ", expandedOutput.Kind)
 			}
-			fmt.Printf("\t%s%s%s = %s\n", brief, kind, expandedOutput.Name, expandedOutput.Value)
+			fmt.Printf("\t%s%s%s = %s\n", brief, kind, expandedOutput.Name,
+				explainValue(expandedOutput.Name, expandedOutput.Kind, expandedOutput.Value))
 		}
 	}
 }
@@ -133,6 +133,7 @@ func mergeStateParameters(parameters parameters.LockedParameters, add []paramete
 }
 
 func mergeStateParameter(parameters parameters.LockedParameters, add parameters.LockedParameter) {
+	add.Value = DecryptValue(add.Value)
 	qName := add.QName()
 	current, exists := parameters[qName]
 	if exists {
@@ -143,6 +144,7 @@ func mergeStateParameter(parameters parameters.LockedParameters, add parameters.
 				util.Warn("Parameter `%s` empty value is replaced by value `%s` from state",
 					qName, util.Trim(util.MaybeMaskedValue(config.Trace, qName, addValue)))
 				current.Value = add.Value
+				current.Source = add.Source
 			} else {
 				util.Warn("Parameter `%s` current value `%s` does not match value `%s` from state - keeping current value",
 					qName,
@@ -170,7 +172,7 @@ func mergeStateParameter(parameters parameters.LockedParameters, add parameters.
 }
 
 func mergeStateOutputs(outputs parameters.CapturedOutputs, state []parameters.CapturedOutput) {
-	for _, o := range state {
+	for _, o := range DecryptOutputs(state) {
 		parameters.MergeOutput(outputs, o)
 	}
 }
@@ -190,7 +192,7 @@ func mergeStateOutputsFromDependencies(outputs parameters.CapturedOutputs, depen
 	}
 	for _, dependencyName := range util.Reverse(loading) {
 		if dependency, exist := components[dependencyName]; exist {
-			for _, output := range dependency.CapturedOutputs {
+			for _, output := range DecryptOutputs(dependency.CapturedOutputs) {
 				qName := output.QName()
 				current, exists := outputs[qName]
 				overwrite := false
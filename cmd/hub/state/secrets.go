@@ -0,0 +1,166 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/agilestacks/hub/cmd/hub/crypto"
+	"github.com/agilestacks/hub/cmd/hub/parameters"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+func IsSecret(name, kind string) bool {
+	return strings.HasPrefix(kind, "secret") || util.LooksLikeSecret(name)
+}
+
+// DecryptValue returns clear-text value of a secret parameter or output that was encrypted in state,
+// other values are returned as is
+func DecryptValue(value interface{}) interface{} {
+	decrypted, err := decryptValue(value)
+	if err != nil {
+		util.MaybeFatalf("%v", err)
+		return ""
+	}
+	return decrypted
+}
+
+func decryptValue(value interface{}) (interface{}, error) {
+	str, ok := value.(string)
+	if !ok || !crypto.IsEncryptedValue(str) {
+		return value, nil
+	}
+	decrypted, err := crypto.DecryptAnyValue(str)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decrypt state value: %v", err)
+	}
+	return decrypted, nil
+}
+
+func DecryptParameters(list []parameters.LockedParameter) []parameters.LockedParameter {
+	decrypted := make([]parameters.LockedParameter, 0, len(list))
+	for _, p := range list {
+		p.Value = DecryptValue(p.Value)
+		decrypted = append(decrypted, p)
+	}
+	return decrypted
+}
+
+func DecryptOutputs(list []parameters.CapturedOutput) []parameters.CapturedOutput {
+	decrypted := make([]parameters.CapturedOutput, 0, len(list))
+	for _, o := range list {
+		o.Value = DecryptValue(o.Value)
+		decrypted = append(decrypted, o)
+	}
+	return decrypted
+}
+
+func encryptValue(name, kind string, value interface{}) (interface{}, error) {
+	if !IsSecret(name, kind) || util.Empty(value) {
+		return value, nil
+	}
+	if str, ok := value.(string); ok {
+		return crypto.EncryptValue(str)
+	}
+	return crypto.EncryptYamlValue(value)
+}
+
+// encryptSecrets returns a copy of the state with secret parameters and outputs values encrypted,
+// in-memory state is kept in clear-text
+func encryptSecrets(manifest *StateManifest) (*StateManifest, error) {
+	return transformSecrets(manifest, encryptValue)
+}
+
+// MaskSecrets returns a copy of the state with secret parameters and outputs values masked
+func MaskSecrets(manifest *StateManifest) *StateManifest {
+	masked, _ := transformSecrets(manifest, func(name, kind string, value interface{}) (interface{}, error) {
+		if IsSecret(name, kind) && !util.Empty(value) {
+			return "(masked)", nil
+		}
+		return value, nil
+	})
+	return masked
+}
+
+type secretTransform func(name, kind string, value interface{}) (interface{}, error)
+
+func transformSecrets(manifest *StateManifest, transform secretTransform) (*StateManifest, error) {
+	transformed := *manifest
+	var err error
+	transformed.StackParameters, err = transformParameters(manifest.StackParameters, transform)
+	if err != nil {
+		return nil, err
+	}
+	transformed.CapturedOutputs, err = transformOutputs(manifest.CapturedOutputs, transform)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.StackOutputs != nil {
+		transformed.StackOutputs = make([]parameters.ExpandedOutput, 0, len(manifest.StackOutputs))
+		for _, o := range manifest.StackOutputs {
+			o.Value, err = transform(o.Name, o.Kind, o.Value)
+			if err != nil {
+				return nil, err
+			}
+			transformed.StackOutputs = append(transformed.StackOutputs, o)
+		}
+	}
+	if manifest.Components != nil {
+		transformed.Components = make(map[string]*StateStep, len(manifest.Components))
+		for name, step := range manifest.Components {
+			transformedStep := *step
+			transformedStep.Parameters, err = transformParameters(step.Parameters, transform)
+			if err != nil {
+				return nil, err
+			}
+			transformedStep.CapturedOutputs, err = transformOutputs(step.CapturedOutputs, transform)
+			if err != nil {
+				return nil, err
+			}
+			if step.RawOutputs != nil {
+				transformedStep.RawOutputs = make([]parameters.RawOutput, 0, len(step.RawOutputs))
+				for _, o := range step.RawOutputs {
+					value, err := transform(o.Name, "", o.Value)
+					if err != nil {
+						return nil, err
+					}
+					o.Value = util.String(value)
+					transformedStep.RawOutputs = append(transformedStep.RawOutputs, o)
+				}
+			}
+			transformed.Components[name] = &transformedStep
+		}
+	}
+	return &transformed, nil
+}
+
+func transformParameters(list []parameters.LockedParameter, transform secretTransform) ([]parameters.LockedParameter, error) {
+	if list == nil {
+		return nil, nil
+	}
+	transformed := make([]parameters.LockedParameter, 0, len(list))
+	for _, p := range list {
+		var err error
+		p.Value, err = transform(p.Name, "", p.Value)
+		if err != nil {
+			return nil, err
+		}
+		transformed = append(transformed, p)
+	}
+	return transformed, nil
+}
+
+func transformOutputs(list []parameters.CapturedOutput, transform secretTransform) ([]parameters.CapturedOutput, error) {
+	if list == nil {
+		return nil, nil
+	}
+	transformed := make([]parameters.CapturedOutput, 0, len(list))
+	for _, o := range list {
+		var err error
+		o.Value, err = transform(o.Name, o.Kind, o.Value)
+		if err != nil {
+			return nil, err
+		}
+		transformed = append(transformed, o)
+	}
+	return transformed, nil
+}
@@ -41,6 +41,15 @@ type StateStep struct {
 	Parameters      []parameters.LockedParameter `yaml:",omitempty"`
 	RawOutputs      []parameters.RawOutput       `yaml:"rawOutputs,omitempty"`
 	CapturedOutputs []parameters.CapturedOutput  `yaml:"capturedOutputs,omitempty"`
+	Drift           *DriftStatus                 `yaml:",omitempty"`
+}
+
+// DriftStatus is recorded by `hub check --record`
+type DriftStatus struct {
+	Timestamp time.Time
+	Status    string   // in-sync, drifted, error
+	Outputs   []string `yaml:",omitempty"`
+	Message   string   `yaml:",omitempty"`
 }
 
 type LifecyclePhase struct {
@@ -93,6 +102,9 @@ type BackupManifest struct {
 	Timestamp  time.Time                  `json:"timestamp"`
 	Status     string                     `json:"status"`
 	Components map[string]ComponentBackup `json:"components"`
+	Previous   string                     `yaml:",omitempty" json:"previous,omitempty"`
+	Checksum   string                     `yaml:",omitempty" json:"checksum,omitempty"`
+	Signature  string                     `yaml:",omitempty" json:"signature,omitempty"`
 
 	// not present in backup bundle, used for diagnostic in `backup unbundle`
 	Source string `yaml:",omitempty" json:"source,omitempty"`
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -11,9 +12,11 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/crypto"
 	"github.com/agilestacks/hub/cmd/hub/manifest"
 	"github.com/agilestacks/hub/cmd/hub/parameters"
 	"github.com/agilestacks/hub/cmd/hub/storage"
+	"github.com/agilestacks/hub/cmd/hub/tracing"
 	"github.com/agilestacks/hub/cmd/hub/util"
 )
 
@@ -27,6 +30,7 @@ func InitWriter(stateFiles *storage.Files, atWrite func(*StateManifest)) func(in
 	ticker := time.NewTicker(1 * time.Second)
 	go writer(ch, done, ticker.C, stateFiles, atWrite)
 	update := func(v interface{}) {
+		util.CheckAbort()
 		ch <- v
 		if cmd, ok := v.(string); ok && cmd == "done" {
 			ticker.Stop()
@@ -47,8 +51,11 @@ func writer(ch <-chan interface{}, done chan<- struct{}, ticker <-chan time.Time
 	var state *StateManifest
 
 	maybeWrite := func() {
+		defer util.RecoverAbort()
 		if pending && state != nil {
+			_, span := tracing.Start(context.Background(), "state write")
 			err := WriteState(state, files)
+			span.EndWithError(err)
 			if err != nil {
 				log.Printf("%v", err)
 			}
@@ -56,7 +63,7 @@ func writer(ch <-chan interface{}, done chan<- struct{}, ticker <-chan time.Time
 				atWrite(state)
 			}
 			if err != nil {
-				os.Exit(2)
+				util.Exit(2)
 			}
 			pending = false
 		}
@@ -113,6 +120,7 @@ func UpdateState(manifest *StateManifest, componentName string,
 	componentState.Timestamp = now
 	componentState.Parameters = componentParameters
 	componentState.CapturedOutputs = parameters.CapturedOutputsToList(outputs)
+	componentState.Drift = nil // outputs are fresh
 	if len(rawOutputs) > 0 {
 		componentState.RawOutputs = parameters.RawOutputsToList(rawOutputs)
 	}
@@ -292,6 +300,14 @@ func WriteState(manifest *StateManifest, stateFiles *storage.Files) error {
 	manifest.Version = 1
 	manifest.Kind = "state"
 
+	if crypto.CurrentKeySetup().IsSet() {
+		var err error
+		manifest, err = encryptSecrets(manifest)
+		if err != nil {
+			return fmt.Errorf("Unable to encrypt state secrets: %v", err)
+		}
+	}
+
 	yamlBytes, err := yaml.Marshal(manifest)
 	if err != nil {
 		return fmt.Errorf("Unable to marshal state into YAML: %v", err)
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/agilestacks/hub/cmd/hub/aws"
+	"github.com/agilestacks/hub/cmd/hub/azure"
+	"github.com/agilestacks/hub/cmd/hub/gcp"
+)
+
+// List returns files under prefix sorted by modification time, newest first.
+// Prefix is a directory (ends with /) or a file name prefix: backups/, s3://bucket/backups/app-
+func List(prefix, kind string) ([]File, error) {
+	location, err := checkPath(prefix, kind)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]File, 0)
+	object := func(path string, size int64, modTime time.Time) {
+		files = append(files, File{Kind: location.Kind, Path: path, Exist: true, Size: size, ModTime: modTime})
+	}
+	switch location.Kind {
+	case "fs":
+		var paths []string
+		paths, err = filepath.Glob(globEscape(prefix) + "*")
+		for _, path := range paths {
+			info, statErr := os.Stat(path)
+			if statErr == nil && info.Mode().IsRegular() {
+				object(path, info.Size(), info.ModTime())
+			}
+		}
+	case "s3":
+		err = aws.ListS3(prefix, object)
+	case "gs":
+		err = gcp.ListGCS(prefix, object)
+	case "az":
+		err = azure.ListStorageBlobs(prefix, object)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list `%s` %s files: %v", prefix, kind, err)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].ModTime.After(files[j].ModTime)
+	})
+	return files, nil
+}
+
+func globEscape(path string) string {
+	for _, meta := range []string{"\\", "*", "?", "["} {
+		path = strings.ReplaceAll(path, meta, "\\"+meta)
+	}
+	return path
+}
+
+func Remove(file File) error {
+	var err error
+	switch file.Kind {
+	case "fs":
+		err = os.Remove(file.Path)
+	case "s3":
+		err = aws.DeleteS3(file.Path)
+	case "gs":
+		err = gcp.DeleteGCS(file.Path)
+	case "az":
+		err = azure.DeleteStorageBlob(file.Path)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to remove `%s`: %v", file.Path, err)
+	}
+	return nil
+}
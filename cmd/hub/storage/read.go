@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -182,7 +183,9 @@ func EnsureNoLockFiles(files *Files) {
 	}
 }
 
-func chooseFile(files *Files) (*File, error) {
+// chooseFile returns the most recent file; a local copy is preferred if it has the same content.
+// If remote file is read to make the decision, its data is returned too.
+func chooseFile(files *Files) (*File, []byte, error) {
 	delta := time.Duration(-10) * time.Second
 
 	filesExist := make([]File, 0, len(files.Files))
@@ -193,10 +196,10 @@ func chooseFile(files *Files) (*File, error) {
 	}
 
 	if len(filesExist) == 0 {
-		return nil, os.ErrNotExist
+		return nil, nil, os.ErrNotExist
 	}
 	if len(filesExist) == 1 {
-		return &filesExist[0], nil
+		return &filesExist[0], nil, nil
 	}
 
 	modTime := filesExist[0].ModTime
@@ -214,7 +217,7 @@ func chooseFile(files *Files) (*File, error) {
 	}
 
 	if len(candidates) == 1 {
-		return &candidates[0], nil
+		return &candidates[0], nil, nil
 	}
 
 	largest := candidates[0]
@@ -224,19 +227,53 @@ func chooseFile(files *Files) (*File, error) {
 		}
 	}
 	if largest.Kind == "fs" {
-		return &largest, nil
+		return &largest, nil, nil
 	}
+	var largestData []byte
 	for _, file := range candidates {
-		if file.Kind == "fs" &&
-			(file.Size == largest.Size ||
-				file.Size+crypto.EncryptionV1Overhead == largest.Size ||
-				file.Size+crypto.EncryptionV2Overhead == largest.Size ||
-				file.Size+crypto.EncryptionV3Overhead == largest.Size) {
-			return &file, nil
+		if file.Kind != "fs" {
+			continue
+		}
+		if file.Size == largest.Size {
+			return &file, nil, nil
+		}
+		if largestData == nil {
+			data, err := readFile(&largest)
+			if err != nil {
+				return nil, nil, err
+			}
+			largestData = data
+		}
+		if sameContent(&file, largestData) {
+			return &file, nil, nil
 		}
 	}
 
-	return &largest, nil
+	return &largest, largestData, nil
+}
+
+// sameContent compares local file to remote data which is usually encrypted: by exact encryption
+// overhead computed from the header, otherwise by decrypted content
+func sameContent(file *File, remoteData []byte) bool {
+	if overhead, err := crypto.EncryptionOverhead(remoteData); err == nil && int64(len(remoteData)-overhead) == file.Size {
+		return true
+	}
+	data, err := readFile(file)
+	if err != nil {
+		return false
+	}
+	data, err = decode(data, file.Path)
+	if err != nil {
+		return false
+	}
+	remoteData, err = decode(remoteData, "")
+	if err != nil {
+		if config.Debug {
+			log.Printf("Unable to compare `%s` to remote file: %v", file.Path, err)
+		}
+		return false
+	}
+	return bytes.Equal(data, remoteData)
 }
 
 func readFile(file *File) ([]byte, error) {
@@ -263,32 +300,42 @@ func readFile(file *File) ([]byte, error) {
 }
 
 func chooseAndReadFile(files *Files) ([]byte, string, error) {
-	file, err := chooseFile(files)
+	file, data, err := chooseFile(files)
 	if err != nil {
 		return nil, "", err
 	}
-	data, err := readFile(file)
+	if data == nil {
+		data, err = readFile(file)
+	}
 	return data, file.Path, err
 }
 
-func Read(files *Files) ([]byte, string, error) {
-	data, path, err := chooseAndReadFile(files)
-	if err != nil {
-		return nil, "", err
-	}
-
+func decode(data []byte, path string) ([]byte, error) {
+	var err error
 	if crypto.IsEncryptedData(data) {
 		data, err = crypto.Decrypt(data)
 		if err != nil {
-			return nil, "", fmt.Errorf("Unable to decrypt `%s`: %v", path, err)
+			return nil, fmt.Errorf("Unable to decrypt `%s`: %v", path, err)
 		}
 	}
 	if util.IsGzipData(data) {
 		data, err = util.Gunzip(data)
 		if err != nil {
-			return nil, "", fmt.Errorf("Unable to gunzip `%s`: %v", path, err)
+			return nil, fmt.Errorf("Unable to gunzip `%s`: %v", path, err)
 		}
 	}
+	return data, nil
+}
+
+func Read(files *Files) ([]byte, string, error) {
+	data, path, err := chooseAndReadFile(files)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err = decode(data, path)
+	if err != nil {
+		return nil, "", err
+	}
 	if config.Verbose {
 		log.Printf("Read `%s` %s file", path, files.Kind)
 	}
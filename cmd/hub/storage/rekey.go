@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/agilestacks/hub/cmd/hub/aws"
+	"github.com/agilestacks/hub/cmd/hub/azure"
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/crypto"
+	"github.com/agilestacks/hub/cmd/hub/gcp"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+// Rekey re-encrypts file content in place with the new key setup, including individually encrypted
+// `encrypted:` values of YAML documents, such as secrets in state, whether the file is encrypted or not.
+// Returns encryption mode of the file (or values) before rekey; files without encrypted data are left as is.
+// On dry run the data is decrypted with current key but not written.
+func Rekey(file *File, setup crypto.KeySetup, dryRun bool) (string, error) {
+	data, err := readFile(file)
+	if err != nil {
+		return "", err
+	}
+	mode := crypto.EncryptionMode(data)
+	encrypted := crypto.IsEncryptedData(data)
+	clear := data
+	if encrypted {
+		clear, err = crypto.Decrypt(data)
+		if err != nil {
+			return mode, fmt.Errorf("Unable to decrypt `%s` with current key: %v", file.Path, err)
+		}
+	}
+	compressed := util.IsGzipData(clear)
+	if compressed {
+		clear, err = util.Gunzip(clear)
+		if err != nil {
+			return mode, fmt.Errorf("Unable to gunzip `%s`: %v", file.Path, err)
+		}
+	}
+	rekeyed, valuesMode, err := rekeyValues(clear, setup, dryRun)
+	if err != nil {
+		return mode, fmt.Errorf("Unable to rekey `%s` values: %v", file.Path, err)
+	}
+	if !encrypted {
+		if rekeyed == nil {
+			return mode, nil
+		}
+		mode = valuesMode
+	}
+	if dryRun {
+		return mode, nil
+	}
+	if rekeyed == nil {
+		data, err = crypto.Rekey(data, setup)
+	} else {
+		data = rekeyed
+		if compressed {
+			data, err = util.Gzip(data)
+		}
+		if err == nil && encrypted {
+			data, err = crypto.EncryptWithKeySetup(data, setup)
+		}
+	}
+	if err != nil {
+		return mode, fmt.Errorf("Unable to rekey `%s`: %v", file.Path, err)
+	}
+	err = writeFile(file, data)
+	if err != nil {
+		return mode, fmt.Errorf("Unable to write `%s`: %v", file.Path, err)
+	}
+	return mode, nil
+}
+
+// rekeyValues re-encrypts `encrypted:` string values found in YAML documents with the new key setup.
+// Returns nil if there are no such values, or data is not YAML.
+func rekeyValues(data []byte, setup crypto.KeySetup, dryRun bool) ([]byte, string, error) {
+	trimmed := bytes.TrimSpace(data)
+	if !bytes.Contains(data, []byte("encrypted:")) || bytes.HasPrefix(trimmed, []byte("{")) {
+		return nil, "", nil
+	}
+	documents := make([]yaml.MapSlice, 0, 1)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document yaml.MapSlice
+		err := decoder.Decode(&document)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", nil
+		}
+		documents = append(documents, document)
+	}
+	mode := ""
+	var rekey func(interface{}) (interface{}, error)
+	rekey = func(value interface{}) (interface{}, error) {
+		switch v := value.(type) {
+		case string:
+			if !crypto.IsEncryptedValue(v) {
+				return v, nil
+			}
+			if mode == "" {
+				mode = crypto.ValueEncryptionMode(v)
+			}
+			if dryRun {
+				_, err := crypto.DecryptValue(v)
+				return v, err
+			}
+			return crypto.RekeyValue(v, setup)
+		case yaml.MapSlice:
+			for i := range v {
+				rekeyed, err := rekey(v[i].Value)
+				if err != nil {
+					return nil, err
+				}
+				v[i].Value = rekeyed
+			}
+		case []interface{}:
+			for i := range v {
+				rekeyed, err := rekey(v[i])
+				if err != nil {
+					return nil, err
+				}
+				v[i] = rekeyed
+			}
+		}
+		return value, nil
+	}
+	var rekeyed bytes.Buffer
+	for i, document := range documents {
+		_, err := rekey(document)
+		if err != nil {
+			return nil, mode, err
+		}
+		out, err := yaml.Marshal(document)
+		if err != nil {
+			return nil, mode, err
+		}
+		if i > 0 {
+			rekeyed.WriteString("---\n")
+		}
+		rekeyed.Write(out)
+	}
+	if mode == "" {
+		return nil, "", nil
+	}
+	return rekeyed.Bytes(), mode, nil
+}
+
+func writeFile(file *File, data []byte) error {
+	switch file.Kind {
+	case "fs":
+		mode := os.FileMode(0644)
+		if info, err := os.Stat(file.Path); err == nil {
+			mode = info.Mode().Perm()
+		}
+		return ioutil.WriteFile(file.Path, data, mode)
+	case "s3":
+		return aws.WriteS3(file.Path, data)
+	case "gs":
+		return gcp.WriteGCS(file.Path, data)
+	case "az":
+		return azure.WriteStorageBlob(file.Path, data)
+	}
+	return fmt.Errorf("Unsupported storage kind `%s`", file.Kind)
+}
+
+// RekeyFiles re-encrypts files with the new key setup. Path that ends with / or * is a prefix
+// to list files under, for example s3://bucket/backups/ or states/app-*
+func RekeyFiles(paths []string, setup crypto.KeySetup, dryRun bool) {
+	files := make([]File, 0, len(paths))
+	var errs []error
+	for _, path := range paths {
+		if strings.HasSuffix(path, "/") || strings.HasSuffix(path, "*") {
+			listed, err := List(strings.TrimSuffix(path, "*"), "encrypted")
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if len(listed) == 0 {
+				util.Warn("No files found under `%s`", path)
+			}
+			files = append(files, listed...)
+			continue
+		}
+		checked, checkErrs := Check([]string{path}, "encrypted")
+		if len(checkErrs) > 0 {
+			errs = append(errs, checkErrs...)
+			continue
+		}
+		for _, file := range checked.Files {
+			if !file.Exist {
+				errs = append(errs, fmt.Errorf("`%s` does not exist", file.Path))
+				continue
+			}
+			files = append(files, file)
+		}
+	}
+
+	rekeyed := 0
+	for i := range files {
+		file := &files[i]
+		mode, err := Rekey(file, setup, dryRun)
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+		case mode == "none":
+			if config.Verbose {
+				log.Printf("Skipping `%s`: not encrypted", file.Path)
+			}
+		case dryRun:
+			if setup.IsSet() {
+				log.Printf("Would rekey `%s` (%s -> %s)", file.Path, mode, setup.Mode())
+			} else {
+				log.Printf("Would rekey `%s` (%s)", file.Path, mode)
+			}
+			rekeyed++
+		default:
+			log.Printf("Rekeyed `%s` (%s -> %s)", file.Path, mode, setup.Mode())
+			rekeyed++
+		}
+	}
+	if config.Verbose {
+		verb := "Rekeyed"
+		if dryRun {
+			verb = "Would rekey"
+		}
+		log.Printf("%s %d of %d file(s)", verb, rekeyed, len(files))
+	}
+	if len(errs) > 0 {
+		util.MaybeFatalf("Rekey failed:\n\t%s", util.Errors("\n\t", errs...))
+	}
+}
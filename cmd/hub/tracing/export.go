@@ -0,0 +1,208 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+const (
+	otlpTracesPath     = "/v1/traces"
+	statusCodeUnset    = 0
+	statusCodeError    = 2
+	defaultServiceName = "hub-cli"
+)
+
+var httpClient = util.RobustHttpClient(0, false)
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func export(spans []*Span) {
+	body, err := json.Marshal(encode(spans))
+	if err != nil {
+		util.Warn("Unable to marshal traces: %v", err)
+		return
+	}
+	if config.TracingFile != "" {
+		err = appendFile(config.TracingFile, body)
+		if err != nil {
+			util.Warn("Unable to write traces to `%s`: %v", config.TracingFile, err)
+		} else if config.Debug {
+			log.Printf("Wrote %d trace spans to `%s`", len(spans), config.TracingFile)
+		}
+	}
+	if config.TracingEndpoint != "" {
+		endpoint := tracesEndpoint(config.TracingEndpoint)
+		err = post(endpoint, body)
+		if err != nil {
+			util.Warn("Unable to export traces to %s: %v", endpoint, err)
+		} else if config.Debug {
+			log.Printf("Exported %d trace spans to %s", len(spans), endpoint)
+		}
+	}
+}
+
+func encode(spans []*Span) *otlpTraces {
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	scope := otlpScopeSpans{Spans: make([]otlpSpan, 0, len(spans))}
+	scope.Scope.Name = "github.com/agilestacks/hub"
+	for _, span := range spans {
+		scope.Spans = append(scope.Spans, encodeSpan(span))
+	}
+	resource := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scope}}
+	resource.Resource.Attributes = []otlpKeyValue{attribute("service.name", serviceName)}
+	return &otlpTraces{ResourceSpans: []otlpResourceSpans{resource}}
+}
+
+func encodeSpan(span *Span) otlpSpan {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	encoded := otlpSpan{
+		TraceId:           hex.EncodeToString(span.traceId[:]),
+		SpanId:            hex.EncodeToString(span.spanId[:]),
+		Name:              span.name,
+		Kind:              span.kind,
+		StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+		Status:            otlpStatus{Code: statusCodeUnset},
+	}
+	if span.parentId != [8]byte{} {
+		encoded.ParentSpanId = hex.EncodeToString(span.parentId[:])
+	}
+	if span.err != "" {
+		encoded.Status = otlpStatus{Code: statusCodeError, Message: span.err}
+	}
+	keys := make([]string, 0, len(span.attributes))
+	for key := range span.attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		encoded.Attributes = append(encoded.Attributes, attribute(key, span.attributes[key]))
+	}
+	return encoded
+}
+
+func attribute(key string, value interface{}) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: anyValue(value)}
+}
+
+func anyValue(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case string:
+		return map[string]interface{}{"stringValue": v}
+	case bool:
+		return map[string]interface{}{"boolValue": v}
+	case int:
+		return map[string]interface{}{"intValue": strconv.Itoa(v)}
+	case int64:
+		return map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]interface{}{"doubleValue": v}
+	case []string:
+		values := make([]map[string]interface{}, 0, len(v))
+		for _, s := range v {
+			values = append(values, anyValue(s))
+		}
+		return map[string]interface{}{"arrayValue": map[string]interface{}{"values": values}}
+	}
+	return map[string]interface{}{"stringValue": fmt.Sprintf("%v", value)}
+}
+
+func tracesEndpoint(endpoint string) string {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if strings.HasSuffix(endpoint, otlpTracesPath) {
+		return endpoint
+	}
+	return endpoint + otlpTracesPath
+}
+
+func post(endpoint string, body []byte) error {
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// OTEL_EXPORTER_OTLP_HEADERS=api-key=secret,other=value
+	for _, header := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		kv := strings.SplitN(header, "=", 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) != "" {
+			req.Header.Set(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+		}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%d HTTP", resp.StatusCode)
+	}
+	return nil
+}
+
+func appendFile(filename string, line []byte) error {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if err2 := file.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
@@ -0,0 +1,215 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agilestacks/hub/cmd/hub/config"
+	"github.com/agilestacks/hub/cmd/hub/util"
+)
+
+// A minimal OpenTelemetry compatible tracer: one trace per lifecycle operation, spans are buffered
+// and exported at the end of operation in OTLP/HTTP JSON encoding to --tracing-endpoint and / or
+// appended as JSON lines to --tracing-file.
+
+const TraceParentEnvVarName = "TRACEPARENT"
+
+const (
+	KindInternal = 1
+	KindClient   = 3
+)
+
+type Span struct {
+	traceId    [16]byte
+	spanId     [8]byte
+	parentId   [8]byte
+	name       string
+	kind       int
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	err        string
+	lock       sync.Mutex
+}
+
+type spanKey struct{}
+
+var (
+	lock   sync.Mutex
+	root   *Span
+	active = make(map[*Span]struct{})
+	ended  []*Span
+)
+
+func Enabled() bool {
+	return config.TracingEndpoint != "" || config.TracingFile != ""
+}
+
+// StartOperation starts a new trace, or joins the trace of the parent process passed in TRACEPARENT
+func StartOperation(ctx context.Context, name string) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+	span := &Span{name: name, kind: KindInternal, start: time.Now()}
+	if traceId, parentId, ok := parseTraceParent(os.Getenv(TraceParentEnvVarName)); ok {
+		span.traceId = traceId
+		span.parentId = parentId
+	} else {
+		rand.Read(span.traceId[:])
+	}
+	rand.Read(span.spanId[:])
+	lock.Lock()
+	root = span
+	active[span] = struct{}{}
+	lock.Unlock()
+	util.AtDone(flush)
+	if config.Debug {
+		log.Printf("Tracing %s: trace id %s", name, hex.EncodeToString(span.traceId[:]))
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Start starts a child span of the span in context, or of the current operation;
+// there are no spans outside of an operation
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return StartKind(ctx, name, KindInternal)
+}
+
+func StartKind(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	span := &Span{traceId: parent.traceId, parentId: parent.spanId, name: name, kind: kind, start: time.Now()}
+	rand.Read(span.spanId[:])
+	lock.Lock()
+	active[span] = struct{}{}
+	lock.Unlock()
+	return ContextWithSpan(ctx, span), span
+}
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func FromContext(ctx context.Context) *Span {
+	if span, ok := ctx.Value(spanKey{}).(*Span); ok {
+		return span
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	return root
+}
+
+// TraceParent returns W3C Trace Context `traceparent` value of the span in context, if any
+func TraceParent(ctx context.Context) string {
+	span := FromContext(ctx)
+	if span == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(span.traceId[:]), hex.EncodeToString(span.spanId[:]))
+}
+
+func TraceParentEnv(ctx context.Context) []string {
+	if traceParent := TraceParent(ctx); traceParent != "" {
+		return []string{fmt.Sprintf("%s=%s", TraceParentEnvVarName, traceParent)}
+	}
+	return nil
+}
+
+func (span *Span) SetAttribute(key string, value interface{}) {
+	if span == nil {
+		return
+	}
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	if span.attributes == nil {
+		span.attributes = make(map[string]interface{})
+	}
+	span.attributes[key] = value
+}
+
+func (span *Span) SetError(err error) {
+	if span == nil || err == nil {
+		return
+	}
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	span.err = err.Error()
+}
+
+func (span *Span) End() {
+	if span == nil {
+		return
+	}
+	span.lock.Lock()
+	if !span.end.IsZero() {
+		span.lock.Unlock()
+		return
+	}
+	span.end = time.Now()
+	span.lock.Unlock()
+	lock.Lock()
+	delete(active, span)
+	ended = append(ended, span)
+	lock.Unlock()
+}
+
+// EndWithError is a shortcut for the common `defer` pattern
+func (span *Span) EndWithError(err error) {
+	span.SetError(err)
+	span.End()
+}
+
+// flush ends spans of the operation aborted by a fatal error and exports the spans
+func flush() <-chan struct{} {
+	lock.Lock()
+	aborted := make([]*Span, 0, len(active))
+	for span := range active {
+		aborted = append(aborted, span)
+	}
+	lock.Unlock()
+	for _, span := range aborted {
+		span.lock.Lock()
+		if span.err == "" {
+			span.err = "Aborted"
+		}
+		span.lock.Unlock()
+		span.End()
+	}
+	lock.Lock()
+	spans := ended
+	ended = nil
+	root = nil
+	lock.Unlock()
+	if len(spans) > 0 {
+		export(spans)
+	}
+	return nil
+}
+
+func parseTraceParent(traceParent string) ([16]byte, [8]byte, bool) {
+	var traceId [16]byte
+	var parentId [8]byte
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return traceId, parentId, false
+	}
+	if _, err := hex.Decode(traceId[:], []byte(parts[1])); err != nil {
+		return traceId, parentId, false
+	}
+	if _, err := hex.Decode(parentId[:], []byte(parts[2])); err != nil {
+		return traceId, parentId, false
+	}
+	return traceId, parentId, traceId != [16]byte{} && parentId != [8]byte{}
+}
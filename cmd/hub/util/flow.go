@@ -4,11 +4,59 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sync"
 
 	"github.com/agilestacks/hub/cmd/hub/config"
 )
 
-var atDone []func() <-chan struct{}
+var (
+	atDone     []func() <-chan struct{}
+	atDoneLock sync.Mutex
+)
+
+// Exit is replaced by `hub serve` to abort the current job instead of the process, see AbortJobs
+var Exit = os.Exit
+
+// in `hub serve` log.Fatal* and Exit panic, goroutines hand the panic over to the operation
+var (
+	abort     interface{}
+	abortLock sync.Mutex
+	handOver  bool
+)
+
+// AbortJobs installs exit that panics instead of terminating the process, and enables
+// hand over of goroutine panics to the operation via RecoverAbort and CheckAbort
+func AbortJobs(exit func(int)) {
+	Exit = exit
+	handOver = true
+}
+
+// RecoverAbort must be deferred by goroutines that may call log.Fatal* or Exit,
+// the panic is raised again by CheckAbort in the operation goroutine.
+// Outside of `hub serve` the panic is not recovered and crashes the process as usual.
+func RecoverAbort() {
+	if !handOver {
+		return
+	}
+	if p := recover(); p != nil {
+		abortLock.Lock()
+		if abort == nil {
+			abort = p
+		}
+		abortLock.Unlock()
+	}
+}
+
+// CheckAbort panics if a goroutine was aborted, see RecoverAbort
+func CheckAbort() {
+	abortLock.Lock()
+	p := abort
+	abort = nil
+	abortLock.Unlock()
+	if p != nil {
+		panic(p)
+	}
+}
 
 func MaybeFatalf(format string, v ...interface{}) {
 	if config.Force {
@@ -31,23 +79,28 @@ func MaybeFatalf2(cleanup func(string, bool), format string, v ...interface{}) {
 	}
 	if !config.Force {
 		Done()
-		os.Exit(1)
+		Exit(1)
 	}
 }
 
 func AtDone(cleanup func() <-chan struct{}) {
+	atDoneLock.Lock()
 	atDone = append(atDone, cleanup)
+	atDoneLock.Unlock()
 }
 
 func Done() {
+	atDoneLock.Lock()
+	cleanups := atDone
+	atDone = nil
+	atDoneLock.Unlock()
 	var chs []<-chan struct{}
-	for _, cleanup := range atDone {
+	for _, cleanup := range cleanups {
 		ch := cleanup()
 		if ch != nil {
 			chs = append(chs, ch)
 		}
 	}
-	atDone = nil
 	for _, ch := range chs {
 		<-ch
 	}
@@ -576,7 +576,7 @@ func initSecretSuffixes() []string {
 }
 
 var secretSuffixes = initSecretSuffixes()
-var notASecretWhitelist = []string{"cloud.sshKey"}
+var notASecretWhitelist = []string{"cloud.sshKey", "each.key"}
 
 func LooksLikeSecret(name string) bool {
 	i := strings.Index(name, "|")